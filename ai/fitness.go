@@ -119,7 +119,7 @@ func FitnessOfAttackAction(w World, pos Pt) int64 {
 	w.Step(input)
 
 	// The player might have just been attacked and hit.
-	if w.Player.JustHit {
+	if w.StepEvents.Contains(PlayerHit) {
 		return 0
 	}
 
@@ -143,6 +143,6 @@ func Test_GeneratePracticeLevels(t *testing.T) {
 		levelS := fmt.Sprintf("practice-%02d", paramIdx+1)
 		SaveYAML(fmt.Sprintf("%s.mln999-params", levelS), params)
 		l := GenerateLevelFromParams(params)
-		l.SaveToYAML(RInt63(), fmt.Sprintf("%s.mln999-level", levelS))
+		l.SaveToYAML(RInt63(), fmt.Sprintf("%s.mln1000-level", levelS))
 	}
 }
@@ -0,0 +1,231 @@
+package ai
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"slices"
+)
+
+// PlayerMetrics describes how a human played, in one playthrough or summed
+// over many playthroughs of the same user.
+type PlayerMetrics struct {
+	User          string
+	NPlaythroughs int64
+	NFrames       int64
+	NActions      int64
+	// Frames between consecutive actions (moves or shots).
+	ActionIntervals []int64
+	// Clicks that didn't result in an action.
+	NFailedMoves int64
+	NFailedShots int64
+	// Shots at visible tiles, whether they hit something or not, and how many
+	// of them hit at least one enemy.
+	NShots    int64
+	NShotsHit int64
+	NKills    int64
+	// Hits taken and how long the player stayed off the map after them.
+	NHitsTaken           int64
+	FramesOffMapAfterHit int64
+	AmmoCollected        int64
+	AmmoUsed             int64
+	// Rank of each action compared to the actions the AI would take, 1 means
+	// the AI agrees it was the best action. Only computed on request because
+	// it is slow.
+	ActionRanks []int64
+}
+
+// ComputePlayerMetrics replays the playthrough and measures how the player
+// behaved.
+func ComputePlayerMetrics(user string, p Playthrough, withRanks bool) (m PlayerMetrics) {
+	m.User = user
+	m.NPlaythroughs = 1
+	m.NFrames = int64(len(p.History))
+
+	w := NewWorldFromPlaythrough(p)
+	lastAction := int64(-1)
+	offMapSince := int64(-1)
+	for i := range p.History {
+		input := p.History[i]
+		if input.LeftButtonPressed && !input.Move {
+			m.NFailedMoves++
+		}
+		if input.RightButtonPressed && !input.Shoot {
+			m.NFailedShots++
+		}
+		if input.Move || input.Shoot {
+			m.NActions++
+			if lastAction >= 0 {
+				m.ActionIntervals = append(m.ActionIntervals, int64(i)-lastAction)
+			}
+			lastAction = int64(i)
+		}
+
+		p.StepFrame(&w, i)
+
+		events := &w.StepEvents
+		if events.Contains(ShotMissed) {
+			m.NShots++
+		}
+		if events.Contains(ShotFired) {
+			m.NShots++
+			if w.UseAmmo {
+				m.AmmoUsed++
+			}
+			if events.Contains(EnemyHit) {
+				m.NShotsHit++
+			}
+		}
+		m.NKills += events.Count(EnemyKilled)
+		for j := range events.N {
+			if events.V[j].Type == AmmoCollected {
+				m.AmmoCollected += events.V[j].Amount.ToInt64()
+			}
+		}
+		if events.Contains(PlayerHit) {
+			m.NHitsTaken += events.Count(PlayerHit)
+			if offMapSince < 0 {
+				offMapSince = int64(i)
+			}
+		}
+		if w.Player.OnMap && offMapSince >= 0 {
+			m.FramesOffMapAfterHit += int64(i) - offMapSince
+			offMapSince = -1
+		}
+	}
+	if offMapSince >= 0 {
+		m.FramesOffMapAfterHit += m.NFrames - offMapSince
+	}
+
+	if withRanks {
+		framesWithActions := GetFramesWithActions(p)
+		decisionFrames := GetDecisionFrames(framesWithActions)
+		m.ActionRanks = GetRanksOfPlayerActions(p, framesWithActions, decisionFrames)
+	}
+	return
+}
+
+// Add sums up the metrics of another playthrough.
+func (m *PlayerMetrics) Add(other PlayerMetrics) {
+	m.NPlaythroughs += other.NPlaythroughs
+	m.NFrames += other.NFrames
+	m.NActions += other.NActions
+	m.ActionIntervals = append(m.ActionIntervals, other.ActionIntervals...)
+	m.NFailedMoves += other.NFailedMoves
+	m.NFailedShots += other.NFailedShots
+	m.NShots += other.NShots
+	m.NShotsHit += other.NShotsHit
+	m.NKills += other.NKills
+	m.NHitsTaken += other.NHitsTaken
+	m.FramesOffMapAfterHit += other.FramesOffMapAfterHit
+	m.AmmoCollected += other.AmmoCollected
+	m.AmmoUsed += other.AmmoUsed
+	m.ActionRanks = append(m.ActionRanks, other.ActionRanks...)
+}
+
+func ratio(a, b int64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+func median(s []int64) int64 {
+	if len(s) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(s)
+	slices.Sort(sorted)
+	return sorted[len(sorted)/2]
+}
+
+func sum(s []int64) (total int64) {
+	for _, v := range s {
+		total += v
+	}
+	return
+}
+
+// Accuracy is the fraction of shots that hit at least one enemy.
+func (m *PlayerMetrics) Accuracy() float64 {
+	return ratio(m.NShotsHit, m.NShots)
+}
+
+func (m *PlayerMetrics) HitsTakenPerMinute() float64 {
+	// The game runs at 60 frames per second.
+	return ratio(m.NHitsTaken*60*60, m.NFrames)
+}
+
+// KillsPerAmmo is how many enemies were killed for each ammo spent.
+func (m *PlayerMetrics) KillsPerAmmo() float64 {
+	return ratio(m.NKills, m.AmmoUsed)
+}
+
+// MetricsTable puts the metrics in a table with one row for each element of
+// metrics. If files is nil, the metrics are per user and there is no file
+// column.
+func MetricsTable(files []string, metrics []PlayerMetrics) (t Table) {
+	if files != nil {
+		t.AddColumn("file", StringColumn)
+	}
+	t.AddColumn("user", StringColumn)
+	t.AddColumn("n_playthroughs", Int64Column)
+	t.AddColumn("n_frames", Int64Column)
+	t.AddColumn("n_actions", Int64Column)
+	t.AddColumn("median_action_interval", Int64Column)
+	t.AddColumn("mean_action_interval", Float64Column)
+	t.AddColumn("n_failed_moves", Int64Column)
+	t.AddColumn("n_failed_shots", Int64Column)
+	t.AddColumn("n_shots", Int64Column)
+	t.AddColumn("accuracy", Float64Column)
+	t.AddColumn("n_kills", Int64Column)
+	t.AddColumn("n_hits_taken", Int64Column)
+	t.AddColumn("hits_taken_per_minute", Float64Column)
+	t.AddColumn("frames_off_map_after_hit", Int64Column)
+	t.AddColumn("mean_frames_off_map_per_hit", Float64Column)
+	t.AddColumn("ammo_collected", Int64Column)
+	t.AddColumn("ammo_used", Int64Column)
+	t.AddColumn("kills_per_ammo", Float64Column)
+	t.AddColumn("median_action_rank", Int64Column)
+	t.AddColumn("fraction_best_actions", Float64Column)
+
+	for i := range metrics {
+		m := &metrics[i]
+		nBest := int64(0)
+		for _, r := range m.ActionRanks {
+			if r == 1 {
+				nBest++
+			}
+		}
+		var values []any
+		if files != nil {
+			values = append(values, files[i])
+		}
+		values = append(values, m.User, m.NPlaythroughs, m.NFrames, m.NActions,
+			median(m.ActionIntervals),
+			ratio(sum(m.ActionIntervals), int64(len(m.ActionIntervals))),
+			m.NFailedMoves, m.NFailedShots, m.NShots, m.Accuracy(), m.NKills,
+			m.NHitsTaken, m.HitsTakenPerMinute(), m.FramesOffMapAfterHit,
+			ratio(m.FramesOffMapAfterHit, m.NHitsTaken), m.AmmoCollected,
+			m.AmmoUsed, m.KillsPerAmmo(), median(m.ActionRanks),
+			ratio(nBest, int64(len(m.ActionRanks))))
+		t.AddRow(values...)
+	}
+	return
+}
+
+// DistributionTable puts the values of a per-action or per-interval metric in
+// a long table, with one row for each value, so that histograms can be
+// computed per file or per user.
+func DistributionTable(name string, files []string, metrics []PlayerMetrics,
+	values func(m *PlayerMetrics) []int64) (t Table) {
+	t.AddColumn("file", StringColumn)
+	t.AddColumn("user", StringColumn)
+	t.AddColumn("idx", Int64Column)
+	t.AddColumn(name, Int64Column)
+	for i := range metrics {
+		for j, v := range values(&metrics[i]) {
+			t.AddRow(files[i], metrics[i].User, int64(j), v)
+		}
+	}
+	return
+}
@@ -0,0 +1,53 @@
+package ai
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestComputePlayerMetrics(t *testing.T) {
+	p := DeserializePlaythrough(ReadFile("../world/playthroughs/average-playthrough.mln999-999"))
+	m := ComputePlayerMetrics("user", p, false)
+	assert.Equal(t, int64(len(p.History)), m.NFrames)
+	assert.Equal(t, m.NActions-1, int64(len(m.ActionIntervals)))
+	assert.LessOrEqual(t, m.NShotsHit, m.NShots)
+	assert.Nil(t, m.ActionRanks)
+
+	total := PlayerMetrics{User: "user"}
+	total.Add(m)
+	total.Add(m)
+	assert.Equal(t, int64(2), total.NPlaythroughs)
+	assert.Equal(t, 2*m.NActions, total.NActions)
+	assert.Equal(t, m.Accuracy(), total.Accuracy())
+
+	table := MetricsTable([]string{"a", "b"}, []PlayerMetrics{m, m})
+	assert.Equal(t, 2, len(table.Rows))
+	assert.Equal(t, "file", table.Names[0])
+}
+
+func TestComputePlayerMetrics_Misses(t *testing.T) {
+	var p Playthrough
+	p.InputVersion = I(InputVersion)
+	p.SimulationVersion = I(SimulationVersion)
+	p.HoundMaxHealth = I(2)
+	p.SpawnPortalsParams.N = 1
+	p.SpawnPortalsParams.V[0].Pos = IPt(6, 6)
+	p.Events.N = 1
+	p.Events.V[0] = LevelEvent{Trigger: AtStart, Action: SpawnWave,
+		Portal: IPt(6, 6), NHounds: I(1), Burst: true}
+	p.History = []PlayerInput{{}, {Move: true, MovePt: IPt(2, 2)},
+		{Shoot: true, ShootPt: IPt(3, 3)}}
+	w := NewWorldFromPlaythrough(p)
+	for i := range p.History {
+		p.StepFrame(&w, i)
+	}
+	p.History = append(p.History,
+		PlayerInput{Shoot: true, ShootPt: w.Enemies.V[0].Pos()})
+
+	m := ComputePlayerMetrics("user", p, false)
+	assert.Equal(t, int64(2), m.NShots)
+	assert.Equal(t, int64(1), m.NShotsHit)
+	assert.Equal(t, 0.5, m.Accuracy())
+}
@@ -24,13 +24,12 @@ func NumFramesUntilAttacked(w World, pos Pt) int64 {
 	// Put the player at pos so that enemies will come to it.
 	w.Player.SetPos(pos)
 	w.Player.OnMap = true
-	w.Player.JustHit = false
 
 	// If an enemy doesn't hit within 100k frames, it's not happening.
 	input := PlayerInput{} // Don't move, don't attack.
 	for frameIdx := int64(0); frameIdx < 100000; frameIdx++ {
 		w.Step(input)
-		if w.Player.JustHit {
+		if w.StepEvents.Contains(PlayerHit) {
 			return frameIdx
 		}
 	}
@@ -90,6 +89,7 @@ type TargetSeeker struct {
 	vision    Vision
 	world     *World
 	obstacles MatBool
+	solid     MatBool
 }
 
 func NewTargetSeeker(world *World) (h TargetSeeker) {
@@ -97,21 +97,27 @@ func NewTargetSeeker(world *World) (h TargetSeeker) {
 	h.vision = NewVision()
 	// For the purposes of this calculator, both terrain obstacles and enemies
 	// constitute obstacles for vision.
-	h.obstacles = world.Obstacles
+	h.obstacles = world.OpaqueTiles
 	h.obstacles.Add(h.world.EnemyPositions())
+	// Some terrain can be seen through but not moved to (rocks) and some can
+	// be moved to but not seen through (bushes).
+	h.solid = world.SolidTiles
+	h.solid.Add(h.world.EnemyPositions())
 	return h
 }
 
 // Get all the positions that are visible from the positions indicated by the
-// "startPositions" matrix.
-func (h *TargetSeeker) computeVisiblePositions(startPositions MatBool) MatBool {
+// "startPositions" matrix. Also get the positions among those that can be
+// reached in one move, according to the world's movement rules.
+func (h *TargetSeeker) computeVisiblePositions(startPositions MatBool) (allVisible, allReachable MatBool) {
 	positions := startPositions.ToArray()
-	allVisible := MatBool{}
 	for i := range positions.N {
 		visible := h.vision.Compute(positions.V[i], h.obstacles)
 		allVisible.Add(visible)
+		visible.IntersectWith(h.world.MoveRange(positions.V[i]))
+		allReachable.Add(visible)
 	}
-	return allVisible
+	return
 }
 
 // NumMovesUntilTargetVisible computes the minimum number of moves required to see
@@ -134,7 +140,7 @@ func (h *TargetSeeker) NumMovesUntilTargetVisible(startPos Pt, targets MatBool)
 	// Try a maximum of 10 moves for now, even though normally there should be
 	// a better stop condition like "all possible positions have been visited".
 	for iMove := 0; iMove < 10; iMove++ {
-		visiblePositions := h.computeVisiblePositions(lookoutPositions)
+		visiblePositions, reachablePositions := h.computeVisiblePositions(lookoutPositions)
 		// Check if any targets are visible from the current start positions.
 		visibleTargets := visiblePositions
 		visibleTargets.IntersectWith(targets)
@@ -143,11 +149,11 @@ func (h *TargetSeeker) NumMovesUntilTargetVisible(startPos Pt, targets MatBool)
 			return iMove
 		}
 		// Compute the lookout positions for the next move.
-		// They are all the positions visible at this move, minus the obstacles
-		// and minus the current lookoutPositions (no sense recomputing for the
-		// positions in lookoutPositions).
-		newLookoutPositions := visiblePositions
-		newLookoutPositions.Subtract(h.obstacles)
+		// They are all the positions reachable at this move, minus the
+		// obstacles and minus the current lookoutPositions (no sense
+		// recomputing for the positions in lookoutPositions).
+		newLookoutPositions := reachablePositions
+		newLookoutPositions.Subtract(h.solid)
 		newLookoutPositions.Subtract(lookoutPositions)
 		lookoutPositions = newLookoutPositions
 	}
@@ -62,7 +62,7 @@ func PlayLevel(l Level, seed Int, r RandomnessInPlay, levelIdx int, playIdx int,
 				str += fmt.Sprintf("             ")
 			}
 			AppendToFile(debugFile, str)
-			AppendToFile(debugFile, world.StateStr()+"\n")
+			AppendToFile(debugFile, world.StateStr()+" "+world.StepEvents.String()+"\n")
 		}
 		if world.Status() != Ongoing {
 			return world
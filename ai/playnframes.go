@@ -77,7 +77,7 @@ func PlayLevelForAtLeastNFrames(l Level, seed Int, nFrames int) (p Playthrough)
 		if w.Status() != Ongoing {
 			return
 		}
-		if w.Player.JustHit {
+		if w.StepEvents.Contains(PlayerHit) {
 			break
 		}
 	}
@@ -115,7 +115,7 @@ func PlayLevelForAtLeastNFrames(l Level, seed Int, nFrames int) (p Playthrough)
 		if w.Status() != Ongoing {
 			return
 		}
-		if w.Player.JustHit {
+		if w.StepEvents.Contains(PlayerHit) {
 			break
 		}
 	}
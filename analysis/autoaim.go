@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// AutoAim replays every playthrough in a folder once for each auto-aim factor,
+// resolving the recorded clicks again with that factor, and writes how many
+// clicks turned into actions and how the level ended to autoaim.<format>.
+// A factor of 0 means auto-aim is off. The block size and margin are the ones
+// the GUI uses (see data/gui/gui.yaml and Gui.guiMargin), because the recorded
+// mouse positions only make sense in that layout.
+func AutoAim() {
+	if len(os.Args) < 5 {
+		fmt.Println("Usage: analysis.exe autoaim <folder> <csv/jsonl/parquet> " +
+			"<factor> [factor ...]")
+		return
+	}
+	dir := os.Args[2]
+	format := os.Args[3]
+	var factors []int64
+	for _, arg := range os.Args[4:] {
+		factor, err := strconv.ParseInt(arg, 10, 64)
+		Check(err)
+		factors = append(factors, factor)
+	}
+
+	var t Table
+	t.AddColumn("file", StringColumn)
+	t.AddColumn("factor", Int64Column)
+	t.AddColumn("original_status", StringColumn)
+	t.AddColumn("status", StringColumn)
+	t.AddColumn("original_n_frames", Int64Column)
+	t.AddColumn("n_frames", Int64Column)
+	t.AddColumn("n_move_clicks", Int64Column)
+	t.AddColumn("n_moves", Int64Column)
+	t.AddColumn("n_moves_rescued", Int64Column)
+	t.AddColumn("n_shot_clicks", Int64Column)
+	t.AddColumn("n_shots", Int64Column)
+	t.AddColumn("n_shots_rescued", Int64Column)
+	t.AddColumn("n_changed_actions", Int64Column)
+
+	for _, name := range GetFiles(os.DirFS(dir).(FS), ".", "*.mln*") {
+		playthrough, ok := loadPlaythrough(dir + "/" + name)
+		if !ok {
+			continue
+		}
+		original := playthrough.ReplayUpTo(len(playthrough.History))
+		for _, factor := range factors {
+			a := AimParams{
+				BlockSize:           I(100),
+				Margin:              I(50),
+				AutoAimAttack:       factor > 0,
+				AutoAimAttackFactor: I64(factor),
+				AutoAimMove:         factor > 0,
+				AutoAimMoveFactor:   I64(factor),
+			}
+			_, s := Reaim(playthrough, a)
+			t.AddRow(filepath.Base(name), factor,
+				original.Status().String(), s.Status.String(),
+				int64(len(playthrough.History)), s.NFrames,
+				s.NMoveClicks, s.NMoves, s.NMovesRescued,
+				s.NShotClicks, s.NShots, s.NShotsRescued,
+				s.NChangedActions)
+		}
+	}
+	WriteTable("autoaim."+format, format, &t)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/marisvali/miln/catalog"
+	. "github.com/marisvali/miln/gamelib"
+	"os"
+	"strings"
+	"time"
+)
+
+const catalogUsage = `Usage:
+  analysis.exe catalog index <folder> <catalog-file>
+  analysis.exe catalog query <catalog-file> [options]`
+
+// Catalog indexes folders of playthroughs and queries the index, see package
+// catalog. For example, all the losses on a level by the users in a cohort
+// after a date:
+//
+//	analysis.exe catalog index recordings catalog.db
+//	analysis.exe catalog query catalog.db -cohort testers.txt -level 3f2a9c
+//	  -status lost -after 2025-05-01
+//
+// A query prints the files it finds, one per line, or a table if -group is
+// given.
+func Catalog() {
+	if len(os.Args) < 4 {
+		fmt.Println(catalogUsage)
+		return
+	}
+	if os.Args[2] == "index" && len(os.Args) > 4 {
+		c := catalog.Open(os.Args[4])
+		defer c.Close()
+		s := c.Index(os.Args[3])
+		fmt.Printf("%d files: %d cached, %d replayed, %d failed\n",
+			s.Files, s.Cached, s.Replayed, s.Failed)
+	} else if os.Args[2] == "query" {
+		queryCatalog(os.Args[3], os.Args[4:])
+	} else {
+		fmt.Println(catalogUsage)
+	}
+}
+
+func queryCatalog(name string, args []string) {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	users := flags.String("users", "", "comma-separated users")
+	cohort := flags.String("cohort", "", "file with one user per line")
+	level := flags.String("level", "", "prefix of the level hash")
+	status := flags.String("status", "", "ongoing, won or lost")
+	after := flags.String("after", "", "started on or after this day (YYYY-MM-DD)")
+	before := flags.String("before", "", "started before this day (YYYY-MM-DD)")
+	release := flags.Int64("release", 0, "release version")
+	tag := flags.String("tag", "", "tag")
+	group := flags.String("group", "", "user, level, status, release or day")
+	Check(flags.Parse(args))
+
+	q := catalog.Query{Level: *level, Status: *status,
+		ReleaseVersion: *release, Tag: *tag}
+	if *users != "" {
+		q.Users = strings.Split(*users, ",")
+	}
+	if *cohort != "" {
+		for _, line := range SplitInLines(ReadFile(*cohort)) {
+			if line = strings.TrimSpace(line); line != "" {
+				q.Users = append(q.Users, line)
+			}
+		}
+	}
+	q.After = parseDay(*after)
+	q.Before = parseDay(*before)
+
+	c := catalog.Open(name)
+	defer c.Close()
+	entries := c.Find(q)
+	if *group == "" {
+		for _, e := range entries {
+			fmt.Println(e.File)
+		}
+		return
+	}
+	if _, ok := catalog.GroupKeys[*group]; !ok {
+		fmt.Printf("unknown group: %s\n", *group)
+		return
+	}
+	fmt.Printf("%s,n,won,lost,avg_seconds,avg_actions,avg_health\n", *group)
+	for _, g := range catalog.Aggregate(entries, *group) {
+		fmt.Printf("%s,%d,%d,%d,%.1f,%.1f,%.1f\n", g.Key, g.N, g.Won,
+			g.Lost, g.Seconds, g.Actions, g.Health)
+	}
+}
+
+// parseDay parses a day like 2025-05-01, in UTC. An empty string gives the
+// zero time, which queries ignore.
+func parseDay(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	Check(err)
+	return t
+}
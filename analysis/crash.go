@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"os"
+)
+
+// ReplayCrash loads a crash saved by the GUI, prints what was saved and
+// replays the playthrough to check that the crash reproduces. If playthrough
+// is given, it also saves the playthrough by itself, so that it can be opened
+// in the GUI or given to "minimize".
+func ReplayCrash() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: analysis.exe crash <crash-file> [playthrough]")
+		return
+	}
+	c := DeserializeCrash(ReadFile(os.Args[2]))
+	fmt.Printf("release: %d, simulation: %d, input: %d\n",
+		c.ReleaseVersion.ToInt64(), c.SimulationVersion.ToInt64(),
+		c.InputVersion.ToInt64())
+	fmt.Printf("playthrough: %s, frames: %d\n", c.Playthrough.Id,
+		len(c.Playthrough.History))
+	fmt.Printf("panic: %s\n%s\n", c.Message, c.Stack)
+
+	if len(os.Args) > 3 {
+		WriteFile(os.Args[3], c.Playthrough.Serialize())
+	}
+
+	f, failed, err := c.Reproduce()
+	if err != nil {
+		fmt.Printf("can't replay: %v\n", err)
+	} else if !failed {
+		// The crash happened outside the World, e.g. while drawing.
+		fmt.Println("the replay doesn't crash")
+	} else if f.Message != c.Message {
+		fmt.Printf("the replay crashes differently at frame %d: %s at %s\n",
+			f.Frame, f.Message, f.Location)
+	} else {
+		fmt.Printf("reproduced at frame %d: %s\n", f.Frame, f.Location)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	"os"
+	"path/filepath"
+)
+
+// Desync replays all the playthroughs in a folder and reports the ones that
+// don't match the checksums recorded while they were played, e.g. because
+// they were recorded by a build whose simulation is different from this one.
+func Desync() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: analysis.exe desync <folder>")
+		return
+	}
+	dir := os.Args[2]
+
+	nChecked, nDesynced := 0, 0
+	for _, name := range GetFiles(os.DirFS(dir).(FS), ".", "*.mln*") {
+		playthrough, ok := loadPlaythrough(dir + "/" + name)
+		if !ok {
+			continue
+		}
+		if playthrough.ChecksumInterval.IsZero() {
+			fmt.Printf("%s: no checksums\n", filepath.Base(name))
+			continue
+		}
+		nChecked++
+		if playthrough.FindDesync() > 0 {
+			nDesynced++
+			fmt.Printf("%s: %s\n", filepath.Base(name),
+				playthrough.DesyncWarning())
+		}
+	}
+	fmt.Printf("%d of %d playthroughs with checksums don't match this build\n",
+		nDesynced, nChecked)
+}
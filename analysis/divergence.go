@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"os"
+)
+
+// How many frames to show before and after a divergence.
+const divergenceWindow = 5
+
+// Trace saves the StateTrace of a playthrough, as computed by the current
+// build, so that a later build can be compared against it with diverge.
+func Trace() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: analysis.exe trace <playthrough> <output-trace>")
+		return
+	}
+	p := DeserializePlaythrough(ReadFile(os.Args[2]))
+	t := ComputeStateTrace(&p)
+	WriteFile(os.Args[3], t.Serialize())
+}
+
+// Diverge compares the StateTrace of a playthrough with the current build
+// against a trace saved by trace, or compares two saved traces. It prints the
+// first frame where they differ, what is different, the input that led to
+// that frame and the states around it.
+func Diverge() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: analysis.exe diverge <playthrough> <expected-trace> " +
+			"[actual-trace]")
+		fmt.Println("Without actual-trace, the playthrough is run with this build.")
+		return
+	}
+	p := DeserializePlaythrough(ReadFile(os.Args[2]))
+	expected := DeserializeStateTrace(ReadFile(os.Args[3]))
+	var actual StateTrace
+	if len(os.Args) > 4 {
+		actual = DeserializeStateTrace(ReadFile(os.Args[4]))
+	} else {
+		actual = ComputeStateTrace(&p)
+	}
+
+	if warning := p.DesyncWarning(); warning != "" {
+		fmt.Println(warning)
+	}
+
+	d, found := FindDivergence(expected, actual)
+	if !found {
+		fmt.Printf("no divergence in %d frames\n", len(expected))
+		return
+	}
+
+	fmt.Printf("first divergence at frame %d\n", d.Frame)
+	for _, diff := range d.Diffs {
+		fmt.Println("  " + diff)
+	}
+	if d.Frame > 0 && d.Frame <= len(p.History) {
+		fmt.Printf("input at frame %d: %+v\n", d.Frame-1, p.History[d.Frame-1])
+	}
+
+	fmt.Println("frame  expected | actual")
+	start := max(d.Frame-divergenceWindow, 0)
+	end := d.Frame + divergenceWindow
+	for i := start; i <= end && (i < len(expected) || i < len(actual)); i++ {
+		marker := " "
+		if i == d.Frame {
+			marker = ">"
+		}
+		fmt.Printf("%s%5d  %s | %s\n", marker, i, traceStr(expected, i),
+			traceStr(actual, i))
+	}
+}
+
+func traceStr(t StateTrace, i int) string {
+	if i >= len(t) {
+		return "-"
+	}
+	s := DecodeState(t[i])
+	return s.String()
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -66,6 +68,28 @@ func DownloadRecordings() {
 				m.Year(), m.Month(), m.Day(), m.Hour(), m.Minute(), m.Second(),
 				dbRows[i].simulationVersion, dbRows[i].inputVersion)
 		}
-		WriteFile(filename, dbRows[i].data)
+		if strings.HasSuffix(dbRows[i].user, CrashSuffix) {
+			// Uploaded by Gui.recoverCrash, see Crash. Keep the suffix so
+			// that the tools that look for playthroughs leave it out.
+			WriteFile(filename+CrashSuffix, dbRows[i].data)
+		} else {
+			WriteFile(filename, withHeader(dbRows[i]))
+		}
+	}
+}
+
+// withHeader puts a header with what the database knows about a playthrough
+// in front of it (see SerializePlaythroughFile), so that it isn't lost once
+// the playthrough is downloaded. Playthroughs that can't be loaded (e.g. too
+// old) are saved as they are.
+func withHeader(row dbRow) []byte {
+	h, err := TryReadPlaythroughHeader(row.data)
+	if err != nil {
+		return row.data
 	}
+	h.User = row.user
+	h.Start = row.startMoment.UTC()
+	h.End = row.endMoment.UTC()
+	h.ReleaseVersion = row.releaseVersion
+	return AddPlaythroughHeader(h, row.data)
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+var stepEventTypes = []StepEventType{PlayerMoved, ShotFired, ShotMissed,
+	EnemyHit, EnemyKilled, EnemySpawned, PlayerHit, AmmoCollected, WaveStarted,
+	SpawnPortalDestroyed, LevelWon, LevelLost}
+
+// Events replays every playthrough in a folder and writes how many times each
+// kind of event happened in each playthrough.
+func Events() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: analysis.exe events <folder>")
+		return
+	}
+	dir := os.Args[2]
+
+	file, err := os.Create("events.csv")
+	Check(err)
+	defer CloseFile(file)
+
+	header := "file"
+	for _, t := range stepEventTypes {
+		header += "," + t.String()
+	}
+	_, err = file.WriteString(header + "\n")
+	Check(err)
+
+	for _, name := range GetFiles(os.DirFS(dir).(FS), ".", "*.mln*") {
+		playthrough, ok := loadPlaythrough(dir + "/" + name)
+		if !ok {
+			continue
+		}
+		w := NewWorldFromPlaythrough(playthrough)
+		counts := map[StepEventType]int64{}
+		for i := range playthrough.History {
+			playthrough.StepFrame(&w, i)
+			for j := range w.StepEvents.N {
+				counts[w.StepEvents.V[j].Type]++
+			}
+		}
+
+		line := filepath.Base(name)
+		for _, t := range stepEventTypes {
+			line += "," + strconv.FormatInt(counts[t], 10)
+		}
+		_, err = file.WriteString(line + "\n")
+		Check(err)
+	}
+}
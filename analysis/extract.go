@@ -24,13 +24,13 @@ func Extract() {
 	inputFile := "d:\\gms\\Miln\\analysis\\tools\\playthroughs\\denis\\20250319-170648.mln010"
 	playthrough := DeserializePlaythrough(ReadFile(inputFile))
 
-	_, err = file.WriteString("frame_idx,mouse_x,mouse_y,left_clicked,right_clicked\n")
+	_, err = file.WriteString("frame_idx,mouse_x,mouse_y,left_clicked,right_clicked,undo\n")
 	Check(err)
 
 	for i := 1; i < len(playthrough.History); i++ {
 		input := playthrough.History[i]
 		// Write data to the CSV file line by line
-		nums := []Int{I(i + 1), input.MousePt.X, input.MousePt.Y, intFromBool(input.LeftButtonPressed), intFromBool(input.RightButtonPressed)}
+		nums := []Int{I(i + 1), input.MousePt.X, input.MousePt.Y, intFromBool(input.LeftButtonPressed), intFromBool(input.RightButtonPressed), intFromBool(input.Undo)}
 		for _, num := range nums {
 			_, err = file.WriteString(strconv.Itoa(num.ToInt()) + ",")
 			Check(err)
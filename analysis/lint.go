@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"os"
+	"path/filepath"
+)
+
+// Lint validates all the levels in a folder (e.g. data/levels) and prints
+// their issues. It exits with an error code if a level has errors, so that it
+// can run before a release.
+func Lint() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: analysis.exe lint <folder>")
+		return
+	}
+	dir := os.Args[2]
+
+	nLevels, nBroken := 0, 0
+	for _, name := range GetFiles(os.DirFS(dir).(FS), ".", "*") {
+		nLevels++
+		issues := ValidateLevelYAML(ReadFile(dir + "/" + name))
+		for _, issue := range issues {
+			fmt.Printf("%s: %s\n", filepath.Base(name), issue)
+		}
+		if HasErrors(issues) {
+			nBroken++
+		}
+	}
+	fmt.Printf("%d of %d levels have errors\n", nBroken, nLevels)
+	if nBroken > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+)
+
+// loadPlaythrough loads a playthrough that the current simulation can replay.
+// If it can't, it says why and returns false, so that the commands that go
+// through many downloaded playthroughs can skip the corrupt or old ones
+// instead of crashing.
+func loadPlaythrough(filename string) (p Playthrough, ok bool) {
+	data, err := TryReadFile(filename)
+	if err == nil {
+		p, err = TryDeserializePlaythrough(data)
+	}
+	if err == nil {
+		_, err = TryNewWorldFromPlaythrough(p)
+	}
+	if err != nil {
+		fmt.Printf("skipping %s: %v\n", filename, err)
+		return p, false
+	}
+	return p, true
+}
@@ -7,7 +7,7 @@ import (
 
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: analysis.exe <download/extract>")
+		fmt.Println("Usage: analysis.exe <download/extract/objectives/events/timeline/metrics/autoaim/thumbnail/frames/gif/trace/diverge/desync/minimize/lint/crash/catalog>")
 		return
 	}
 	action := os.Args[1]
@@ -17,5 +17,33 @@ func main() {
 		Extract()
 	} else if action == "updatever" {
 		UpdateVersion()
+	} else if action == "objectives" {
+		Objectives()
+	} else if action == "events" {
+		Events()
+	} else if action == "timeline" {
+		ExportTimeline()
+	} else if action == "metrics" {
+		Metrics()
+	} else if action == "autoaim" {
+		AutoAim()
+	} else if action == "thumbnail" {
+		Thumbnail()
+	} else if action == "frames" || action == "gif" {
+		Frames(action)
+	} else if action == "trace" {
+		Trace()
+	} else if action == "diverge" {
+		Diverge()
+	} else if action == "desync" {
+		Desync()
+	} else if action == "minimize" {
+		MinimizePlaythrough()
+	} else if action == "lint" {
+		Lint()
+	} else if action == "crash" {
+		ReplayCrash()
+	} else if action == "catalog" {
+		Catalog()
 	}
 }
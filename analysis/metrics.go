@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"github.com/marisvali/miln/ai"
+	. "github.com/marisvali/miln/gamelib"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Metrics computes how human players behaved, for every playthrough in a
+// folder. The folder is expected to be organized like the output of
+// "download": one subfolder per user. It writes:
+// - metrics-playthroughs.<format>: one row per playthrough
+// - metrics-users.<format>: one row per user
+// - metrics-intervals.<format>: frames between consecutive actions
+// - metrics-ranks.<format>: rank of each action relative to the AI (only if
+// "ranks" is given, because it is slow)
+func Metrics() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: analysis.exe metrics <folder> [csv/jsonl/parquet] [ranks]")
+		return
+	}
+	dir := os.Args[2]
+	format := "csv"
+	if len(os.Args) > 3 {
+		format = os.Args[3]
+	}
+	withRanks := len(os.Args) > 4 && os.Args[4] == "ranks"
+
+	var files []string
+	var metrics []ai.PlayerMetrics
+	perUser := map[string]*ai.PlayerMetrics{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		Check(err)
+		if d.IsDir() {
+			return nil
+		}
+		if match, _ := filepath.Match("*.mln*", d.Name()); !match {
+			return nil
+		}
+		user := filepath.Base(filepath.Dir(path))
+		playthrough, ok := loadPlaythrough(path)
+		if !ok {
+			return nil
+		}
+		m := ai.ComputePlayerMetrics(user, playthrough, withRanks)
+		files = append(files, path)
+		metrics = append(metrics, m)
+		if perUser[user] == nil {
+			perUser[user] = &ai.PlayerMetrics{User: user}
+		}
+		perUser[user].Add(m)
+		return nil
+	})
+	Check(err)
+
+	table := ai.MetricsTable(files, metrics)
+	WriteTable("metrics-playthroughs."+format, format, &table)
+
+	var users []string
+	for user := range perUser {
+		users = append(users, user)
+	}
+	slices.Sort(users)
+	var userMetrics []ai.PlayerMetrics
+	for _, user := range users {
+		userMetrics = append(userMetrics, *perUser[user])
+	}
+	table = ai.MetricsTable(nil, userMetrics)
+	WriteTable("metrics-users."+format, format, &table)
+
+	table = ai.DistributionTable("frames_between_actions", files, metrics,
+		func(m *ai.PlayerMetrics) []int64 { return m.ActionIntervals })
+	WriteTable("metrics-intervals."+format, format, &table)
+
+	if withRanks {
+		table = ai.DistributionTable("rank", files, metrics,
+			func(m *ai.PlayerMetrics) []int64 { return m.ActionRanks })
+		WriteTable("metrics-ranks."+format, format, &table)
+	}
+}
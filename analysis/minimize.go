@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// MinimizePlaythrough shrinks a playthrough that crashes the simulation or
+// breaks one of its invariants (see CheckInvariants) to the smallest
+// playthrough that still fails in the same way, and saves it as a new
+// fixture. If test-file is given, it also writes a Go test that replays the
+// fixture, to be copied to the world package once the bug is fixed.
+func MinimizePlaythrough() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: analysis.exe minimize <playthrough> <output> [test-file]")
+		return
+	}
+	p := DeserializePlaythrough(ReadFile(os.Args[2]))
+	output := os.Args[3]
+
+	minimized, f, s, ok := Minimize(p, CheckInvariants)
+	if !ok {
+		fmt.Println("the playthrough doesn't fail, nothing to minimize")
+		return
+	}
+	fmt.Printf("failure: %s\n", f.Signature())
+	fmt.Printf("frames: %d -> %d, actions: %d -> %d, replays: %d\n",
+		s.OriginalFrames, s.Frames, s.OriginalActions, s.Actions, s.NReplays)
+	WriteFile(output, minimized.Serialize())
+
+	if len(os.Args) > 4 {
+		WriteFile(os.Args[4], []byte(testSkeleton(output, f)))
+	}
+}
+
+// testName turns a file name like "crash-mud.mln1000-999" into "CrashMud".
+func testName(filename string) string {
+	base := filepath.Base(filename)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	var name strings.Builder
+	upper := true
+	for _, r := range base {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		name.WriteRune(r)
+	}
+	return name.String()
+}
+
+func testSkeleton(fixture string, f Failure) string {
+	return fmt.Sprintf(`package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// Minimized from a playthrough that failed at frame %d with:
+// %s
+func TestReplay_%s(t *testing.T) {
+	p := DeserializePlaythrough(ReadFile("playthroughs/%s"))
+	_, failed := ReplayForFailure(p, CheckInvariants)
+	assert.False(t, failed)
+}
+`, f.Frame, f.Signature(), testName(fixture), filepath.Base(fixture))
+}
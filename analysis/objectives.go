@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	"os"
+	"path/filepath"
+)
+
+// Objectives replays all the playthroughs in a folder and writes the goal
+// type of each level and how the playthrough ended, so that playthroughs can
+// be grouped by goal type.
+func Objectives() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: analysis.exe objectives <folder>")
+		return
+	}
+	dir := os.Args[2]
+
+	file, err := os.Create("objectives.csv")
+	Check(err)
+	defer CloseFile(file)
+
+	_, err = file.WriteString("file,objective,status,n_frames,n_undos\n")
+	Check(err)
+
+	for _, name := range GetFiles(os.DirFS(dir).(FS), ".", "*.mln*") {
+		playthrough, ok := loadPlaythrough(dir + "/" + name)
+		if !ok {
+			continue
+		}
+		w := playthrough.ReplayUpTo(len(playthrough.History))
+		_, err = file.WriteString(fmt.Sprintf("%s,%s,%s,%d,%d\n", filepath.Base(name),
+			playthrough.Level.ObjectiveSummary(),
+			w.Status().String(),
+			len(playthrough.History),
+			playthrough.NUndos()))
+		Check(err)
+	}
+}
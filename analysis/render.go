@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/marisvali/miln/render"
+	. "github.com/marisvali/miln/world"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// The sprites are read from data/gui, relative to the folder analysis.exe is
+// run from.
+const renderBlockSize = 80
+
+func newRenderer() render.Renderer {
+	sprites := render.LoadSprites(os.DirFS(".").(FS), "data/gui")
+	return render.NewRenderer(sprites, renderBlockSize)
+}
+
+func atoi(s string) int {
+	i, err := strconv.Atoi(s)
+	Check(err)
+	return i
+}
+
+// Thumbnail draws a level, from a YAML file or from a playthrough, to a PNG
+// file.
+func Thumbnail() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: analysis.exe thumbnail <level.yaml or playthrough> " +
+			"<output.png>")
+		return
+	}
+	input := os.Args[2]
+	var seed Int
+	var l Level
+	if IsYamlLevel(input) {
+		seed, l = LoadLevelFromYAML(os.DirFS(filepath.Dir(input)).(FS),
+			filepath.Base(input))
+	} else {
+		p := DeserializePlaythrough(ReadFile(input))
+		seed, l = p.Seed, p.Level
+	}
+	r := newRenderer()
+	render.WritePNG(os.Args[3], r.Thumbnail(seed, l))
+}
+
+// Frames draws a range of frames of a playthrough, either as numbered PNG
+// files in a folder or as an animated GIF, depending on the action.
+func Frames(action string) {
+	if len(os.Args) < 7 {
+		fmt.Printf("Usage: analysis.exe %s <playthrough> <output> <start> "+
+			"<end> <skip>\n", action)
+		return
+	}
+	p := DeserializePlaythrough(ReadFile(os.Args[2]))
+	output := os.Args[3]
+	start := atoi(os.Args[4])
+	end := atoi(os.Args[5])
+	skip := atoi(os.Args[6])
+
+	r := newRenderer()
+	imgs := r.Frames(p, start, end, skip)
+	if action == "gif" {
+		render.WriteGIF(output, imgs, skip)
+	} else {
+		render.WriteFrames(output, imgs)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"os"
+)
+
+// ExportTimeline replays a playthrough, or every playthrough in a folder, and
+// writes the state of the World at each frame to timeline.<format>.
+func ExportTimeline() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: analysis.exe timeline <file or folder> " +
+			"<csv/jsonl/parquet> [actions]")
+		return
+	}
+	path := os.Args[2]
+	format := os.Args[3]
+	onlyActions := len(os.Args) > 4 && os.Args[4] == "actions"
+
+	var files []string
+	info, err := os.Stat(path)
+	Check(err)
+	if info.IsDir() {
+		for _, name := range GetFiles(os.DirFS(path).(FS), ".", "*.mln*") {
+			files = append(files, path+"/"+name)
+		}
+	} else {
+		files = append(files, path)
+	}
+
+	var rows []TimelineRow
+	for _, file := range files {
+		playthrough, ok := loadPlaythrough(file)
+		if !ok {
+			continue
+		}
+		rows = append(rows, Timeline(&playthrough, onlyActions)...)
+	}
+	table := TimelineTable(rows)
+	WriteTable("timeline."+format, format, &table)
+}
@@ -0,0 +1,113 @@
+// Package bot lets programs outside of Go play Miln through a simple
+// protocol: newline-delimited JSON, one request and one response per line.
+//
+// Requests:
+//
+//	{"cmd": "start", "level": "path/to/level.yaml", "seed": 123}
+//	{"cmd": "step", "action": {"type": "move", "x": 3, "y": 4}, "frames": 10}
+//	{"cmd": "observe"}
+//	{"cmd": "save"}
+//	{"cmd": "quit"}
+//
+// The seed of "start" is optional, by default the seed in the YAML is used.
+// The action type is one of: none, move, shoot, undo. The action is applied in
+// the first frame and the World then runs without input for the rest of the
+// frames, or until the level ends.
+//
+// Every response has "ok" and either "error" or the observation of the World
+// after the request was handled. Finished games are saved as standard
+// playthrough files, and "save" saves the current game at any point.
+package bot
+
+type Request struct {
+	Cmd    string `json:"cmd"`
+	Level  string `json:"level,omitempty"`
+	Seed   *int64 `json:"seed,omitempty"`
+	Action Action `json:"action"`
+	Frames int64  `json:"frames,omitempty"`
+}
+
+type Action struct {
+	Type string `json:"type"`
+	X    int64  `json:"x"`
+	Y    int64  `json:"y"`
+}
+
+type Response struct {
+	Ok          bool         `json:"ok"`
+	Error       string       `json:"error,omitempty"`
+	Observation *Observation `json:"observation,omitempty"`
+	// Set if the request caused a playthrough to be saved.
+	Playthrough string `json:"playthrough,omitempty"`
+}
+
+// Observation is what the player can know about the World. Matrices are
+// indexed as [y][x].
+type Observation struct {
+	Frame     int64  `json:"frame"`
+	Status    string `json:"status"`
+	Objective string `json:"objective"`
+	TurnBased bool   `json:"turn_based"`
+	UseAmmo   bool   `json:"use_ammo"`
+	// False if the last action was a move or shot that wasn't possible.
+	ActionOk  bool     `json:"action_ok"`
+	Obstacles [][]bool `json:"obstacles"`
+	// One of: ground, bush, rock, mud, crate. Bushes block vision, rocks
+	// block movement and crates block both until the beam breaks them.
+	Terrain [][]string `json:"terrain"`
+	// The health left of each crate, 0 where there is no crate.
+	CrateHealth [][]int64   `json:"crate_health"`
+	Visible     [][]bool    `json:"visible"`
+	Player      PlayerObs   `json:"player"`
+	Hounds      []HoundObs  `json:"hounds"`
+	Ammos       []AmmoObs   `json:"ammos"`
+	Pickups     []EntityObs `json:"pickups"`
+	Objectives  []EntityObs `json:"objectives"`
+	Intents     []IntentObs `json:"intents,omitempty"`
+	// Events of all the frames that ran for the last request.
+	Events []EventObs `json:"events"`
+}
+
+type PlayerObs struct {
+	X         int64 `json:"x"`
+	Y         int64 `json:"y"`
+	OnMap     bool  `json:"on_map"`
+	Health    int64 `json:"health"`
+	MaxHealth int64 `json:"max_health"`
+	Ammo      int64 `json:"ammo"`
+}
+
+type HoundObs struct {
+	X      int64  `json:"x"`
+	Y      int64  `json:"y"`
+	State  string `json:"state"`
+	Health int64  `json:"health"`
+}
+
+type AmmoObs struct {
+	X     int64 `json:"x"`
+	Y     int64 `json:"y"`
+	Count int64 `json:"count"`
+}
+
+type EntityObs struct {
+	X    int64  `json:"x"`
+	Y    int64  `json:"y"`
+	Type string `json:"type"`
+}
+
+type IntentObs struct {
+	FromX  int64 `json:"from_x"`
+	FromY  int64 `json:"from_y"`
+	ToX    int64 `json:"to_x"`
+	ToY    int64 `json:"to_y"`
+	Attack bool  `json:"attack"`
+}
+
+type EventObs struct {
+	Frame  int64  `json:"frame"`
+	Type   string `json:"type"`
+	X      int64  `json:"x"`
+	Y      int64  `json:"y"`
+	Amount int64  `json:"amount"`
+}
@@ -0,0 +1,277 @@
+package bot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Session is one bot playing one level at a time.
+type Session struct {
+	// Folder where playthroughs are saved.
+	OutputDir   string
+	playthrough Playthrough
+	world       World
+	started     bool
+	saved       bool
+	actionOk    bool
+	events      []EventObs
+	startTime   time.Time
+}
+
+func NewSession(outputDir string) (s Session) {
+	s.OutputDir = outputDir
+	s.playthrough.InputVersion = I(InputVersion)
+	s.playthrough.SimulationVersion = I(SimulationVersion)
+	s.playthrough.ChecksumInterval = I(DefaultChecksumInterval)
+	return
+}
+
+// Serve reads requests from r and writes responses to w until it gets a
+// "quit" request or r is closed.
+func (s *Session) Serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	encoder := json.NewEncoder(w)
+	for scanner.Scan() {
+		var req Request
+		var resp Response
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = "invalid request: " + err.Error()
+		} else {
+			resp = s.Handle(req)
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+		if req.Cmd == "quit" {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// Handle executes a request. Errors, including the ones that would make the
+// simulation panic (e.g. a level that can't be loaded), are reported in the
+// response instead of ending the session.
+func (s *Session) Handle(req Request) (resp Response) {
+	defer func() {
+		if r := recover(); r != nil {
+			resp = Response{Error: fmt.Sprint(r)}
+		}
+	}()
+
+	s.events = nil
+	switch req.Cmd {
+	case "start":
+		resp.Playthrough = s.saveIfUnsaved()
+		s.start(req)
+	case "step":
+		if !s.started {
+			return Response{Error: "no level started"}
+		}
+		if err := s.step(req); err != nil {
+			return Response{Error: err.Error()}
+		}
+		if s.world.Status() != Ongoing {
+			resp.Playthrough = s.saveIfUnsaved()
+		}
+	case "observe":
+		if !s.started {
+			return Response{Error: "no level started"}
+		}
+	case "save":
+		if !s.started {
+			return Response{Error: "no level started"}
+		}
+		resp.Playthrough = s.save()
+	case "quit":
+		resp.Playthrough = s.saveIfUnsaved()
+		return Response{Ok: true, Playthrough: resp.Playthrough}
+	default:
+		return Response{Error: "unknown command: " + req.Cmd}
+	}
+	resp.Ok = true
+	obs := s.observe()
+	resp.Observation = &obs
+	return
+}
+
+func (s *Session) start(req Request) {
+	dir, file := filepath.Split(req.Level)
+	if dir == "" {
+		dir = "."
+	}
+	seed, l := LoadLevelFromYAML(os.DirFS(dir).(FS), file)
+	if req.Seed != nil {
+		seed = I64(*req.Seed)
+	}
+	s.playthrough.Seed = seed
+	s.playthrough.Level = l
+	s.playthrough.Id = uuid.New()
+	s.playthrough.History = nil
+	s.playthrough.Checksums = nil
+	s.startTime = time.Now().UTC()
+	s.world = NewWorldFromPlaythrough(s.playthrough)
+	s.started = true
+	s.saved = false
+	s.actionOk = true
+}
+
+func (s *Session) step(req Request) error {
+	// Bots choose tiles, but the playthrough records clicks like for human
+	// players, so that a move that isn't possible shows up as a failed click.
+	var input PlayerInput
+	input.MousePt = TileAimParams.TileToScreen(IPt(int(req.Action.X), int(req.Action.Y)))
+	switch req.Action.Type {
+	case "", "none":
+	case "move":
+		input.LeftButtonPressed = true
+	case "shoot":
+		input.RightButtonPressed = true
+	case "undo":
+		if !s.world.TurnBased {
+			return fmt.Errorf("undo is only possible in turn-based levels")
+		}
+		input.Undo = true
+	default:
+		return fmt.Errorf("unknown action type: %s", req.Action.Type)
+	}
+	input = TileAimParams.ResolveClicks(&s.world, input)
+	s.actionOk = input.LeftButtonPressed == input.Move &&
+		input.RightButtonPressed == input.Shoot
+
+	frames := max(req.Frames, 1)
+	for i := int64(0); i < frames && s.world.Status() == Ongoing; i++ {
+		Step(&s.playthrough, &s.world, input)
+		// A saved game that goes on must be saved again.
+		s.saved = false
+		frame := int64(len(s.playthrough.History) - 1)
+		for j := range s.world.StepEvents.N {
+			e := s.world.StepEvents.V[j]
+			s.events = append(s.events, EventObs{
+				Frame:  frame,
+				Type:   e.Type.String(),
+				X:      e.Pos.X.ToInt64(),
+				Y:      e.Pos.Y.ToInt64(),
+				Amount: e.Amount.ToInt64(),
+			})
+		}
+		// Only the first frame gets the action, the mouse stays where it was.
+		input = PlayerInput{MousePt: input.MousePt}
+	}
+	return nil
+}
+
+// save writes the playthrough to OutputDir and returns the name of the file.
+func (s *Session) save() string {
+	filename := filepath.Join(s.OutputDir, fmt.Sprintf("%s.mln%03d-%03d",
+		s.playthrough.Id, InputVersion, SimulationVersion))
+	h := NewPlaythroughHeader(&s.playthrough, &s.world)
+	h.Start = s.startTime
+	h.Tags = []string{"bot"}
+	WriteFile(filename, SerializePlaythroughFile(h, &s.playthrough))
+	s.saved = true
+	return filename
+}
+
+// saveIfUnsaved saves a game that was played but not saved yet, so that no
+// bot game is lost when the bot moves on to another level or quits.
+func (s *Session) saveIfUnsaved() string {
+	if !s.started || s.saved || len(s.playthrough.History) == 0 {
+		return ""
+	}
+	return s.save()
+}
+
+func (s *Session) observe() (o Observation) {
+	w := &s.world
+	o.Frame = int64(len(s.playthrough.History))
+	o.Status = w.Status().String()
+	o.Objective = w.ObjectivesText()
+	o.TurnBased = w.TurnBased
+	o.UseAmmo = w.UseAmmo
+	o.ActionOk = s.actionOk
+	o.Events = s.events
+
+	var pt Pt
+	for pt.Y = ZERO; pt.Y.Lt(I(NRows)); pt.Y.Inc() {
+		var obstacles, visible []bool
+		var terrain []string
+		var crateHealth []int64
+		for pt.X = ZERO; pt.X.Lt(I(NCols)); pt.X.Inc() {
+			obstacles = append(obstacles, w.Obstacles.At(pt))
+			terrain = append(terrain, w.Terrain.Get(pt).String())
+			health := ZERO
+			if w.Terrain.Get(pt) == Crate {
+				health = w.CrateHealth.Get(pt)
+			}
+			crateHealth = append(crateHealth, health.ToInt64())
+			visible = append(visible, w.Player.OnMap && w.VisibleTiles.At(pt))
+		}
+		o.Obstacles = append(o.Obstacles, obstacles)
+		o.Terrain = append(o.Terrain, terrain)
+		o.CrateHealth = append(o.CrateHealth, crateHealth)
+		o.Visible = append(o.Visible, visible)
+	}
+
+	p := &w.Player
+	o.Player = PlayerObs{
+		X:         p.Pos().X.ToInt64(),
+		Y:         p.Pos().Y.ToInt64(),
+		OnMap:     p.OnMap,
+		Health:    p.Health.ToInt64(),
+		MaxHealth: p.MaxHealth.ToInt64(),
+		Ammo:      p.AmmoCount.ToInt64(),
+	}
+	for i := range w.Enemies.N {
+		h := &w.Enemies.V[i]
+		o.Hounds = append(o.Hounds, HoundObs{
+			X:      h.Pos().X.ToInt64(),
+			Y:      h.Pos().Y.ToInt64(),
+			State:  h.State(),
+			Health: h.Health().ToInt64(),
+		})
+	}
+	for i := range w.Ammos.N {
+		a := &w.Ammos.V[i]
+		o.Ammos = append(o.Ammos, AmmoObs{a.Pos.X.ToInt64(), a.Pos.Y.ToInt64(),
+			a.Count.ToInt64()})
+	}
+	for i := range w.Pickups.N {
+		pk := &w.Pickups.V[i]
+		o.Pickups = append(o.Pickups, EntityObs{pk.Pos().X.ToInt64(),
+			pk.Pos().Y.ToInt64(), pk.State()})
+	}
+	for i := range w.Objectives.N {
+		ob := &w.Objectives.V[i]
+		if ob.State() == "" {
+			continue
+		}
+		o.Objectives = append(o.Objectives, EntityObs{ob.Pos().X.ToInt64(),
+			ob.Pos().Y.ToInt64(), ob.State()})
+	}
+	if w.TurnBased {
+		intents := w.EnemyIntents()
+		for i := range intents.N {
+			in := intents.V[i]
+			if in.Kind == NoIntent {
+				continue
+			}
+			o.Intents = append(o.Intents, IntentObs{
+				FromX:  in.From.X.ToInt64(),
+				FromY:  in.From.Y.ToInt64(),
+				ToX:    in.To.X.ToInt64(),
+				ToY:    in.To.Y.ToInt64(),
+				Attack: in.Kind == AttackIntent,
+			})
+		}
+	}
+	return
+}
@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func testLevel(t *testing.T) string {
+	var l Level
+	l.Terrain.Set(IPt(1, 0), Mud)
+	l.Terrain.Set(IPt(5, 6), Crate)
+	l.CrateMaxHealth = I(2)
+	l.Objectives.N = 1
+	l.Objectives.V[0] = ObjectiveParams{Type: Survive, Seconds: I(1)}
+	filename := t.TempDir() + "/level.yaml"
+	l.SaveToYAML(I(0), filename)
+	return filename
+}
+
+func TestSession(t *testing.T) {
+	s := NewSession(t.TempDir())
+	resp := s.Handle(Request{Cmd: "step"})
+	assert.False(t, resp.Ok)
+
+	resp = s.Handle(Request{Cmd: "start", Level: testLevel(t)})
+	assert.True(t, resp.Ok)
+	assert.Equal(t, "ongoing", resp.Observation.Status)
+	assert.Equal(t, NRows, len(resp.Observation.Obstacles))
+	assert.Equal(t, "ground", resp.Observation.Terrain[0][0])
+	assert.Equal(t, "mud", resp.Observation.Terrain[0][1])
+	assert.Equal(t, "crate", resp.Observation.Terrain[6][5])
+	assert.Equal(t, int64(2), resp.Observation.CrateHealth[6][5])
+	assert.Equal(t, int64(0), resp.Observation.CrateHealth[0][1])
+	assert.False(t, resp.Observation.Player.OnMap)
+
+	resp = s.Handle(Request{Cmd: "step", Frames: 5,
+		Action: Action{Type: "move", X: 2, Y: 3}})
+	assert.True(t, resp.Ok)
+	assert.True(t, resp.Observation.ActionOk)
+	assert.Equal(t, int64(5), resp.Observation.Frame)
+	assert.True(t, resp.Observation.Player.OnMap)
+	assert.Equal(t, int64(2), resp.Observation.Player.X)
+	assert.Equal(t, int64(3), resp.Observation.Player.Y)
+	assert.Equal(t, "PlayerMoved", resp.Observation.Events[0].Type)
+
+	resp = s.Handle(Request{Cmd: "step", Action: Action{Type: "jump"}})
+	assert.False(t, resp.Ok)
+
+	// Saving in the middle of the game doesn't stop the finished game from
+	// being saved.
+	resp = s.Handle(Request{Cmd: "save"})
+	assert.NotEmpty(t, resp.Playthrough)
+
+	// The level is won after one second and the game is saved.
+	resp = s.Handle(Request{Cmd: "step", Frames: 100})
+	assert.True(t, resp.Ok)
+	assert.Equal(t, "won", resp.Observation.Status)
+	assert.NotEmpty(t, resp.Playthrough)
+
+	p := DeserializePlaythrough(ReadFile(resp.Playthrough))
+	assert.Equal(t, int(resp.Observation.Frame), len(p.History))
+	assert.True(t, p.History[0].LeftButtonPressed)
+	assert.True(t, p.History[0].Move)
+	w := p.ReplayUpTo(len(p.History))
+	assert.Equal(t, Won, w.Status())
+
+	h, err := TryReadPlaythroughHeader(ReadFile(resp.Playthrough))
+	assert.Nil(t, err)
+	assert.Equal(t, "won", h.Status)
+	assert.Equal(t, []string{"bot"}, h.Tags)
+	assert.Equal(t, p.Id, h.Id)
+}
+
+func TestSession_Serve(t *testing.T) {
+	s := NewSession(t.TempDir())
+	in := strings.NewReader("{\"cmd\": \"start\", \"level\": \"" +
+		strings.ReplaceAll(testLevel(t), "\\", "\\\\") + "\"}\n" +
+		"not json\n" +
+		"{\"cmd\": \"quit\"}\n" +
+		"{\"cmd\": \"observe\"}\n")
+	out := new(bytes.Buffer)
+	assert.Nil(t, s.Serve(in, out))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, 3, len(lines))
+	var resp Response
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &resp))
+	assert.True(t, resp.Ok)
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &resp))
+	assert.False(t, resp.Ok)
+}
@@ -0,0 +1,318 @@
+// Package catalog indexes folders of playthroughs (e.g. the per-user folders
+// made by "analysis.exe download") so that they can be searched and summed up
+// without replaying thousands of files every time.
+// Each file is replayed once. What is learned from it is kept in an Entry, and
+// the entries are saved in an embedded key-value store (bbolt), along with
+// indexes by user, level and start time for queries. Replays are found again
+// by the hash of the file contents, so files can be moved or renamed without
+// being replayed again.
+package catalog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	bolt "go.etcd.io/bbolt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Entry is what the catalog knows about a playthrough file.
+type Entry struct {
+	// Path of the file, as found by Index.
+	File string `json:"file"`
+	// SHA-256 of the file contents.
+	Hash string `json:"hash"`
+	// SimulationVersion of the build that replayed the file. Entries made
+	// by another simulation are replayed again.
+	IndexedBy int64 `json:"indexed_by"`
+	// Why the file couldn't be read or replayed, empty if it could. The
+	// rest of the fields are only valid if Error is empty.
+	Error string `json:"error,omitempty"`
+
+	User              string    `json:"user"`
+	ReleaseVersion    int64     `json:"release_version"`
+	SimulationVersion int64     `json:"simulation_version"`
+	InputVersion      int64     `json:"input_version"`
+	Id                string    `json:"id"`
+	LevelHash         string    `json:"level_hash"`
+	Start             time.Time `json:"start"`
+	End               time.Time `json:"end"`
+	Tags              []string  `json:"tags,omitempty"`
+	// "ongoing", "won" or "lost".
+	Status  string `json:"status"`
+	Frames  int64  `json:"frames"`
+	Actions int64  `json:"actions"`
+	Undos   int64  `json:"undos"`
+	Health  int64  `json:"health"`
+}
+
+// Seconds is the duration of the playthrough in game time.
+func (e *Entry) Seconds() float64 {
+	return float64(e.Frames) / 60
+}
+
+// The buckets of the store. Keys and values are:
+// - replays: Hash -> Entry as made by NewEntry, without File
+// - files: File -> Entry
+// - users: User + sep + File -> nothing
+// - levels: LevelHash + sep + File -> nothing
+// - starts: Start (see startKey) + File -> nothing
+// Index rewrites all the buckets except replays, which only loses the replays
+// of files that are gone.
+var (
+	replaysBucket = []byte("replays")
+	filesBucket   = []byte("files")
+	usersBucket   = []byte("users")
+	levelsBucket  = []byte("levels")
+	startsBucket  = []byte("starts")
+)
+
+// sep ends the first part of an index key. It can't appear in a user name or
+// a level hash.
+const sep = "\x00"
+
+// Catalog is the index of a set of playthrough files.
+type Catalog struct {
+	db *bolt.DB
+}
+
+// Open opens the catalog saved in the file name, or creates an empty one if
+// the file doesn't exist yet. Only one process can have it open at a time.
+func Open(name string) *Catalog {
+	db, err := bolt.Open(name, 0600, &bolt.Options{Timeout: time.Second})
+	Check(err)
+	Check(db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{replaysBucket, filesBucket, usersBucket,
+			levelsBucket, startsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	return &Catalog{db}
+}
+
+func (c *Catalog) Close() {
+	Check(c.db.Close())
+}
+
+// IndexStats says how much work Index had to do.
+type IndexStats struct {
+	Files    int
+	Cached   int
+	Replayed int
+	Failed   int
+}
+
+// isPlaythroughFile matches the names given to saved playthroughs (e.g.
+// 20250511-091615.mln999-999). Crashes (see Crash.Filename) and levels (e.g.
+// practice-01.mln1000-level) are left out.
+func isPlaythroughFile(name string) bool {
+	matched, err := filepath.Match("*.mln*", filepath.Base(name))
+	Check(err)
+	return matched && !strings.HasSuffix(name, CrashSuffix) &&
+		!strings.HasSuffix(name, "-level")
+}
+
+// Index finds the playthrough files in dir and its subfolders and makes the
+// catalog list exactly those files. Files that were indexed before, under any
+// name, are not replayed again. A file that can't be read is listed with an
+// Error, like a file that can't be replayed.
+func (c *Catalog) Index(dir string) (s IndexStats) {
+	var entries []Entry
+	hashes := map[string]bool{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !isPlaythroughFile(path) {
+			return err
+		}
+		s.Files++
+		e := c.entry(path, &s)
+		hashes[e.Hash] = true
+		e.File = filepath.ToSlash(path)
+		if e.User == "" && e.Error == "" {
+			// Raw playthroughs don't know their user, but the download
+			// puts them in a folder named after the user.
+			e.User = filepath.Base(filepath.Dir(path))
+		}
+		if e.Error != "" {
+			s.Failed++
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	Check(err)
+
+	Check(c.db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{filesBucket, usersBucket, levelsBucket,
+			startsBucket} {
+			if err := tx.DeleteBucket(b); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(b); err != nil {
+				return err
+			}
+		}
+		for i := range entries {
+			if err := putEntry(tx, &entries[i]); err != nil {
+				return err
+			}
+		}
+		// Forget the replays of files that are gone.
+		replays := tx.Bucket(replaysBucket)
+		var gone [][]byte
+		err := replays.ForEach(func(k, v []byte) error {
+			if !hashes[string(k)] {
+				gone = append(gone, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range gone {
+			if err := replays.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	return
+}
+
+// entry reads the file and returns its replay, from the store if it was
+// replayed by this simulation before. A new replay is stored right away, so
+// that an Index that is interrupted doesn't lose it.
+func (c *Catalog) entry(path string, s *IndexStats) (e Entry) {
+	data, err := TryReadFile(path)
+	if err != nil {
+		return Entry{IndexedBy: SimulationVersion, Error: err.Error()}
+	}
+	hash := sha256.Sum256(data)
+	key := []byte(hex.EncodeToString(hash[:]))
+	Check(c.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(replaysBucket).Get(key); v != nil {
+			return json.Unmarshal(v, &e)
+		}
+		return nil
+	}))
+	if e.Hash != "" && e.IndexedBy == SimulationVersion {
+		s.Cached++
+		return
+	}
+
+	e = NewEntry(data)
+	s.Replayed++
+	v, err := json.Marshal(e)
+	Check(err)
+	Check(c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(replaysBucket).Put(key, v)
+	}))
+	return
+}
+
+// startKey sorts like the time, which Unix nanoseconds can't represent for
+// the zero time.
+func startKey(t time.Time) []byte {
+	return []byte(t.UTC().Format("20060102150405.000000000"))
+}
+
+func putEntry(tx *bolt.Tx, e *Entry) error {
+	v, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	file := []byte(e.File)
+	if err = tx.Bucket(filesBucket).Put(file, v); err != nil {
+		return err
+	}
+	if e.Error != "" {
+		// Queries never find these, see Query.Matches.
+		return nil
+	}
+	indexes := []struct{ bucket, key []byte }{
+		{usersBucket, []byte(e.User + sep + e.File)},
+		{levelsBucket, []byte(e.LevelHash + sep + e.File)},
+		{startsBucket, append(startKey(e.Start), file...)},
+	}
+	for _, i := range indexes {
+		if err = tx.Bucket(i.bucket).Put(i.key, []byte{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Entries returns the entries of all the files, sorted by File.
+func (c *Catalog) Entries() (entries []Entry) {
+	Check(c.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(filesBucket).ForEach(func(k, v []byte) error {
+			var e Entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			entries = append(entries, e)
+			return nil
+		})
+	}))
+	return
+}
+
+// NewEntry replays a playthrough file and describes it. A file that can't be
+// replayed gives an Entry with an Error.
+func NewEntry(data []byte) (e Entry) {
+	hash := sha256.Sum256(data)
+	e.Hash = hex.EncodeToString(hash[:])
+	e.IndexedBy = SimulationVersion
+	defer func() {
+		// The simulation panics on errors, see Check.
+		if r := recover(); r != nil {
+			e = Entry{Hash: e.Hash, IndexedBy: e.IndexedBy,
+				Error: fmt.Sprint(r)}
+		}
+	}()
+
+	h, err := TryReadPlaythroughHeader(data)
+	var p Playthrough
+	if err == nil {
+		p, err = TryDeserializePlaythrough(data)
+	}
+	var w World
+	if err == nil {
+		w, err = TryNewWorldFromPlaythrough(p)
+	}
+	if err != nil {
+		e.Error = err.Error()
+		return
+	}
+	for i := range p.History {
+		p.StepFrame(&w, i)
+		// Older playthroughs only have the clicks, not what they resolved to.
+		input := p.History[i]
+		if input.LeftButtonPressed || input.RightButtonPressed ||
+			input.Move || input.Shoot {
+			e.Actions++
+		}
+	}
+
+	e.User = h.User
+	e.ReleaseVersion = h.ReleaseVersion
+	e.SimulationVersion = h.SimulationVersion
+	e.InputVersion = h.InputVersion
+	e.Id = h.Id.String()
+	e.LevelHash = h.LevelHash
+	e.Start = h.Start
+	e.End = h.End
+	e.Tags = h.Tags
+	e.Status = w.Status().String()
+	e.Frames = int64(len(p.History))
+	e.Undos = int64(p.NUndos())
+	e.Health = w.Player.Health.ToInt64()
+	return
+}
@@ -0,0 +1,142 @@
+package catalog
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testFolder makes a folder like the ones made by "analysis.exe download":
+// - ana/a.mln999-999: a raw playthrough
+// - bob/b.mln1000-999: the same playthrough with a header for bob
+// - bob/bad.mln1000-999: not a playthrough
+// - bob/c.mln1000-999-crash: not indexed
+// - bob/l.mln1000-level: not indexed
+func testFolder(t *testing.T) (dir string, p Playthrough) {
+	raw := ReadFile("../world/playthroughs/average-playthrough.mln999-999")
+	p = DeserializePlaythrough(raw)
+	h := NewPlaythroughHeader(&p, nil)
+	h.User = "bob"
+	h.Start = time.Date(2025, 5, 11, 9, 16, 15, 0, time.UTC)
+	h.Tags = []string{"test"}
+
+	dir = t.TempDir()
+	Check(os.Mkdir(filepath.Join(dir, "ana"), os.ModePerm))
+	Check(os.Mkdir(filepath.Join(dir, "bob"), os.ModePerm))
+	WriteFile(filepath.Join(dir, "ana", "a.mln999-999"), raw)
+	WriteFile(filepath.Join(dir, "bob", "b.mln1000-999"),
+		SerializePlaythroughFile(h, &p))
+	WriteFile(filepath.Join(dir, "bob", "bad.mln1000-999"), []byte("bad"))
+	WriteFile(filepath.Join(dir, "bob", "c.mln1000-999-crash"), []byte("bad"))
+	WriteFile(filepath.Join(dir, "bob", "l.mln1000-level"), []byte("bad"))
+	return
+}
+
+func openTestCatalog(t *testing.T) *Catalog {
+	c := Open(filepath.Join(t.TempDir(), "catalog.db"))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestCatalog_Index(t *testing.T) {
+	dir, p := testFolder(t)
+	name := filepath.Join(t.TempDir(), "catalog.db")
+	c := Open(name)
+	s := c.Index(dir)
+	assert.Equal(t, IndexStats{Files: 3, Replayed: 3, Failed: 1}, s)
+
+	entries := c.Entries()
+	a, b, bad := entries[0], entries[1], entries[2]
+	assert.Equal(t, "ana", a.User)
+	assert.True(t, a.Start.IsZero())
+	assert.NotEmpty(t, bad.Error)
+	assert.Equal(t, "bob", b.User)
+	assert.Equal(t, []string{"test"}, b.Tags)
+	assert.Equal(t, LevelHash(p.Level), b.LevelHash)
+	assert.Equal(t, p.Id.String(), b.Id)
+	assert.Equal(t, int64(len(p.History)), b.Frames)
+	assert.Equal(t, int64(p.NUndos()), b.Undos)
+	w := p.ReplayUpTo(len(p.History))
+	assert.Equal(t, w.Status().String(), b.Status)
+	assert.Equal(t, w.Player.Health.ToInt64(), b.Health)
+	assert.Positive(t, b.Actions)
+
+	// The catalog survives being closed and files aren't replayed again, even
+	// if they are renamed.
+	c.Close()
+	c = Open(name)
+	defer c.Close()
+	Check(os.Rename(filepath.Join(dir, "ana", "a.mln999-999"),
+		filepath.Join(dir, "ana", "z.mln999-999")))
+	s = c.Index(dir)
+	assert.Equal(t, IndexStats{Files: 3, Cached: 3, Failed: 1}, s)
+	assert.Equal(t, filepath.ToSlash(filepath.Join(dir, "ana", "z.mln999-999")),
+		c.Entries()[0].File)
+
+	// Files that are gone are forgotten.
+	Check(os.Remove(filepath.Join(dir, "bob", "bad.mln1000-999")))
+	s = c.Index(dir)
+	assert.Equal(t, IndexStats{Files: 2, Cached: 2}, s)
+	assert.Len(t, c.Entries(), 2)
+
+	assert.Empty(t, openTestCatalog(t).Entries())
+}
+
+func TestCatalog_IndexUnreadableFile(t *testing.T) {
+	dir := t.TempDir()
+	err := os.Symlink(filepath.Join(dir, "missing"),
+		filepath.Join(dir, "a.mln1000-999"))
+	if err != nil {
+		t.Skip("symlinks are not available:", err)
+	}
+	c := openTestCatalog(t)
+	s := c.Index(dir)
+	assert.Equal(t, IndexStats{Files: 1, Failed: 1}, s)
+	assert.NotEmpty(t, c.Entries()[0].Error)
+}
+
+func TestCatalog_Find(t *testing.T) {
+	dir, p := testFolder(t)
+	c := openTestCatalog(t)
+	c.Index(dir)
+	status := c.Entries()[0].Status
+	found := func(q Query) (users []string) {
+		for _, e := range c.Find(q) {
+			users = append(users, e.User)
+		}
+		return
+	}
+
+	assert.Equal(t, []string{"ana", "bob"}, found(Query{}))
+	assert.Equal(t, []string{"bob"},
+		found(Query{Users: []string{"bob", "cid", "bob"}}))
+	assert.Empty(t, found(Query{Users: []string{"bo"}}))
+	assert.Equal(t, []string{"ana", "bob"},
+		found(Query{Level: LevelHash(p.Level)[:8], Status: status}))
+	assert.Empty(t, found(Query{Level: "x"}))
+	assert.Empty(t, found(Query{Status: "nope"}))
+	may := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"bob"}, found(Query{After: may}))
+	assert.Empty(t, found(Query{Before: may}))
+	assert.Equal(t, []string{"bob"},
+		found(Query{Before: may.AddDate(0, 1, 0)}))
+	assert.Equal(t, []string{"bob"}, found(Query{Tag: "test"}))
+}
+
+func TestAggregate(t *testing.T) {
+	entries := []Entry{
+		{User: "bob", Status: "won", Frames: 60, Actions: 4, Health: 3},
+		{User: "ana", Status: "lost", Frames: 120, Actions: 2, Health: 0},
+		{User: "bob", Status: "lost", Frames: 180, Actions: 2, Health: 0},
+	}
+	assert.Equal(t, []Group{
+		{Key: "ana", N: 1, Lost: 1, Seconds: 2, Actions: 2, Health: 0},
+		{Key: "bob", N: 2, Won: 1, Lost: 1, Seconds: 2, Actions: 3, Health: 1.5},
+	}, Aggregate(entries, "user"))
+	assert.Equal(t, "unknown", Aggregate(entries, "day")[0].Key)
+	assert.Panics(t, func() { Aggregate(entries, "color") })
+}
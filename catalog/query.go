@@ -0,0 +1,194 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	bolt "go.etcd.io/bbolt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Query selects entries. The zero value of a field means "any", so the zero
+// Query selects all the playthroughs that could be replayed.
+type Query struct {
+	// The cohort: only playthroughs of these users.
+	Users []string
+	// A prefix of the LevelHash is enough, like for git commits.
+	Level  string
+	Status string
+	// Only playthroughs started in [After, Before). Playthroughs without a
+	// start time (raw files) are left out if either is set.
+	After  time.Time
+	Before time.Time
+	// Only playthroughs of this release.
+	ReleaseVersion int64
+	Tag            string
+}
+
+func (q *Query) Matches(e *Entry) bool {
+	if e.Error != "" {
+		return false
+	}
+	if len(q.Users) > 0 && !slices.Contains(q.Users, e.User) {
+		return false
+	}
+	if !strings.HasPrefix(e.LevelHash, q.Level) {
+		return false
+	}
+	if q.Status != "" && q.Status != e.Status {
+		return false
+	}
+	if (!q.After.IsZero() || !q.Before.IsZero()) && e.Start.IsZero() {
+		return false
+	}
+	if !q.After.IsZero() && e.Start.Before(q.After) {
+		return false
+	}
+	if !q.Before.IsZero() && !e.Start.Before(q.Before) {
+		return false
+	}
+	if q.ReleaseVersion != 0 && q.ReleaseVersion != e.ReleaseVersion {
+		return false
+	}
+	if q.Tag != "" && !slices.Contains(e.Tags, q.Tag) {
+		return false
+	}
+	return true
+}
+
+// Find returns the entries that match q, sorted by File. It only reads the
+// entries listed by one of the indexes, if q narrows the search down to it.
+func (c *Catalog) Find(q Query) (entries []Entry) {
+	Check(c.db.View(func(tx *bolt.Tx) error {
+		files := tx.Bucket(filesBucket)
+		return q.scan(tx, func(file []byte) error {
+			var e Entry
+			if err := json.Unmarshal(files.Get(file), &e); err != nil {
+				return err
+			}
+			if q.Matches(&e) {
+				entries = append(entries, e)
+			}
+			return nil
+		})
+	}))
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return strings.Compare(a.File, b.File)
+	})
+	return
+}
+
+// scan calls f for each file that might match q, using the index that fits q
+// best. See the buckets in catalog.go.
+func (q *Query) scan(tx *bolt.Tx, f func(file []byte) error) error {
+	switch {
+	case len(q.Users) > 0:
+		users := slices.Clone(q.Users)
+		slices.Sort(users)
+		for _, u := range slices.Compact(users) {
+			err := scanPrefix(tx.Bucket(usersBucket), []byte(u+sep), f)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case q.Level != "":
+		return scanPrefix(tx.Bucket(levelsBucket), []byte(q.Level), f)
+	case !q.After.IsZero() || !q.Before.IsZero():
+		n := len(startKey(time.Time{}))
+		c := tx.Bucket(startsBucket).Cursor()
+		for k, _ := c.Seek(startKey(q.After)); k != nil; k, _ = c.Next() {
+			if !q.Before.IsZero() &&
+				bytes.Compare(k[:n], startKey(q.Before)) >= 0 {
+				break
+			}
+			if err := f(k[n:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return tx.Bucket(filesBucket).ForEach(func(k, v []byte) error {
+			return f(k)
+		})
+	}
+}
+
+// scanPrefix calls f for the file in each key of b that starts with prefix.
+// The keys are made of a value, sep and the file.
+func scanPrefix(b *bolt.Bucket, prefix []byte, f func(file []byte) error) error {
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		file := k[bytes.IndexByte(k, sep[0])+1:]
+		if err := f(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Group is a row of the table made by Aggregate.
+type Group struct {
+	Key     string
+	N       int
+	Won     int
+	Lost    int
+	Seconds float64 // average
+	Actions float64 // average
+	Health  float64 // average
+}
+
+// GroupKeys are the ways in which Aggregate can group entries.
+var GroupKeys = map[string]func(e *Entry) string{
+	"user":    func(e *Entry) string { return e.User },
+	"level":   func(e *Entry) string { return e.LevelHash },
+	"status":  func(e *Entry) string { return e.Status },
+	"release": func(e *Entry) string { return fmt.Sprint(e.ReleaseVersion) },
+	"day": func(e *Entry) string {
+		if e.Start.IsZero() {
+			return "unknown"
+		}
+		return e.Start.Format(time.DateOnly)
+	},
+}
+
+// Aggregate groups the entries by one of the GroupKeys, sorted by key.
+func Aggregate(entries []Entry, by string) (groups []Group) {
+	key, ok := GroupKeys[by]
+	if !ok {
+		panic(fmt.Errorf("unknown group key: %s", by))
+	}
+	idx := map[string]int{}
+	for i := range entries {
+		e := &entries[i]
+		k := key(e)
+		if _, ok := idx[k]; !ok {
+			idx[k] = len(groups)
+			groups = append(groups, Group{Key: k})
+		}
+		g := &groups[idx[k]]
+		g.N++
+		if e.Status == "won" {
+			g.Won++
+		} else if e.Status == "lost" {
+			g.Lost++
+		}
+		// Sum for now, divide at the end.
+		g.Seconds += e.Seconds()
+		g.Actions += float64(e.Actions)
+		g.Health += float64(e.Health)
+	}
+	for i := range groups {
+		n := float64(groups[i].N)
+		groups[i].Seconds /= n
+		groups[i].Actions /= n
+		groups[i].Health /= n
+	}
+	slices.SortFunc(groups, func(a, b Group) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return
+}
@@ -1,3 +1,5 @@
+//go:build !headless
+
 package gamelib
 
 import (
@@ -7,8 +9,6 @@ import (
 	"strconv"
 )
 
-var AnimationFps = I(10)
-
 // Animation represents an instance of a running animation.
 // It is cheap to copy this struct. You should make copies for every
 // instance of an animation that you need.
@@ -0,0 +1,50 @@
+package gamelib
+
+import (
+	"fmt"
+)
+
+// The Try functions (TryReadFile, TryUnzip etc.) return these errors, so that
+// tools which go through many files can tell why a file couldn't be loaded
+// and decide to skip it. Use errors.As to get them from a returned error.
+
+// VersionError means the data was saved by a build with a different version
+// (e.g. InputVersion) than the one that tries to load it.
+type VersionError struct {
+	What     string // what kind of version, e.g. "InputVersion"
+	Found    int64
+	Expected int64
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("%s mismatch: found %d, expected %d", e.What, e.Found,
+		e.Expected)
+}
+
+// CorruptDataError means the data can't be decoded: it is truncated, it is
+// not a zip archive, it is not valid YAML etc.
+type CorruptDataError struct {
+	Err error
+}
+
+func (e *CorruptDataError) Error() string {
+	return "corrupt data: " + e.Err.Error()
+}
+
+func (e *CorruptDataError) Unwrap() error {
+	return e.Err
+}
+
+// MissingFileError means the file doesn't exist.
+type MissingFileError struct {
+	Name string
+	Err  error
+}
+
+func (e *MissingFileError) Error() string {
+	return "missing file: " + e.Name
+}
+
+func (e *MissingFileError) Unwrap() error {
+	return e.Err
+}
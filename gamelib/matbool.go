@@ -169,13 +169,13 @@ func (m *MatBool) UnmarshalYAML(b []byte) error {
 		return nil
 	}
 
-	rows := strings.Split(s, "\n")
-	for rowIdx := range rows {
-		trimmedRow := strings.TrimSpace(rows[rowIdx])
-		innerRow := trimmedRow[3 : len(trimmedRow)-1]
-		tokens := strings.Split(innerRow, ",")
+	rows, err := SplitMatrixRows(s)
+	if err != nil {
+		return err
+	}
+	for rowIdx, tokens := range rows {
 		for cellIdx, token := range tokens {
-			if strings.TrimSpace(token) == "X" {
+			if token == "X" {
 				m.Set(IPt(cellIdx, rowIdx))
 			}
 		}
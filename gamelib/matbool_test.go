@@ -3,6 +3,7 @@ package gamelib
 import (
 	"github.com/stretchr/testify/assert"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -95,3 +96,13 @@ func Test_Yaml(t *testing.T) {
 	}
 	RunYamlTest(t, m)
 }
+
+func Test_YamlMalformedRows(t *testing.T) {
+	var m MatBool
+	assert.Error(t, m.UnmarshalYAML([]byte("- x")))
+	assert.Error(t, m.UnmarshalYAML([]byte("- [X, .]")))
+	assert.Error(t, m.UnmarshalYAML([]byte(
+		strings.Repeat("- [., ., ., ., ., ., ., .]\n", NRows+1))))
+	assert.Nil(t, m.UnmarshalYAML([]byte("- [X, ., ., ., ., ., ., .]")))
+	assert.True(t, m.At(IPt(0, 0)))
+}
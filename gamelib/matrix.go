@@ -1,5 +1,10 @@
 package gamelib
 
+import (
+	"fmt"
+	"strings"
+)
+
 const NRows = 8
 const NCols = 8
 
@@ -41,3 +46,32 @@ func (m *Matrix[T]) RandomPos(r *Rand) Pt {
 	pt.Y = r.RInt(ZERO, I(NCols).Minus(ONE))
 	return pt
 }
+
+// SplitMatrixRows splits the YAML of a matrix, one "- [a,b,c]" line per row,
+// into the tokens of each row. Hand-written levels get this wrong easily, so
+// it returns an error for a line that doesn't have this shape, for more than
+// NRows rows and for a row that doesn't have exactly NCols tokens.
+func SplitMatrixRows(s string) (rows [][]string, err error) {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > NRows {
+		return nil, fmt.Errorf("matrix has %d rows, at most %d are supported",
+			len(lines), NRows)
+	}
+	for rowIdx, line := range lines {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "- [") || !strings.HasSuffix(line, "]") {
+			return nil, fmt.Errorf("row %d is not of the form - [a,b,c]: %s",
+				rowIdx, line)
+		}
+		tokens := strings.Split(line[3:len(line)-1], ",")
+		if len(tokens) != NCols {
+			return nil, fmt.Errorf("row %d has %d columns instead of %d",
+				rowIdx, len(tokens), NCols)
+		}
+		for i := range tokens {
+			tokens[i] = strings.TrimSpace(tokens[i])
+		}
+		rows = append(rows, tokens)
+	}
+	return
+}
@@ -0,0 +1,231 @@
+package gamelib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// WriteParquet writes the table as a Parquet file. It is a minimal writer,
+// just enough for pandas, pyarrow, DuckDB etc. to read our exports without
+// pulling in a dependency: a single row group, one uncompressed data page per
+// column, PLAIN encoding, all columns required.
+// The metadata is encoded with the Thrift compact protocol, as the Parquet
+// format requires.
+func (t *Table) WriteParquet(w io.Writer) {
+	file := new(bytes.Buffer)
+	file.WriteString("PAR1")
+
+	type chunk struct {
+		offset int64
+		size   int64
+	}
+	chunks := make([]chunk, len(t.Names))
+	for col := range t.Names {
+		data := t.plainValues(col)
+
+		var header thriftWriter
+		header.fieldI32(1, 0) // type: DATA_PAGE
+		header.fieldI32(2, int32(len(data)))
+		header.fieldI32(3, int32(len(data)))
+		header.fieldStructBegin(5) // data_page_header
+		header.fieldI32(1, int32(len(t.Rows)))
+		header.fieldI32(2, 0) // encoding: PLAIN
+		header.fieldI32(3, 3) // definition_level_encoding: RLE
+		header.fieldI32(4, 3) // repetition_level_encoding: RLE
+		header.structEnd()
+		header.structEnd()
+
+		chunks[col].offset = int64(file.Len())
+		file.Write(header.Bytes())
+		file.Write(data)
+		chunks[col].size = int64(file.Len()) - chunks[col].offset
+	}
+
+	var meta thriftWriter
+	meta.fieldI32(1, 1) // version
+	meta.fieldListBegin(2, thriftStruct, len(t.Names)+1)
+	// Root of the schema.
+	meta.structBegin()
+	meta.fieldBinary(4, "schema")
+	meta.fieldI32(5, int32(len(t.Names)))
+	meta.structEnd()
+	for col, name := range t.Names {
+		meta.structBegin()
+		meta.fieldI32(1, parquetType(t.Types[col]))
+		meta.fieldI32(3, 0) // repetition_type: REQUIRED
+		meta.fieldBinary(4, name)
+		if t.Types[col] == StringColumn {
+			meta.fieldI32(6, 0) // converted_type: UTF8
+		}
+		meta.structEnd()
+	}
+	meta.fieldI64(3, int64(len(t.Rows)))
+	meta.fieldListBegin(4, thriftStruct, 1)
+	meta.structBegin() // row group
+	totalSize := int64(0)
+	for _, c := range chunks {
+		totalSize += c.size
+	}
+	meta.fieldListBegin(1, thriftStruct, len(t.Names))
+	for col, name := range t.Names {
+		meta.structBegin() // column chunk
+		meta.fieldI64(2, chunks[col].offset)
+		meta.fieldStructBegin(3) // column metadata
+		meta.fieldI32(1, parquetType(t.Types[col]))
+		meta.fieldListBegin(2, thriftI32, 1)
+		meta.i32(0) // PLAIN
+		meta.fieldListBegin(3, thriftBinary, 1)
+		meta.binary(name)
+		meta.fieldI32(4, 0) // codec: UNCOMPRESSED
+		meta.fieldI64(5, int64(len(t.Rows)))
+		meta.fieldI64(6, chunks[col].size)
+		meta.fieldI64(7, chunks[col].size)
+		meta.fieldI64(9, chunks[col].offset)
+		meta.structEnd()
+		meta.structEnd()
+	}
+	meta.fieldI64(2, totalSize)
+	meta.fieldI64(3, int64(len(t.Rows)))
+	meta.structEnd()
+	meta.fieldBinary(6, "miln")
+	meta.structEnd()
+
+	file.Write(meta.Bytes())
+	Check(binary.Write(file, binary.LittleEndian, uint32(len(meta.Bytes()))))
+	file.WriteString("PAR1")
+	_, err := w.Write(file.Bytes())
+	Check(err)
+}
+
+func parquetType(t ColumnType) int32 {
+	switch t {
+	case BoolColumn:
+		return 0 // BOOLEAN
+	case Int64Column:
+		return 2 // INT64
+	case Float64Column:
+		return 5 // DOUBLE
+	default:
+		return 6 // BYTE_ARRAY
+	}
+}
+
+// plainValues encodes a column with the PLAIN encoding. Required columns have
+// no definition or repetition levels, so this is the whole page.
+func (t *Table) plainValues(col int) []byte {
+	buf := new(bytes.Buffer)
+	switch t.Types[col] {
+	case Int64Column:
+		for _, row := range t.Rows {
+			Check(binary.Write(buf, binary.LittleEndian, row[col].(int64)))
+		}
+	case Float64Column:
+		for _, row := range t.Rows {
+			Check(binary.Write(buf, binary.LittleEndian, row[col].(float64)))
+		}
+	case StringColumn:
+		for _, row := range t.Rows {
+			s := row[col].(string)
+			Check(binary.Write(buf, binary.LittleEndian, uint32(len(s))))
+			buf.WriteString(s)
+		}
+	case BoolColumn:
+		// Bit-packed, least significant bit first.
+		packed := make([]byte, (len(t.Rows)+7)/8)
+		for i, row := range t.Rows {
+			if row[col].(bool) {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		buf.Write(packed)
+	}
+	return buf.Bytes()
+}
+
+// Thrift compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the few parts of the Thrift compact protocol needed
+// for the Parquet metadata.
+type thriftWriter struct {
+	bytes.Buffer
+	lastField  int16
+	fieldStack []int16
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	w.Write(binary.AppendUvarint(nil, v))
+}
+
+func (w *thriftWriter) zigzag(v int64) {
+	w.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftWriter) fieldHeader(id int16, typ byte) {
+	delta := id - w.lastField
+	if delta > 0 && delta <= 15 {
+		w.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.WriteByte(typ)
+		w.zigzag(int64(id))
+	}
+	w.lastField = id
+}
+
+func (w *thriftWriter) i32(v int32) { w.zigzag(int64(v)) }
+
+func (w *thriftWriter) binary(s string) {
+	w.varint(uint64(len(s)))
+	w.WriteString(s)
+}
+
+func (w *thriftWriter) fieldI32(id int16, v int32) {
+	w.fieldHeader(id, thriftI32)
+	w.i32(v)
+}
+
+func (w *thriftWriter) fieldI64(id int16, v int64) {
+	w.fieldHeader(id, thriftI64)
+	w.zigzag(v)
+}
+
+func (w *thriftWriter) fieldBinary(id int16, s string) {
+	w.fieldHeader(id, thriftBinary)
+	w.binary(s)
+}
+
+func (w *thriftWriter) fieldListBegin(id int16, elemType byte, size int) {
+	w.fieldHeader(id, thriftList)
+	if size < 15 {
+		w.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		w.WriteByte(0xF0 | elemType)
+		w.varint(uint64(size))
+	}
+}
+
+func (w *thriftWriter) fieldStructBegin(id int16) {
+	w.fieldHeader(id, thriftStruct)
+	w.structBegin()
+}
+
+// structBegin starts a struct which is not a field, e.g. a list element.
+func (w *thriftWriter) structBegin() {
+	w.fieldStack = append(w.fieldStack, w.lastField)
+	w.lastField = 0
+}
+
+func (w *thriftWriter) structEnd() {
+	w.WriteByte(0) // STOP
+	if len(w.fieldStack) > 0 {
+		w.lastField = w.fieldStack[len(w.fieldStack)-1]
+		w.fieldStack = w.fieldStack[:len(w.fieldStack)-1]
+	}
+}
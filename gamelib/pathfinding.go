@@ -1,6 +1,9 @@
 package gamelib
 
-import "slices"
+import (
+	"math"
+	"slices"
+)
 
 type PathArray struct {
 	N int64
@@ -91,3 +94,66 @@ func ComputePath(startPt, endPt Pt, m MatBool) (path PathArray) {
 	}
 	return
 }
+
+// ComputePathWithCosts is like ComputePath, except that stepping on a tile
+// costs costs.Get(tile) instead of always costing 1. The returned path is the
+// cheapest one, not the shortest one. Costs smaller than 1 are treated as 1.
+func ComputePathWithCosts(startPt, endPt Pt, m MatBool, costs Matrix[Int]) (path PathArray) {
+	const NNodes = NCols * NRows
+	const unreached = math.MaxInt64
+
+	var dist [NNodes]int64
+	var done [NNodes]bool
+	var parents [NNodes]int64
+	for i := range dist {
+		dist[i] = unreached
+		parents[i] = -1
+	}
+
+	start := m.PtToIndex(startPt).ToInt64()
+	end := m.PtToIndex(endPt).ToInt64()
+	dist[start] = 0
+
+	dirs := Directions8()
+	for {
+		// Take the closest node that wasn't processed yet. There are so few
+		// nodes that a linear search is fine. Ties go to the lowest index, so
+		// the result is deterministic.
+		node := int64(-1)
+		for i := range dist {
+			if !done[i] && dist[i] != unreached && (node < 0 || dist[i] < dist[node]) {
+				node = int64(i)
+			}
+		}
+		if node < 0 {
+			// The end can't be reached.
+			return
+		}
+
+		if node == end {
+			// Compute path.
+			for node >= 0 {
+				path.V[path.N] = m.IndexToPt(I64(node))
+				path.N++
+				node = parents[node]
+			}
+			slices.Reverse(path.V[0:path.N])
+			return
+		}
+		done[node] = true
+
+		pt := m.IndexToPt(I64(node))
+		for i := range dirs {
+			neighbor := pt.Plus(dirs[i])
+			if !m.InBounds(neighbor) || m.At(neighbor) {
+				continue
+			}
+			n := m.PtToIndex(neighbor).ToInt64()
+			cost := max(costs.Get(neighbor).ToInt64(), 1)
+			if dist[node]+cost < dist[n] {
+				dist[n] = dist[node] + cost
+				parents[n] = node
+			}
+		}
+	}
+}
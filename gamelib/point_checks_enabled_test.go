@@ -0,0 +1,21 @@
+//go:build int_overflow_checks_enabled
+
+package gamelib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestPt_Overflow(t *testing.T) {
+	assert.Panics(t, func() { IPt(math.MaxInt64, math.MaxInt64).Len() })
+	assert.Panics(t, func() { IPt(math.MinInt64, 34).Len() })
+	a := IPt(14037623, -3212809)
+	assert.Panics(t, func() { a.Scale(I(math.MaxInt64), I(math.MaxInt64)) })
+	assert.Panics(t, func() { IPt(3424543543, -943242123433).SquaredDistTo(IPt(-3424543543, 943242123433)) })
+	assert.Panics(t, func() { IPt(math.MaxInt64, math.MaxInt64).SquaredLen() })
+	assert.Panics(t, func() { IPt(math.MinInt64, 34).SquaredLen() })
+	assert.Panics(t, func() { IPt(math.MinInt64, math.MinInt64).To(IPt(math.MaxInt64, math.MaxInt64)) })
+	assert.Panics(t, func() { IPt(math.MaxInt64, math.MaxInt64).To(IPt(math.MinInt64, math.MinInt64)) })
+}
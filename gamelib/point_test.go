@@ -132,8 +132,6 @@ func TestPt_Len(t *testing.T) {
 	assert.Equal(t, IPt(-13, 5).Len(), I(13))                 // real length: 13.928..
 	assert.Equal(t, IPt(13, -5).Len(), I(13))                 // real length: 13.928..
 	assert.Equal(t, IPt(130034, 23458883).Len(), I(23459243)) // real length: 23,459,243.390..
-	assert.Panics(t, func() { IPt(math.MaxInt64, math.MaxInt64).Len() })
-	assert.Panics(t, func() { IPt(math.MinInt64, 34).Len() })
 }
 
 func TestPt_Scale(t *testing.T) {
@@ -158,7 +156,6 @@ func TestPt_Scale(t *testing.T) {
 	assert.Equal(t, a, IPt(0, 0))
 
 	a = IPt(14037623, -3212809)
-	assert.Panics(t, func() { a.Scale(I(math.MaxInt64), I(math.MaxInt64)) })
 }
 
 func TestPt_SetLen(t *testing.T) {
@@ -241,7 +238,6 @@ func TestPt_SquaredDistTo(t *testing.T) {
 	assert.Equal(t, IPt(1, 1).SquaredDistTo(IPt(0, 0)), I(2))
 	assert.Equal(t, IPt(6, -8).SquaredDistTo(IPt(4, 77)), I(7229))
 	assert.Equal(t, IPt(130034, 23458883).SquaredDistTo(IPt(0, 0)), I(550336100448845))
-	assert.Panics(t, func() { IPt(3424543543, -943242123433).SquaredDistTo(IPt(-3424543543, 943242123433)) })
 }
 
 func TestPt_SquaredLen(t *testing.T) {
@@ -253,8 +249,6 @@ func TestPt_SquaredLen(t *testing.T) {
 	assert.Equal(t, IPt(-13, 5).SquaredLen(), I(194))
 	assert.Equal(t, IPt(13, -5).SquaredLen(), I(194))
 	assert.Equal(t, IPt(130034, 23458883).SquaredLen(), I(550336100448845))
-	assert.Panics(t, func() { IPt(math.MaxInt64, math.MaxInt64).SquaredLen() })
-	assert.Panics(t, func() { IPt(math.MinInt64, 34).SquaredLen() })
 }
 
 func TestPt_To(t *testing.T) {
@@ -263,6 +257,4 @@ func TestPt_To(t *testing.T) {
 	assert.Equal(t, IPt(6, -8).To(IPt(4, 77)), IPt(-2, 85))
 	assert.Equal(t, IPt(123, 0).To(IPt(122, 1)), IPt(-1, 1))
 	assert.Equal(t, IPt(3424543543, -943242123433).To(IPt(-3424543543, 943242123433)), IPt(-6849087086, 1886484246866))
-	assert.Panics(t, func() { IPt(math.MinInt64, math.MinInt64).To(IPt(math.MaxInt64, math.MaxInt64)) })
-	assert.Panics(t, func() { IPt(math.MaxInt64, math.MaxInt64).To(IPt(math.MinInt64, math.MinInt64)) })
 }
@@ -0,0 +1,128 @@
+package gamelib
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Table is a simple in-memory table meant for exporting data to tools outside
+// of Go (spreadsheets, pandas etc). Every value in a column has the same type,
+// which is one of: int64, float64, string, bool.
+type Table struct {
+	Names []string
+	Types []ColumnType
+	Rows  [][]any
+}
+
+type ColumnType int
+
+const (
+	Int64Column ColumnType = iota
+	StringColumn
+	BoolColumn
+	Float64Column
+)
+
+func (t *Table) AddColumn(name string, typ ColumnType) {
+	t.Names = append(t.Names, name)
+	t.Types = append(t.Types, typ)
+}
+
+// AddRow adds a row to the table. The values must match the columns, in
+// number and type.
+func (t *Table) AddRow(values ...any) {
+	if len(values) != len(t.Names) {
+		Check(fmt.Errorf("table has %d columns, got %d values",
+			len(t.Names), len(values)))
+	}
+	for i, v := range values {
+		ok := false
+		switch t.Types[i] {
+		case Int64Column:
+			_, ok = v.(int64)
+		case StringColumn:
+			_, ok = v.(string)
+		case BoolColumn:
+			_, ok = v.(bool)
+		case Float64Column:
+			_, ok = v.(float64)
+		}
+		if !ok {
+			Check(fmt.Errorf("wrong type %T for column %s", v, t.Names[i]))
+		}
+	}
+	t.Rows = append(t.Rows, values)
+}
+
+func (t *Table) WriteCSV(w io.Writer) {
+	cw := csv.NewWriter(w)
+	Check(cw.Write(t.Names))
+	record := make([]string, len(t.Names))
+	for _, row := range t.Rows {
+		for i, v := range row {
+			switch v := v.(type) {
+			case int64:
+				record[i] = strconv.FormatInt(v, 10)
+			case string:
+				record[i] = v
+			case bool:
+				record[i] = strconv.FormatBool(v)
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'g', -1, 64)
+			}
+		}
+		Check(cw.Write(record))
+	}
+	cw.Flush()
+	Check(cw.Error())
+}
+
+// WriteJSONL writes one JSON object per row, with the keys in column order.
+func (t *Table) WriteJSONL(w io.Writer) {
+	bw := bufio.NewWriter(w)
+	for _, row := range t.Rows {
+		var sb strings.Builder
+		sb.WriteString("{")
+		for i, v := range row {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			key, err := json.Marshal(t.Names[i])
+			Check(err)
+			val, err := json.Marshal(v)
+			Check(err)
+			sb.Write(key)
+			sb.WriteString(":")
+			sb.Write(val)
+		}
+		sb.WriteString("}\n")
+		_, err := bw.WriteString(sb.String())
+		Check(err)
+	}
+	Check(bw.Flush())
+}
+
+// WriteTable writes the table to a file. The format is one of: csv, jsonl,
+// parquet.
+func WriteTable(filename string, format string, t *Table) {
+	f, err := os.Create(filename)
+	Check(err)
+	defer CloseFile(f)
+
+	switch format {
+	case "csv":
+		t.WriteCSV(f)
+	case "jsonl":
+		t.WriteJSONL(f)
+	case "parquet":
+		t.WriteParquet(f)
+	default:
+		Check(fmt.Errorf("unknown table format: %s", format))
+	}
+}
@@ -0,0 +1,64 @@
+package gamelib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func testTable() (t Table) {
+	t.AddColumn("frame", Int64Column)
+	t.AddColumn("id", StringColumn)
+	t.AddColumn("on_map", BoolColumn)
+	t.AddColumn("ratio", Float64Column)
+	t.AddRow(int64(1), "a,b", true, 0.5)
+	t.AddRow(int64(2), "c", false, 2.0)
+	return
+}
+
+func TestTable_CSVAndJSONL(t *testing.T) {
+	table := testTable()
+
+	buf := new(bytes.Buffer)
+	table.WriteCSV(buf)
+	assert.Equal(t, "frame,id,on_map,ratio\n1,\"a,b\",true,0.5\n2,c,false,2\n", buf.String())
+
+	buf.Reset()
+	table.WriteJSONL(buf)
+	assert.Equal(t, `{"frame":1,"id":"a,b","on_map":true,"ratio":0.5}`+"\n"+
+		`{"frame":2,"id":"c","on_map":false,"ratio":2}`+"\n", buf.String())
+}
+
+func TestTable_Parquet(t *testing.T) {
+	table := testTable()
+	buf := new(bytes.Buffer)
+	table.WriteParquet(buf)
+	data := buf.Bytes()
+
+	// The file starts and ends with the magic number and the footer length
+	// points to the start of the metadata.
+	assert.Equal(t, "PAR1", string(data[:4]))
+	assert.Equal(t, "PAR1", string(data[len(data)-4:]))
+	footerLen := binary.LittleEndian.Uint32(data[len(data)-8 : len(data)-4])
+	assert.Less(t, int(footerLen), len(data)-12)
+
+	// The first column chunk is a page header followed by the plain int64
+	// values. The header bytes are derived by hand from the Thrift compact
+	// protocol specification.
+	header := []byte{
+		0x15, 0x00, // type: DATA_PAGE
+		0x15, 0x20, // uncompressed_page_size: 16
+		0x15, 0x20, // compressed_page_size: 16
+		0x2C,       // data_page_header
+		0x15, 0x04, // num_values: 2
+		0x15, 0x00, // encoding: PLAIN
+		0x15, 0x06, // definition_level_encoding: RLE
+		0x15, 0x06, // repetition_level_encoding: RLE
+		0x00, 0x00, // end of structs
+	}
+	assert.Equal(t, header, data[4:4+len(header)])
+	values := data[4+len(header) : 4+len(header)+16]
+	assert.Equal(t, uint64(1), binary.LittleEndian.Uint64(values[:8]))
+	assert.Equal(t, uint64(2), binary.LittleEndian.Uint64(values[8:]))
+}
@@ -10,10 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/goccy/go-yaml"
-	"github.com/hajimehoshi/ebiten/v2"
-	"image"
 	"image/color"
-	"image/png"
 	"io"
 	"io/fs"
 	"math"
@@ -80,11 +77,33 @@ func DeleteFile(name string) {
 }
 
 func ReadFile(name string) []byte {
-	data, err := os.ReadFile(name)
+	data, err := TryReadFile(name)
 	Check(err)
 	return data
 }
 
+// fileError turns the error of opening or reading a file into a
+// MissingFileError, if that is why it failed.
+func fileError(name string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return &MissingFileError{name, err}
+	}
+	return err
+}
+
+// TryReadFile returns a MissingFileError if the file doesn't exist and the
+// error of os.ReadFile for anything else (e.g. no permission).
+func TryReadFile(name string) ([]byte, error) {
+	data, err := os.ReadFile(name)
+	return data, fileError(name, err)
+}
+
+// TryReadFileFS is TryReadFile for a file in fsys.
+func TryReadFileFS(fsys FS, name string) ([]byte, error) {
+	data, err := fsys.ReadFile(name)
+	return data, fileError(name, err)
+}
+
 func FileExists(fsys FS, name string) bool {
 	file, err := fsys.Open(name)
 	if err == nil {
@@ -213,8 +232,17 @@ func Serialize(w io.Writer, data any) {
 }
 
 func Deserialize(r io.Reader, data any) {
+	Check(TryDeserialize(r, data))
+}
+
+// TryDeserialize returns a CorruptDataError if r ends before data is filled.
+// It can't tell if the values make sense.
+func TryDeserialize(r io.Reader, data any) error {
 	err := binary.Read(r, binary.LittleEndian, data)
-	Check(err)
+	if err != nil {
+		return &CorruptDataError{err}
+	}
+	return nil
 }
 
 func SerializeSlice[T any](buf *bytes.Buffer, s []T) {
@@ -223,10 +251,25 @@ func SerializeSlice[T any](buf *bytes.Buffer, s []T) {
 }
 
 func DeserializeSlice[T any](buf *bytes.Buffer, s *[]T) {
+	Check(TryDeserializeSlice(buf, s))
+}
+
+// TryDeserializeSlice checks the length of the slice against the data left in
+// buf before allocating it, so that corrupt data can't make it allocate all
+// the memory. It returns a CorruptDataError if the length is wrong.
+func TryDeserializeSlice[T any](buf *bytes.Buffer, s *[]T) error {
 	var lenSlice int64
-	Deserialize(buf, &lenSlice)
+	if err := TryDeserialize(buf, &lenSlice); err != nil {
+		return err
+	}
+	var elem T
+	elemSize := int64(binary.Size(elem))
+	if lenSlice < 0 || elemSize > 0 && lenSlice > int64(buf.Len())/elemSize {
+		return &CorruptDataError{fmt.Errorf("slice of %d elements is longer "+
+			"than the %d bytes left", lenSlice, buf.Len())}
+	}
 	*s = make([]T, lenSlice)
-	Deserialize(buf, *s)
+	return TryDeserialize(buf, *s)
 }
 
 type TimedFunction func()
@@ -253,10 +296,21 @@ func LoadJSON(fsys FS, filename string, v any) {
 }
 
 func LoadYAML(fsys FS, filename string, v any) {
-	data, err := fsys.ReadFile(filename)
-	Check(err)
-	err = yaml.Unmarshal(data, v)
-	Check(err)
+	Check(TryLoadYAML(fsys, filename, v))
+}
+
+// TryLoadYAML returns a MissingFileError or a CorruptDataError for a file that
+// isn't valid YAML. Fields of v that are not in the file are left as they are,
+// so a file meant for another structure usually loads without an error.
+func TryLoadYAML(fsys FS, filename string, v any) error {
+	data, err := TryReadFileFS(fsys, filename)
+	if err != nil {
+		return err
+	}
+	if err = yaml.Unmarshal(data, v); err != nil {
+		return &CorruptDataError{err}
+	}
+	return nil
 }
 
 func SaveYAML(filename string, v any) {
@@ -303,42 +357,43 @@ func Home(relativePath string) string {
 }
 
 func Unzip(data []byte) []byte {
+	content, err := TryUnzip(data)
+	Check(err)
+	return content
+}
+
+// TryUnzip returns a CorruptDataError if data is not a zip archive with
+// exactly one file in it.
+func TryUnzip(data []byte) (content []byte, err error) {
 	// Get a bytes.Reader, which implements the io.ReaderAt interface required
 	// by the zip.NewReader() function.
 	bytesReader := bytes.NewReader(data)
 
 	// Open a zip archive for reading.
 	r, err := zip.NewReader(bytesReader, int64(len(data)))
-	Check(err)
+	if err != nil {
+		return nil, &CorruptDataError{err}
+	}
 
 	// We assume there's exactly 1 file in the zip archive.
 	if len(r.File) != 1 {
-		Check(errors.New(fmt.Sprintf("expected exactly one file in zip archive, got: %d", len(r.File))))
+		return nil, &CorruptDataError{fmt.Errorf("expected exactly one file "+
+			"in zip archive, got: %d", len(r.File))}
 	}
 
-	// Get a reader for that 1 file.
-	f := r.File[0]
-	rc, err := f.Open()
-	Check(err)
-	defer func(rc io.ReadCloser) { Check(rc.Close()) }(rc)
-
-	// Keep reading bytes, 1024 bytes at a time.
-	buffer := make([]byte, 1024)
-	fullContent := make([]byte, 0, 1024)
-	for {
-		nbytesActuallyRead, err := rc.Read(buffer)
-		fullContent = append(fullContent, buffer[:nbytesActuallyRead]...)
-		if err == io.EOF {
-			break
-		}
-		Check(err)
-		if nbytesActuallyRead == 0 {
-			break
-		}
+	// Read the whole file.
+	rc, err := r.File[0].Open()
+	if err != nil {
+		return nil, &CorruptDataError{err}
 	}
-
-	// Return bytes.
-	return fullContent
+	content, err = io.ReadAll(rc)
+	if closeErr := rc.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, &CorruptDataError{err}
+	}
+	return content, nil
 }
 
 func UnzipFromFile(filename string) []byte {
@@ -372,29 +427,6 @@ func ZipToFile(filename string, data []byte) {
 	WriteFile(filename, Zip(data))
 }
 
-func SaveImage(str string, img *ebiten.Image) {
-	file, err := os.Create(str)
-	defer func(file *os.File) { Check(file.Close()) }(file)
-	Check(err)
-
-	err = png.Encode(file, img)
-	Check(err)
-}
-
-func LoadImage(fsys FS, str string) *ebiten.Image {
-	file, err := fsys.Open(str)
-	defer func(file fs.File) { Check(file.Close()) }(file)
-	Check(err)
-
-	img, _, err := image.Decode(file)
-	Check(err)
-	if err != nil {
-		return nil
-	}
-
-	return ebiten.NewImageFromImage(img)
-}
-
 func EqualFloats(f1, f2 float64) bool {
 	return math.Abs(f1-f2) < 0.000001
 }
@@ -416,20 +448,6 @@ func Remove[S ~[]E, E any](s S, i int) S {
 	return s[:len(s)-1]
 }
 
-func ComputeSpriteMask(img *ebiten.Image) *ebiten.Image {
-	mask := ebiten.NewImageFromImage(img)
-	sz := mask.Bounds().Size()
-	for y := 0; y < sz.Y; y++ {
-		for x := 0; x < sz.X; x++ {
-			_, _, _, a := img.At(x, y).RGBA()
-			if a > 0 {
-				mask.Set(x, y, Col(0, 0, 0, 255))
-			}
-		}
-	}
-	return mask
-}
-
 func Directions8() [8]Pt {
 	// This order is needed so that straight lines get priority in pathfinding.
 	return [8]Pt{
@@ -480,100 +498,8 @@ func SplitInLines(content []byte) (lines []string) {
 	return
 }
 
-// drawnOffset computes the offset between the (0, 0) of the window and the
-// drawn region.
-// See GameToOs for an explanation of what the drawn region is.
-func drawnSizeAndOffset(layout Pt) (drawnSize, drawnOffset Pt) {
-	// Check if going from windowSize to drawnSize, we need to adjust the width
-	// or the height. Either the drawnSize width matches the windowSize width or
-	// the drawnSize height matches the windowSize height.
-	windowSize := IPt(ebiten.WindowSize())
-	widthsMatch := windowSize.X.Times(layout.Y).DivBy(layout.X).Lt(windowSize.Y)
-	if widthsMatch {
-		drawnSize.X = windowSize.X
-		drawnSize.Y = layout.Y.Times(windowSize.X).DivBy(layout.X)
-	} else {
-		drawnSize.X = layout.X.Times(windowSize.Y).DivBy(layout.Y)
-		drawnSize.Y = windowSize.Y
-	}
-
-	drawnOffset = windowSize.Minus(drawnSize).DivBy(TWO)
-	return
-}
-
-// OsToGame converts an (x, y) position from the "OS coordinate system" to the
-// "Game coordinate system". See GameToOs for an explanation of what these
-// coordinate systems are.
-//
-// Basically it transforms what is returned by robotgo.Location() to match
-// what is returned by ebiten.CursorPosition(). The main use of this function
-// is to check that the conversion from OS to Game is correct (matches what
-// is returned by ebiten.CursorPosition()), so that we can then implement
-// GameToOs by reversing the operations.
-func OsToGame(os, layout Pt) (game Pt) {
-	// OS -> Window
-	window := os.Minus(IPt(ebiten.WindowPosition()))
-
-	// Window -> Drawn region
-	drawnSize, drawnOffset := drawnSizeAndOffset(layout)
-	drawn := window.Minus(drawnOffset)
-
-	// Drawn -> Game
-	game.X = drawn.X.Times(layout.X).DivBy(drawnSize.X)
-	game.Y = drawn.Y.Times(layout.Y).DivBy(drawnSize.Y)
-	return
-}
-
-// GameToOs converts an (x, y) position from the "Game coordinate system" to the
-// "OS coordinate system". See below for an explanation of what these coordinate
-// systems are.
-//
-// OS coordinate system: (0, 0) is the top-left of the monitor, x, y is the
-// number of pixels to the right and down from that corner. If the OS has
-// a resolution of 1920x1080 then the most bottom-right pixel is
-// (1919, 1079).
-//
-// Window coordinate system: (0, 0) is the top-left pixel in the window
-// spawned when the game is started. The size of this area is set and
-// retrieved using ebiten.SetWindowSize() and ebiten.WindowSize(). The
-// position of this area within the OS coordinate system is set and
-// retrieved using ebiten.SetWindowPosition() and ebiten.WindowPosition().
-// This window contains the game's drawn region. A pixel in this coordinate
-// system has the same size as in the OS. So if ebiten.WindowSize() returns
-// (13, 25) and ebiten.WindowPosition() returns (20, 30), then the
-// bottom-right pixel in the window is (12, 24), corresponding to pixel
-// (32, 54) in the OS coordinate system.
-//
-// Drawn region coordinate system: (0, 0) is the top-left pixel inside the
-// game's window that is actually drawn. A pixel in this coordinate system
-// has the same size as in the Window coordinate system and OS. The drawn
-// region has its width or height equal to the window, but the other
-// dimension is equal or smaller. This is so that the drawn region always
-// fits inside the window. The dimensions of the drawn region depend on
-// what the game's Layout() function returns. If Layout() returns a width
-// and height proportional to the width and height returned by WindowSize(),
-// then the drawn region will fill the window perfectly.
-//
-// Game coordinate system: (0, 0) is the top-left pixel inside the game's
-// window that is actually drawn. Layout() returns the number of pixels in
-// this coordinate system. A pixel in this coordinate system is not the same
-// as in the OS coordinate system. It is scaled so that layout width matches
-// the drawn region's width and the layout height matches the drawn region's
-// height.
-func GameToOs(game, layout Pt) (os Pt) {
-	// Game -> Drawn region
-	drawnSize, drawnOffset := drawnSizeAndOffset(layout)
-	drawn := Pt{}
-	drawn.X = game.X.Times(drawnSize.X).DivBy(layout.X)
-	drawn.Y = game.Y.Times(drawnSize.Y).DivBy(layout.Y)
-
-	// Drawn region -> Window
-	window := drawn.Plus(drawnOffset)
-
-	// Window -> OS
-	os = window.Plus(IPt(ebiten.WindowPosition()))
-	return
-}
+// AnimationFps is the speed of all animations, in frames per second.
+var AnimationFps = I(10)
 
 type Cooldown struct {
 	Duration Int
@@ -21,13 +21,37 @@ func UploadDataToDbHttp(user string,
 	id uuid.UUID, data []byte) {
 }
 
+func TryInitializeIdInDbHttp(user string,
+	releaseVersion int64,
+	simulationVersion int64,
+	inputVersion int64,
+	id uuid.UUID) error {
+	return nil
+}
+
+func TryUploadDataToDbHttp(user string,
+	releaseVersion int64,
+	simulationVersion int64,
+	inputVersion int64,
+	id uuid.UUID, data []byte) error {
+	return nil
+}
+
 func SetUserDataHttp(user string, data string) {
 }
 
+func TrySetUserDataHttp(user string, data string) error {
+	return nil
+}
+
 func GetUserDataHttp(user string) string {
 	return ""
 }
 
+func TryGetUserDataHttp(user string) (string, error) {
+	return "", nil
+}
+
 func ConnectToDbSql() *sql.DB {
 	return nil
 }
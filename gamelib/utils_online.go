@@ -17,38 +17,47 @@ import (
 
 // makeHttpRequest makes a POST HTTP request to an endpoint and returns the
 // body of the response as a string.
-func makeHttpRequest(url string, fields map[string]string, files map[string][]byte) string {
+func makeHttpRequest(url string, fields map[string]string, files map[string][]byte) (string, error) {
 	// Create a buffer to write our multipart form data.
 	var requestBody bytes.Buffer
 	writer := multipart.NewWriter(&requestBody)
 	for k, v := range fields {
-		err := writer.WriteField(k, v)
-		Check(err)
+		if err := writer.WriteField(k, v); err != nil {
+			return "", err
+		}
 	}
 	for k, v := range files {
 		part, err := writer.CreateFormFile(k, k)
-		Check(err)
-		_, err = part.Write(v)
-		Check(err)
+		if err != nil {
+			return "", err
+		}
+		if _, err = part.Write(v); err != nil {
+			return "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
 	}
-	err := writer.Close()
-	Check(err)
 
 	// Create a POST request with the multipart form data.
 	request, err := http.NewRequest("POST", url, &requestBody)
-	Check(err)
+	if err != nil {
+		return "", err
+	}
 	request.Header.Set("content-type", writer.FormDataContentType())
 
 	// Perform the request.
 	client := &http.Client{}
 	response, err := client.Do(request)
-	Check(err)
+	if err != nil {
+		return "", err
+	}
+	defer func(body io.ReadCloser) { _ = body.Close() }(response.Body)
 	if response.StatusCode != 200 {
-		Check(fmt.Errorf("http request failed: %d", response.StatusCode))
+		return "", fmt.Errorf("http request failed: %d", response.StatusCode)
 	}
 	data, err := io.ReadAll(response.Body)
-	Check(err)
-	return string(data)
+	return string(data), err
 }
 
 func InitializeIdInDbHttp(user string,
@@ -56,8 +65,20 @@ func InitializeIdInDbHttp(user string,
 	simulationVersion int64,
 	inputVersion int64,
 	id uuid.UUID) {
+	Check(TryInitializeIdInDbHttp(user, releaseVersion, simulationVersion,
+		inputVersion, id))
+}
+
+// TryInitializeIdInDbHttp returns an error if the server can't be reached or
+// doesn't answer with 200. A 200 doesn't mean the row was inserted, the
+// script answers with 200 if it can't connect to the database.
+func TryInitializeIdInDbHttp(user string,
+	releaseVersion int64,
+	simulationVersion int64,
+	inputVersion int64,
+	id uuid.UUID) error {
 	url := "https://playful-patterns.com/submit-playthrough.php"
-	makeHttpRequest(url,
+	_, err := makeHttpRequest(url,
 		map[string]string{
 			"user":               user,
 			"release_version":    strconv.FormatInt(releaseVersion, 10),
@@ -65,6 +86,7 @@ func InitializeIdInDbHttp(user string,
 			"input_version":      strconv.FormatInt(inputVersion, 10),
 			"id":                 id.String()},
 		map[string][]byte{})
+	return err
 }
 
 func UploadDataToDbHttp(user string,
@@ -72,8 +94,19 @@ func UploadDataToDbHttp(user string,
 	simulationVersion int64,
 	inputVersion int64,
 	id uuid.UUID, data []byte) {
+	Check(TryUploadDataToDbHttp(user, releaseVersion, simulationVersion,
+		inputVersion, id, data))
+}
+
+// TryUploadDataToDbHttp fails like TryInitializeIdInDbHttp. The data is only
+// stored if the id was initialized before.
+func TryUploadDataToDbHttp(user string,
+	releaseVersion int64,
+	simulationVersion int64,
+	inputVersion int64,
+	id uuid.UUID, data []byte) error {
 	url := "https://playful-patterns.com/submit-playthrough.php"
-	makeHttpRequest(url,
+	_, err := makeHttpRequest(url,
 		map[string]string{
 			"user":               user,
 			"release_version":    strconv.FormatInt(releaseVersion, 10),
@@ -81,16 +114,33 @@ func UploadDataToDbHttp(user string,
 			"input_version":      strconv.FormatInt(inputVersion, 10),
 			"id":                 id.String()},
 		map[string][]byte{"playthrough": data})
+	return err
 }
 
 func SetUserDataHttp(user string, data string) {
+	Check(TrySetUserDataHttp(user, data))
+}
+
+// TrySetUserDataHttp returns an error if the server can't be reached or
+// doesn't answer with 200.
+func TrySetUserDataHttp(user string, data string) error {
 	url := "https://playful-patterns.com/set-user-data.php"
-	makeHttpRequest(url,
+	_, err := makeHttpRequest(url,
 		map[string]string{"user": user, "data": data},
 		map[string][]byte{})
+	return err
 }
 
 func GetUserDataHttp(user string) string {
+	data, err := TryGetUserDataHttp(user)
+	Check(err)
+	return data
+}
+
+// TryGetUserDataHttp returns the body of the response, which is empty for a
+// user without data, or an error if the server can't be reached or doesn't
+// answer with 200.
+func TryGetUserDataHttp(user string) (string, error) {
 	url := "https://playful-patterns.com/get-user-data.php"
 	return makeHttpRequest(url,
 		map[string]string{"user": user},
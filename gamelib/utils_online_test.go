@@ -0,0 +1,39 @@
+//go:build online
+
+package gamelib
+
+// These tests talk to the real database, so they only make sense in an online
+// build (the offline build doesn't store anything).
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDbSql(t *testing.T) {
+	db := ConnectToDbSql()
+	id := uuid.New()
+	InitializeIdInDbSql(db, id)
+	UploadDataToDbSql(db, id, []byte("what do you mean"))
+	InspectDataFromDbSql(db)
+	assert.True(t, true)
+}
+
+func TestDbHttp(t *testing.T) {
+	id := uuid.New()
+	// id, err := uuid.Parse("550e8400-e29b-41d4-a716-446655440002")
+	// Check(err)
+	InitializeIdInDbHttp("test-user", 19, 999, 999, id)
+	UploadDataToDbHttp("test-user", 19, 999, 999, id, []byte("mele 1"))
+	UploadDataToDbHttp("test-user", 19, 999, 999, id, []byte("mele 2"))
+	UploadDataToDbHttp("test-user", 19, 999, 999, id, []byte("mele totusi, da -------"))
+
+	SetUserDataHttp("test-user1", "test-data1")
+	data := GetUserDataHttp("test-user1")
+	assert.Equal(t, "test-data1", data)
+
+	SetUserDataHttp("test-user1", "test-data2")
+	data = GetUserDataHttp("test-user1")
+	assert.Equal(t, "test-data2", data)
+}
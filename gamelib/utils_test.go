@@ -2,7 +2,6 @@ package gamelib
 
 import (
 	"bytes"
-	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"slices"
 	"testing"
@@ -23,33 +22,6 @@ func TestZip(t *testing.T) {
 	assert.Equal(t, zippedData2, zippedData3)
 }
 
-func TestDbSql(t *testing.T) {
-	db := ConnectToDbSql()
-	id := uuid.New()
-	InitializeIdInDbSql(db, id)
-	UploadDataToDbSql(db, id, []byte("what do you mean"))
-	InspectDataFromDbSql(db)
-	assert.True(t, true)
-}
-
-func TestDbHttp(t *testing.T) {
-	id := uuid.New()
-	// id, err := uuid.Parse("550e8400-e29b-41d4-a716-446655440002")
-	// Check(err)
-	InitializeIdInDbHttp("test-user", 19, id)
-	UploadDataToDbHttp("test-user", 19, id, []byte("mele 1"))
-	UploadDataToDbHttp("test-user", 19, id, []byte("mele 2"))
-	UploadDataToDbHttp("test-user", 19, id, []byte("mele totusi, da -------"))
-
-	SetUserDataHttp("test-user1", "test-data1")
-	data := GetUserDataHttp("test-user1")
-	assert.Equal(t, "test-data1", data)
-
-	SetUserDataHttp("test-user1", "test-data2")
-	data = GetUserDataHttp("test-user1")
-	assert.Equal(t, "test-data2", data)
-}
-
 func TestSeralizationInt(t *testing.T) {
 	var x Int
 	buf := new(bytes.Buffer)
@@ -80,9 +52,9 @@ func TestSeralizationSlice(t *testing.T) {
 	x = make([]int64, 3)
 	x[0], x[1], x[2] = 3, 12, 9
 	buf := new(bytes.Buffer)
-	Serialize(buf, x)
+	SerializeSlice(buf, x)
 	var y []int64
-	Deserialize(buf, &y)
+	DeserializeSlice(buf, &y)
 	assert.True(t, slices.Equal(x, y))
 }
 
@@ -104,11 +76,10 @@ func TestSeralizationSliceOfSlice(t *testing.T) {
 	x.X[1].X[1] = -3
 	x.Y = 931
 
+	// Only fixed-size values can be serialized, slices have to be serialized
+	// one by one with SerializeSlice.
 	buf := new(bytes.Buffer)
-	Serialize(buf, x)
-	var y T2
-	Deserialize(buf, &y)
-	assert.Equal(t, x, y)
+	assert.Panics(t, func() { Serialize(buf, x) })
 }
 
 func TestSeralizationArray(t *testing.T) {
@@ -13,7 +13,9 @@ require (
 	github.com/hajimehoshi/ebiten/v2 v2.6.3
 	github.com/kelindar/binary v1.0.19
 	github.com/stretchr/testify v1.9.0
+	go.etcd.io/bbolt v1.3.11
 	golang.org/x/image v0.21.0
+	golang.org/x/term v0.27.0
 )
 
 require (
@@ -91,13 +91,27 @@ func (g *Gui) Draw(screen *ebiten.Image) {
 }
 
 func (g *Gui) DrawPlayRegion(screen *ebiten.Image) {
-	// Draw ground and trees.
+	// Draw ground, terrain and trees.
 	rows := I(NRows)
 	cols := I(NCols)
 	var pt Pt
 	for pt.Y = ZERO; pt.Y.Lt(rows); pt.Y.Inc() {
 		for pt.X = ZERO; pt.X.Lt(cols); pt.X.Inc() {
-			g.DrawTile(screen, g.imgGround, pt)
+			switch g.world.Terrain.Get(pt) {
+			case Mud:
+				g.DrawTile(screen, g.imgMud, pt)
+			case Bush:
+				g.DrawTile(screen, g.imgGround, pt)
+				g.DrawTile(screen, g.imgBush, pt)
+			case Rock:
+				g.DrawTile(screen, g.imgGround, pt)
+				g.DrawTile(screen, g.imgRock, pt)
+			case Crate:
+				g.DrawTile(screen, g.imgGround, pt)
+				g.DrawTile(screen, g.imgCrate, pt)
+			default:
+				g.DrawTile(screen, g.imgGround, pt)
+			}
 			if g.world.Obstacles.At(pt) {
 				g.DrawTile(screen, g.imgTree, pt)
 			}
@@ -109,6 +123,9 @@ func (g *Gui) DrawPlayRegion(screen *ebiten.Image) {
 		for i := range g.world.SpawnPortals.N {
 			p := &g.world.SpawnPortals.V[i]
 			g.DrawTile(screen, g.imgSpawnPortal, p.Pos())
+			if p.Destructible {
+				g.DrawHealth(screen, g.imgEnemyHealth, p.Health, p.Pos())
+			}
 		}
 	}
 
@@ -157,6 +174,11 @@ func (g *Gui) DrawPlayRegion(screen *ebiten.Image) {
 	}
 	DrawSpriteXY(screen, beamScreen, 0, 0)
 
+	// Show what the enemies will do in the next turn.
+	if g.world.TurnBased {
+		g.DrawIntents(screen)
+	}
+
 	// Highlight attack
 	attackOk, attackPos := g.GetAttackTarget()
 	highlightedPositions := []Pt{}
@@ -185,7 +207,9 @@ func (g *Gui) DrawPlayRegion(screen *ebiten.Image) {
 
 	// Draw all temporary animations.
 	for _, o := range g.visWorld.Temporary {
-		if o.Animation.Valid() {
+		if o.Animation.Valid() && o.OnTile {
+			g.DrawTile(screen, o.Animation.Img(), o.TilePos)
+		} else if o.Animation.Valid() {
 			g.DrawTile(screen, o.Animation.Img(), g.ScreenToTile(o.ScreenPos))
 		}
 	}
@@ -203,7 +227,41 @@ func (g *Gui) DrawPlayRegion(screen *ebiten.Image) {
 	}
 }
 
+// DrawIntents draws an arrow from each enemy towards where it will be after
+// the next turn. The head of the arrow is a small square.
+func (g *Gui) DrawIntents(screen *ebiten.Image) {
+	// The enemies only act during turns, so the intents don't change between
+	// turns. Undos and rewinds restore NTurns along with the rest of the
+	// World.
+	key := intentsKey{g.playthrough.Id, g.world.NTurns}
+	if key != g.intentsKey {
+		g.intents = g.world.EnemyIntents()
+		g.intentsKey = key
+	}
+	intents := g.intents
+	for i := range intents.N {
+		intent := intents.V[i]
+		if intent.Kind == NoIntent {
+			continue
+		}
+		col := g.imgIntentMove.At(0, 0)
+		if intent.Kind == AttackIntent {
+			col = g.imgIntentAttack.At(0, 0)
+		}
+		from := g.TileToPlayRegion(intent.From)
+		to := g.TileToPlayRegion(intent.To)
+		// Stop the arrow before the center of the target tile so that it
+		// doesn't cover whatever is there.
+		head := from.Plus(to.Minus(from).Times(TWO).DivBy(I(3)))
+		DrawLine(screen, Line{from, head}, col)
+		DrawFilledSquare(screen, Square{head, g.BlockSize.DivBy(I(8))}, col)
+	}
+}
+
 func (g *Gui) DrawEnemy(screen *ebiten.Image, e *Hound) {
+	if e.Marked() {
+		g.DrawTile(screen, g.imgTargetMark, e.Pos())
+	}
 	if g.DrawEnemyHealth {
 		g.DrawHealth(screen, g.imgEnemyHealth, e.Health(), e.Pos())
 	}
@@ -23,12 +23,19 @@ func (g *Gui) loadGuiData() {
 		LoadYAML(g.FSys, "data/gui/gui.yaml", &g.GuiData)
 		g.imgGround = LoadImage(g.FSys, "data/gui/ground.png")
 		g.imgTree = LoadImage(g.FSys, "data/gui/tree.png")
+		g.imgBush = LoadImage(g.FSys, "data/gui/bush.png")
+		g.imgRock = LoadImage(g.FSys, "data/gui/rock.png")
+		g.imgMud = LoadImage(g.FSys, "data/gui/mud.png")
+		g.imgCrate = LoadImage(g.FSys, "data/gui/crate.png")
+		g.imgTargetMark = LoadImage(g.FSys, "data/gui/target-mark.png")
 		g.imgPlayerHealth = LoadImage(g.FSys, "data/gui/player-health.png")
 		g.imgPlayerAmmo = LoadImage(g.FSys, "data/gui/player-ammo.png")
 		g.imgHound = LoadImage(g.FSys, "data/gui/enemy2.png")
 		g.imgEnemyHealth = LoadImage(g.FSys, "data/gui/enemy-health.png")
 		g.imgEnemyCooldown = LoadImage(g.FSys, "data/gui/enemy-cooldown.png")
 		g.imgBeam = LoadImage(g.FSys, "data/gui/beam.png")
+		g.imgIntentMove = LoadImage(g.FSys, "data/gui/intent-move.png")
+		g.imgIntentAttack = LoadImage(g.FSys, "data/gui/intent-attack.png")
 		g.imgShadow = LoadImage(g.FSys, "data/gui/shadow.png")
 		g.imgTextBackground = LoadImage(g.FSys, "data/gui/text-background.png")
 		g.imgTextColor = LoadImage(g.FSys, "data/gui/text-color.png")
@@ -53,6 +60,14 @@ func (g *Gui) loadGuiData() {
 		g.animHoundAttacking = NewAnimation(g.FSys, "data/gui/hound-attacking")
 		g.animHoundHit = NewAnimation(g.FSys, "data/gui/hound-hit")
 		g.animHoundDead = NewAnimation(g.FSys, "data/gui/hound-dead")
+		g.animPickupHealth = NewAnimation(g.FSys, "data/gui/pickup-health")
+		g.animPickupEnergy = NewAnimation(g.FSys, "data/gui/pickup-energy")
+		g.animPickupDoubleDamage = NewAnimation(g.FSys, "data/gui/pickup-double-damage")
+		g.animPickupFasterCooldown = NewAnimation(g.FSys, "data/gui/pickup-faster-cooldown")
+		g.animKey = NewAnimation(g.FSys, "data/gui/key")
+		g.animExit = NewAnimation(g.FSys, "data/gui/exit")
+		g.animExitLocked = NewAnimation(g.FSys, "data/gui/exit-locked")
+		g.animAlly = NewAnimation(g.FSys, "data/gui/ally")
 		if CheckFailed == nil {
 			break
 		}
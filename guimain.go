@@ -85,6 +85,14 @@ type Animations struct {
 	animHoundAttacking         Animation
 	animHoundHit               Animation
 	animHoundDead              Animation
+	animPickupHealth           Animation
+	animPickupEnergy           Animation
+	animPickupDoubleDamage     Animation
+	animPickupFasterCooldown   Animation
+	animKey                    Animation
+	animExit                   Animation
+	animExitLocked             Animation
+	animAlly                   Animation
 }
 
 type UserData struct {
@@ -99,6 +107,11 @@ type Gui struct {
 	defaultFont           font.Face
 	imgGround             *ebiten.Image
 	imgTree               *ebiten.Image
+	imgBush               *ebiten.Image
+	imgRock               *ebiten.Image
+	imgMud                *ebiten.Image
+	imgCrate              *ebiten.Image
+	imgTargetMark         *ebiten.Image
 	imgPlayerHealth       *ebiten.Image
 	imgPlayerAmmo         *ebiten.Image
 	imgHound              *ebiten.Image
@@ -106,6 +119,8 @@ type Gui struct {
 	imgEnemyCooldown      *ebiten.Image
 	imgTileOverlay        *ebiten.Image
 	imgBeam               *ebiten.Image
+	imgIntentMove         *ebiten.Image
+	imgIntentAttack       *ebiten.Image
 	imgShadow             *ebiten.Image
 	imgTextBackground     *ebiten.Image
 	imgTextColor          *ebiten.Image
@@ -153,6 +168,18 @@ type Gui struct {
 	instructionalText      string
 	fixedLevels            []string
 	username               string
+	releaseFingerprint     string
+	header                 PlaythroughHeader
+	// EnemyIntents simulates a whole turn, so it is only called again when
+	// the World gets to another turn, see DrawIntents.
+	intents    IntentsArray
+	intentsKey intentsKey
+}
+
+// intentsKey identifies the turn that Gui.intents were computed for.
+type intentsKey struct {
+	id     uuid.UUID
+	nTurns Int
 }
 
 type uploadData struct {
@@ -160,6 +187,7 @@ type uploadData struct {
 	releaseVersion    int64
 	simulationVersion int64
 	inputVersion      int64
+	header            PlaythroughHeader
 	playthrough       *Playthrough
 }
 
@@ -180,6 +208,7 @@ func main() {
 	g.playthrough.InputVersion = I(InputVersion)
 	g.playthrough.SimulationVersion = I(SimulationVersion)
 	g.playthrough.ReleaseVersion = I(ReleaseVersion)
+	g.playthrough.ChecksumInterval = I(DefaultChecksumInterval)
 	g.username = getUsername()
 	// A channel size of 10 means the channel will buffer 10 inputs before it is
 	// full and it blocks. Hopefully, when uploading data, a size of 10 is
@@ -220,6 +249,8 @@ func main() {
 		g.folderWatcher2.FolderContentsChanged()
 	}
 
+	g.releaseFingerprint = ReleaseFingerprint(g.FSys)
+
 	if FileExists(g.FSys, "data/levels") {
 		g.InitializeFixedLevels()
 	}
@@ -318,6 +349,8 @@ func (g *Gui) startLevel(seed Int, l Level) {
 	g.playthrough.Level = l
 	g.playthrough.Id = uuid.New()
 	g.playthrough.History = g.playthrough.History[:0]
+	g.playthrough.Checksums = g.playthrough.Checksums[:0]
+	g.startHeader()
 	g.world = NewWorldFromPlaythrough(g.playthrough)
 	InitializeIdInDbHttp(g.username,
 		g.playthrough.ReleaseVersion.ToInt64(),
@@ -11,6 +11,8 @@ import (
 )
 
 func (g *Gui) Update() error {
+	defer g.recoverCrash()
+
 	// Updates common to all states.
 	if g.folderWatcher1.FolderContentsChanged() {
 		g.loadGuiData()
@@ -20,6 +22,8 @@ func (g *Gui) Update() error {
 		g.playthrough.Id = uuid.New()
 		g.playthrough.Level = GenerateLevel(g.FSys)
 		g.playthrough.History = g.playthrough.History[:0]
+		g.playthrough.Checksums = g.playthrough.Checksums[:0]
+		g.startHeader()
 		g.world = NewWorldFromPlaythrough(g.playthrough)
 		g.updateWindowSize()
 	}
@@ -84,9 +88,12 @@ func (g *Gui) UpdateGameOngoing() {
 	}
 
 	if g.world.Player.Health.Lt(g.world.Player.MaxHealth) && !g.world.Player.OnMap {
-		g.instructionalText = "Go back and kill everyone! left click - move, right click - shoot"
+		g.instructionalText = "Go back! " + g.world.ObjectivesText() + " left click - move, right click - shoot"
 	} else {
-		g.instructionalText = "Kill everyone! left click - move, right click - shoot"
+		g.instructionalText = g.world.ObjectivesText() + " left click - move, right click - shoot"
+	}
+	if g.world.TurnBased {
+		g.instructionalText += ", U - undo"
 	}
 
 	var input PlayerInput
@@ -94,20 +101,21 @@ func (g *Gui) UpdateGameOngoing() {
 	input.MousePt = g.mousePt
 	input.LeftButtonPressed = g.leftButtonJustPressed
 	input.RightButtonPressed = g.rightButtonJustPressed
-
-	if g.leftButtonJustPressed {
-		input.Move, input.MovePt = g.GetMoveTarget()
-	}
-	if g.rightButtonJustPressed {
-		input.Shoot, input.ShootPt = g.GetAttackTarget()
-	}
+	input = g.aimParams().ResolveClicks(&g.world, input)
+	input.Undo = g.UserRequestedUndo() && g.world.TurnBased && !input.Move &&
+		!input.Shoot
 
 	// input = g.ai.Step(&g.world)
 	Step(&g.playthrough, &g.world, input)
+	if input.Undo {
+		// The World was rebuilt, the animations of the old one don't apply.
+		g.visWorld = NewVisWorld(g.Animations)
+	}
 	g.visWorld.Step(&g.world, input, g.GuiData)
 
 	if g.recordingFile != "" {
-		WriteFile(g.recordingFile, g.playthrough.Serialize())
+		WriteFile(g.recordingFile,
+			SerializePlaythroughFile(g.playthroughHeader(), &g.playthrough))
 	}
 	if g.frameIdx.Mod(I(60)) == ZERO {
 		g.uploadCurrentWorld()
@@ -136,6 +144,10 @@ func (g *Gui) UserRequestedRestartLevel() bool {
 	return !g.NextLevelRequestable() && (g.JustPressed(ebiten.KeyR) || g.JustClicked(g.buttonRestartLevel))
 }
 
+func (g *Gui) UserRequestedUndo() bool {
+	return g.world.TurnBased && g.JustPressed(ebiten.KeyU)
+}
+
 func (g *Gui) UserRequestedPlaybackPause() bool {
 	return g.JustPressed(ebiten.KeySpace) || g.JustClicked(g.buttonPlaybackPlay)
 }
@@ -153,6 +165,8 @@ func (g *Gui) StartNextLevel() {
 func (g *Gui) RestartLevel() {
 	g.playthrough.Id = uuid.New()
 	g.playthrough.History = g.playthrough.History[:0]
+	g.playthrough.Checksums = g.playthrough.Checksums[:0]
+	g.startHeader()
 	g.world = NewWorldFromPlaythrough(g.playthrough)
 	InitializeIdInDbHttp(g.username,
 		g.playthrough.ReleaseVersion.ToInt64(),
@@ -178,7 +192,7 @@ func (g *Gui) UpdateGamePaused() {
 		g.UpdateGameOngoing()
 		return
 	}
-	g.instructionalText = "Paused. Kill everyone! left click - move, right click - shoot"
+	g.instructionalText = "Paused. " + g.world.ObjectivesText() + " left click - move, right click - shoot"
 }
 
 func (g *Gui) UpdateGameWon() {
@@ -304,7 +318,7 @@ func (g *Gui) UpdatePlayback() {
 		// Replay the world.
 		for i := 0; i < targetFrameIdx.ToInt(); i++ {
 			input := g.playthrough.History[i]
-			g.world.Step(input)
+			g.playthrough.StepFrame(&g.world, i)
 			g.visWorld.Step(&g.world, input, g.GuiData)
 		}
 
@@ -325,7 +339,7 @@ func (g *Gui) UpdatePlayback() {
 
 	// input = g.ai.Step(&g.world)
 	if !g.playbackPaused {
-		g.world.Step(input)
+		g.playthrough.StepFrame(&g.world, g.frameIdx.ToInt())
 		g.visWorld.Step(&g.world, input, g.GuiData)
 
 		if g.frameIdx.Lt(nFrames.Minus(ONE)) {
@@ -341,10 +355,16 @@ func (g *Gui) UpdatePlayback() {
 	if input.RightButtonPressed {
 		g.instructionalText += " Right button pressed."
 	}
+	if input.Undo {
+		g.instructionalText += " Undo."
+	}
 	if g.world.Status() == Won {
 		g.instructionalText += " Won."
 	}
 	if g.world.Status() == Lost {
 		g.instructionalText += " Lost."
 	}
+	if warning := g.playthrough.DesyncWarning(); warning != "" {
+		g.instructionalText += " " + warning
+	}
 }
@@ -2,12 +2,16 @@ package main
 
 import (
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
 	_ "image/png"
+	"os"
 	"slices"
 	"strconv"
+	"time"
 )
 
 func (g *Gui) uploadCurrentWorld() {
@@ -23,9 +27,29 @@ func (g *Gui) uploadCurrentWorld() {
 		g.playthrough.ReleaseVersion.ToInt64(),
 		g.playthrough.SimulationVersion.ToInt64(),
 		g.playthrough.InputVersion.ToInt64(),
+		g.playthroughHeader(),
 		g.playthrough.Clone()}
 }
 
+// startHeader describes the playthrough that just started, see
+// SerializePlaythroughFile. Hashing the Level takes a while, so it is done
+// once here and not every time the playthrough is saved.
+func (g *Gui) startHeader() {
+	g.header = NewPlaythroughHeader(&g.playthrough, nil)
+	g.header.User = g.username
+	g.header.Start = time.Now().UTC()
+	g.header.ReleaseFingerprint = g.releaseFingerprint
+}
+
+// playthroughHeader is the header of the playthrough as it is now.
+func (g *Gui) playthroughHeader() PlaythroughHeader {
+	h := g.header
+	h.End = time.Now().UTC()
+	h.Frames = int64(len(g.playthrough.History))
+	h.Status = g.world.Status().String()
+	return h
+}
+
 func UploadPlaythroughs(ch chan uploadData) {
 	for {
 		// Receive a playthrough from the channel.
@@ -38,10 +62,45 @@ func UploadPlaythroughs(ch chan uploadData) {
 			data.simulationVersion,
 			data.inputVersion,
 			data.playthrough.Id,
-			data.playthrough.Serialize())
+			SerializePlaythroughFile(data.header, data.playthrough))
 	}
 }
 
+// recoverCrash is deferred by Update. If Update panicked (usually because the
+// World panicked in Step) it saves a Crash next to the executable and uploads
+// it, then panics again. The Crash has the playthrough up to and including the
+// input that crashed the game, so "analysis.exe crash <file>" can reproduce
+// the crash.
+func (g *Gui) recoverCrash() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	c := NewCrash(&g.playthrough, I(ReleaseVersion), r)
+	data := c.Serialize()
+	filename := c.Filename()
+	// Saving fails in the browser, there the upload is all we get.
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		filename = ""
+	}
+
+	// Upload right away instead of going through uploadDataChannel, the game
+	// is about to end. Crashes are uploaded for a separate user so that they
+	// don't get mixed with the playthroughs of the real user. Errors are
+	// ignored, there is nothing left to do about them.
+	id := uuid.New()
+	user := g.username + CrashSuffix
+	_ = TryInitializeIdInDbHttp(user, ReleaseVersion, SimulationVersion,
+		InputVersion, id)
+	_ = TryUploadDataToDbHttp(user, ReleaseVersion, SimulationVersion,
+		InputVersion, id, data)
+
+	if filename == "" {
+		panic(r)
+	}
+	panic(fmt.Sprintf("%v\ncrash saved to %s", r, filename))
+}
+
 func GetNextLevel(user string) (seed Int, targetDifficulty Int) {
 	// Get index and increment it.
 	filenameIndex := user + "-index.txt"
@@ -59,9 +118,19 @@ func GetNextLevel(user string) (seed Int, targetDifficulty Int) {
 	return
 }
 
+func (g *Gui) aimParams() AimParams {
+	return AimParams{
+		BlockSize:           g.BlockSize,
+		Margin:              g.guiMargin,
+		AutoAimAttack:       g.AutoAimAttack,
+		AutoAimAttackFactor: g.AutoAimAttackFactor,
+		AutoAimMove:         g.AutoAimMove,
+		AutoAimMoveFactor:   g.AutoAimMoveFactor,
+	}
+}
+
 func (g *Gui) TileToScreen(pos Pt) Pt {
-	half := g.BlockSize.DivBy(TWO)
-	return pos.Times(g.BlockSize).Plus(Pt{half, half}).Plus(Pt{g.guiMargin, g.guiMargin})
+	return g.aimParams().TileToScreen(pos)
 }
 
 func (g *Gui) TilesToScreen(ipt []Pt) (opt []Pt) {
@@ -72,7 +141,7 @@ func (g *Gui) TilesToScreen(ipt []Pt) (opt []Pt) {
 }
 
 func (g *Gui) ScreenToTile(pos Pt) Pt {
-	return pos.Minus(Pt{g.guiMargin, g.guiMargin}).DivBy(g.BlockSize)
+	return g.aimParams().ScreenToTile(pos)
 }
 
 func (g *Gui) TileToPlayRegion(pos Pt) Pt {
@@ -92,52 +161,12 @@ func (g *Gui) MouseCursorIsOverATile() bool {
 	return g.world.Obstacles.InBounds(g.ScreenToTile(g.mousePt))
 }
 
-func (g *Gui) ClosestTileToMouse(tiles []Pt) (tile Pt, dist Int) {
-	opt := []Pt{}
-	for _, pt := range tiles {
-		opt = append(opt, g.TileToScreen(pt))
-	}
-	_, closestPt := GetClosestPoint(opt, g.mousePt)
-	tile = g.ScreenToTile(closestPt)
-	dist = closestPt.To(g.mousePt).Len()
-	return
-}
-
 func (g *Gui) GetAttackTarget() (valid bool, target Pt) {
-	if g.AutoAimAttack {
-		attackablePositions := g.world.VulnerableEnemyPositions()
-		attackablePositions.IntersectWith(g.world.VisibleTiles)
-		pos := attackablePositions.ToArray()
-		tilePos, dist := g.ClosestTileToMouse(pos.V[:pos.N])
-		closeEnough := dist.Lt(g.BlockSize.Times(g.AutoAimAttackFactor).DivBy(I(100)))
-		attackOk := g.world.Player.OnMap && closeEnough
-		return attackOk, tilePos
-	} else {
-		attackablePositions := g.world.VulnerableEnemyPositions()
-		attackablePositions.IntersectWith(g.world.VisibleTiles)
-		tilePos := g.ScreenToTile(g.mousePt)
-		mouseCursorIsOverAVulnerableEnemy :=
-			attackablePositions.InBounds(tilePos) &&
-				attackablePositions.At(tilePos)
-		attackOk := g.world.Player.OnMap && mouseCursorIsOverAVulnerableEnemy
-		return attackOk, tilePos
-	}
+	return g.aimParams().AttackTarget(&g.world, g.mousePt)
 }
 
 func (g *Gui) GetMoveTarget() (valid bool, target Pt) {
-	if g.AutoAimMove {
-		freePositions := g.world.Player.ComputeFreePositions(&g.world).ToArray()
-		tilePos, dist := g.ClosestTileToMouse(freePositions.V[:freePositions.N])
-		closeEnough := dist.Lt(g.BlockSize.Times(g.AutoAimMoveFactor).DivBy(I(100)))
-		return closeEnough, tilePos
-	} else {
-		freePositions := g.world.Player.ComputeFreePositions(&g.world)
-		tilePos := g.ScreenToTile(g.mousePt)
-		mouseCursorIsOverAFreePosition :=
-			freePositions.InBounds(tilePos) &&
-				freePositions.At(tilePos)
-		return mouseCursorIsOverAFreePosition, tilePos
-	}
+	return g.aimParams().MoveTarget(&g.world, g.mousePt)
 }
 
 func (g *Gui) JustPressed(key ebiten.Key) bool {
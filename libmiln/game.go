@@ -0,0 +1,272 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"sync"
+	"sync/atomic"
+)
+
+// These must match the enums in the preamble of libmiln.go, init checks that
+// they do.
+const (
+	actionNone = iota
+	actionMove
+	actionShoot
+	actionUndo
+)
+
+const (
+	obsFrame = iota
+	obsStatus
+	obsActionOk
+	obsTurnBased
+	obsUseAmmo
+	obsPlayerX
+	obsPlayerY
+	obsPlayerOnMap
+	obsPlayerHealth
+	obsPlayerMaxHealth
+	obsPlayerAmmo
+	obsHeaderSize = 16
+)
+
+const (
+	planeObstacles = iota
+	planeVisible
+	planeTerrain
+	planeHoundHealth
+	planeHoundState
+	planeAmmo
+	planePickup
+	planeObjective
+	nPlanes
+)
+
+const obsSize = obsHeaderSize + nPlanes*NRows*NCols
+
+var statusCode = map[WorldStatus]int32{
+	Ongoing: 0,
+	Won:     1,
+	Lost:    2,
+}
+
+var houndStateCode = map[string]int32{
+	"Searching":         1,
+	"PreparingToAttack": 2,
+	"Attacking":         3,
+	"Hit":               4,
+	"Dead":              5,
+}
+
+var pickupCode = map[string]int32{
+	"Health":         1,
+	"Energy":         2,
+	"DoubleDamage":   3,
+	"FasterCooldown": 4,
+}
+
+var objectiveCode = map[string]int32{
+	"Key":        1,
+	"Exit":       2,
+	"ExitLocked": 3,
+	"Ally":       4,
+}
+
+// input is the Go side of miln_input.
+type input struct {
+	action int32
+	x      int32
+	y      int32
+	frames int32
+}
+
+// game is what a handle refers to. A game is only used by one thread at a
+// time, but different games can be used by different threads at the same
+// time.
+type game struct {
+	playthrough Playthrough
+	world       World
+	actionOk    bool
+	lastError   string
+}
+
+// Handles are never reused, so a handle that was destroyed stays invalid
+// instead of suddenly referring to some other game.
+var (
+	games      = map[uint64]*game{}
+	gamesMutex sync.Mutex
+	lastHandle atomic.Uint64
+)
+
+func addGame(g *game) uint64 {
+	h := lastHandle.Add(1)
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+	games[h] = g
+	return h
+}
+
+func getGame(h uint64) *game {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+	return games[h]
+}
+
+func deleteGame(h uint64) bool {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+	_, ok := games[h]
+	delete(games, h)
+	return ok
+}
+
+// catch turns the panics of the simulation (which uses Check for errors) into
+// errors, because a panic must not cross into C.
+func catch(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	f()
+	return
+}
+
+func newGame(levelYaml []byte, seed int64) (g *game, err error) {
+	err = catch(func() {
+		_, l := LevelFromYAML(levelYaml)
+		g = &game{actionOk: true}
+		g.playthrough.InputVersion = I(InputVersion)
+		g.playthrough.SimulationVersion = I(SimulationVersion)
+		g.playthrough.Level = l
+		g.playthrough.Seed = I64(seed)
+		g.playthrough.Id = uuid.New()
+		g.world = NewWorldFromPlaythrough(g.playthrough)
+	})
+	return
+}
+
+// clone returns a game that continues independently from the same state. The
+// clone is a new playthrough, so it gets its own Id.
+func (g *game) clone() *game {
+	c := *g
+	c.playthrough = *g.playthrough.Clone()
+	c.playthrough.Id = uuid.New()
+	c.lastError = ""
+	return &c
+}
+
+// step works like bot.Session: the action is a tile which becomes a click, so
+// that the playthrough looks like one recorded by the GUI.
+func (g *game) step(in input) (err error) {
+	var pi PlayerInput
+	pi.MousePt = TileAimParams.TileToScreen(IPt(int(in.x), int(in.y)))
+	switch in.action {
+	case actionNone:
+	case actionMove:
+		pi.LeftButtonPressed = true
+	case actionShoot:
+		pi.RightButtonPressed = true
+	case actionUndo:
+		if !g.world.TurnBased {
+			return errors.New("undo is only possible in turn-based levels")
+		}
+		pi.Undo = true
+	default:
+		return fmt.Errorf("unknown action: %d", in.action)
+	}
+
+	return catch(func() {
+		pi = TileAimParams.ResolveClicks(&g.world, pi)
+		g.actionOk = pi.LeftButtonPressed == pi.Move &&
+			pi.RightButtonPressed == pi.Shoot
+		frames := max(in.frames, 1)
+		for i := int32(0); i < frames && g.world.Status() == Ongoing; i++ {
+			Step(&g.playthrough, &g.world, pi)
+			pi = PlayerInput{MousePt: pi.MousePt}
+		}
+	})
+}
+
+func boolToInt32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// observe writes the observation into buf and returns obsSize. If buf is
+// smaller than obsSize nothing is written.
+func (g *game) observe(buf []int32) int64 {
+	if len(buf) < obsSize {
+		return obsSize
+	}
+	buf = buf[:obsSize]
+	clear(buf)
+
+	w := &g.world
+	p := &w.Player
+	buf[obsFrame] = int32(len(g.playthrough.History))
+	buf[obsStatus] = statusCode[w.Status()]
+	buf[obsActionOk] = boolToInt32(g.actionOk)
+	buf[obsTurnBased] = boolToInt32(w.TurnBased)
+	buf[obsUseAmmo] = boolToInt32(w.UseAmmo)
+	buf[obsPlayerX] = int32(p.Pos().X.ToInt64())
+	buf[obsPlayerY] = int32(p.Pos().Y.ToInt64())
+	buf[obsPlayerOnMap] = boolToInt32(p.OnMap)
+	buf[obsPlayerHealth] = int32(p.Health.ToInt64())
+	buf[obsPlayerMaxHealth] = int32(p.MaxHealth.ToInt64())
+	buf[obsPlayerAmmo] = int32(p.AmmoCount.ToInt64())
+
+	planes := buf[obsHeaderSize:]
+	set := func(plane int, pos Pt, v int32) {
+		planes[plane*NRows*NCols+pos.Y.ToInt()*NCols+pos.X.ToInt()] = v
+	}
+	var pt Pt
+	for pt.Y = ZERO; pt.Y.Lt(I(NRows)); pt.Y.Inc() {
+		for pt.X = ZERO; pt.X.Lt(I(NCols)); pt.X.Inc() {
+			set(planeObstacles, pt, boolToInt32(w.Obstacles.At(pt)))
+			set(planeVisible, pt, boolToInt32(p.OnMap && w.VisibleTiles.At(pt)))
+			set(planeTerrain, pt, int32(w.Terrain.Get(pt)))
+		}
+	}
+	for i := range w.Enemies.N {
+		h := &w.Enemies.V[i]
+		set(planeHoundHealth, h.Pos(), int32(h.Health().ToInt64()))
+		set(planeHoundState, h.Pos(), houndStateCode[h.State()])
+	}
+	for i := range w.Ammos.N {
+		a := &w.Ammos.V[i]
+		set(planeAmmo, a.Pos, int32(a.Count.ToInt64()))
+	}
+	for i := range w.Pickups.N {
+		pk := &w.Pickups.V[i]
+		set(planePickup, pk.Pos(), pickupCode[pk.State()])
+	}
+	for i := range w.Objectives.N {
+		o := &w.Objectives.V[i]
+		if o.State() != "" {
+			set(planeObjective, o.Pos(), objectiveCode[o.State()])
+		}
+	}
+	return obsSize
+}
+
+// copyBytes copies src into dst if it fits and returns len(src), so that the
+// caller can find out how big the buffer must be by passing an empty one.
+func copyBytes(dst []byte, src []byte) int64 {
+	if len(dst) >= len(src) {
+		copy(dst, src)
+	}
+	return int64(len(src))
+}
+
+// copyString copies s into dst as a NUL-terminated string if it fits and
+// returns the size needed, including the NUL.
+func copyString(dst []byte, s string) int64 {
+	return copyBytes(dst, append([]byte(s), 0))
+}
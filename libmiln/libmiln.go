@@ -0,0 +1,310 @@
+// Command libmiln builds the simulation as a C shared library, for programs
+// that need to run a lot of games quickly (e.g. training agents from Python
+// via ctypes or cffi), where going through milnbot would be too slow.
+//
+// Build it with:
+//
+//	go build -buildmode=c-shared -tags headless,world_debug_info_disabled -o libmiln.so ./libmiln
+//
+// The headless tag leaves Ebiten out of gamelib, so the library doesn't need
+// a graphics stack. The build also writes libmiln.h, which has the types and
+// constants below.
+//
+// Memory: the library never gives out pointers to its own memory. Everything
+// is copied into buffers that belong to the caller. Functions that fill a
+// buffer return the size it needs to have and write nothing if it is too
+// small, so passing a NULL buffer of length 0 asks for the size. A game lives
+// until miln_destroy is called on its handle.
+//
+// Threads: different handles can be used from different threads at the same
+// time. A handle must not be used from two threads at the same time.
+//
+// Errors: functions return MILN_ERR_HANDLE for a handle that doesn't exist
+// (anymore) and MILN_ERR for other errors, whose message is available through
+// miln_last_error.
+package main
+
+/*
+#include <stdint.h>
+
+typedef uint64_t miln_handle; // 0 is never a valid handle
+
+enum {
+	MILN_OK = 0,
+	MILN_ERR = -1,
+	MILN_ERR_HANDLE = -2,
+};
+
+enum {
+	MILN_ACTION_NONE = 0,
+	MILN_ACTION_MOVE = 1,
+	MILN_ACTION_SHOOT = 2,
+	MILN_ACTION_UNDO = 3,
+};
+
+// The action is applied in the first frame, then the game runs without input
+// for the rest of the frames, or until it ends.
+typedef struct {
+	int32_t action;
+	int32_t x; // tile
+	int32_t y; // tile
+	int32_t frames;
+} miln_input;
+
+enum {
+	MILN_STATUS_ONGOING = 0,
+	MILN_STATUS_WON = 1,
+	MILN_STATUS_LOST = 2,
+};
+
+enum {
+	MILN_NROWS = 8,
+	MILN_NCOLS = 8,
+};
+
+// An observation is an array of int32_t: a header followed by planes. Each
+// plane is a MILN_NROWS x MILN_NCOLS matrix stored row by row, so tile (x, y)
+// of plane p is at MILN_OBS_HEADER_SIZE + p*MILN_NROWS*MILN_NCOLS +
+// y*MILN_NCOLS + x.
+enum {
+	MILN_OBS_FRAME = 0,
+	MILN_OBS_STATUS = 1,
+	MILN_OBS_ACTION_OK = 2, // 0 if the last move or shot wasn't possible
+	MILN_OBS_TURN_BASED = 3,
+	MILN_OBS_USE_AMMO = 4,
+	MILN_OBS_PLAYER_X = 5,
+	MILN_OBS_PLAYER_Y = 6,
+	MILN_OBS_PLAYER_ON_MAP = 7,
+	MILN_OBS_PLAYER_HEALTH = 8,
+	MILN_OBS_PLAYER_MAX_HEALTH = 9,
+	MILN_OBS_PLAYER_AMMO = 10,
+	MILN_OBS_HEADER_SIZE = 16,
+};
+
+enum {
+	MILN_PLANE_OBSTACLES = 0,   // 1 for trees
+	MILN_PLANE_VISIBLE = 1,     // 1 for tiles the player sees
+	MILN_PLANE_TERRAIN = 2,     // MILN_TERRAIN_*
+	MILN_PLANE_HOUND_HEALTH = 3,
+	MILN_PLANE_HOUND_STATE = 4, // MILN_HOUND_*
+	MILN_PLANE_AMMO = 5,        // ammo count
+	MILN_PLANE_PICKUP = 6,      // MILN_PICKUP_*
+	MILN_PLANE_OBJECTIVE = 7,   // MILN_OBJECTIVE_*
+	MILN_NPLANES = 8,
+	MILN_OBS_SIZE = MILN_OBS_HEADER_SIZE + MILN_NPLANES*MILN_NROWS*MILN_NCOLS,
+};
+
+enum {
+	MILN_TERRAIN_GROUND = 0,
+	MILN_TERRAIN_BUSH = 1,
+	MILN_TERRAIN_ROCK = 2,
+	MILN_TERRAIN_MUD = 3,
+	MILN_TERRAIN_CRATE = 4,
+};
+
+enum {
+	MILN_HOUND_SEARCHING = 1,
+	MILN_HOUND_PREPARING_TO_ATTACK = 2,
+	MILN_HOUND_ATTACKING = 3,
+	MILN_HOUND_HIT = 4,
+	MILN_HOUND_DEAD = 5,
+};
+
+enum {
+	MILN_PICKUP_HEALTH = 1,
+	MILN_PICKUP_ENERGY = 2,
+	MILN_PICKUP_DOUBLE_DAMAGE = 3,
+	MILN_PICKUP_FASTER_COOLDOWN = 4,
+};
+
+enum {
+	MILN_OBJECTIVE_KEY = 1,
+	MILN_OBJECTIVE_EXIT = 2,
+	MILN_OBJECTIVE_EXIT_LOCKED = 3,
+	MILN_OBJECTIVE_ALLY = 4,
+};
+*/
+import "C"
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"unsafe"
+)
+
+func init() {
+	// The C header can't use the Go constants, so make sure they didn't drift
+	// apart.
+	checks := []struct {
+		name string
+		c, g int
+	}{
+		{"MILN_NROWS", C.MILN_NROWS, NRows},
+		{"MILN_NCOLS", C.MILN_NCOLS, NCols},
+		{"MILN_ACTION_UNDO", C.MILN_ACTION_UNDO, actionUndo},
+		{"MILN_OBS_PLAYER_AMMO", C.MILN_OBS_PLAYER_AMMO, obsPlayerAmmo},
+		{"MILN_OBS_HEADER_SIZE", C.MILN_OBS_HEADER_SIZE, obsHeaderSize},
+		{"MILN_PLANE_OBJECTIVE", C.MILN_PLANE_OBJECTIVE, planeObjective},
+		{"MILN_NPLANES", C.MILN_NPLANES, nPlanes},
+		{"MILN_OBS_SIZE", C.MILN_OBS_SIZE, obsSize},
+		{"MILN_STATUS_LOST", C.MILN_STATUS_LOST, int(statusCode[Lost])},
+		{"MILN_TERRAIN_CRATE", C.MILN_TERRAIN_CRATE, int(Crate)},
+		{"MILN_HOUND_DEAD", C.MILN_HOUND_DEAD, int(houndStateCode["Dead"])},
+		{"MILN_PICKUP_FASTER_COOLDOWN", C.MILN_PICKUP_FASTER_COOLDOWN,
+			int(pickupCode["FasterCooldown"])},
+		{"MILN_OBJECTIVE_ALLY", C.MILN_OBJECTIVE_ALLY, int(objectiveCode["Ally"])},
+	}
+	for _, c := range checks {
+		if c.c != c.g {
+			Check(fmt.Errorf("%s is %d in C and %d in Go", c.name, c.c, c.g))
+		}
+	}
+}
+
+// A c-shared build needs a main, but it is never called.
+func main() {}
+
+func bytesFromC(p unsafe.Pointer, n C.int64_t) []byte {
+	if p == nil || n <= 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(p), int(n))
+}
+
+// withGame calls f for the game of handle h and turns the result into a
+// return code.
+func withGame(h C.miln_handle, f func(g *game) error) C.int32_t {
+	g := getGame(uint64(h))
+	if g == nil {
+		return C.MILN_ERR_HANDLE
+	}
+	if err := f(g); err != nil {
+		g.lastError = err.Error()
+		return C.MILN_ERR
+	}
+	return C.MILN_OK
+}
+
+// miln_create starts a game of the level in level_yaml (the contents of a
+// level file) with the given seed. It returns 0 if the level can't be loaded,
+// and writes the reason into err, if err is not NULL.
+//
+//export miln_create
+func miln_create(levelYaml *C.char, levelYamlLen C.int64_t, seed C.int64_t,
+	err *C.char, errLen C.int64_t) C.miln_handle {
+	g, e := newGame(bytesFromC(unsafe.Pointer(levelYaml), levelYamlLen),
+		int64(seed))
+	if e != nil {
+		copyString(bytesFromC(unsafe.Pointer(err), errLen), e.Error())
+		return 0
+	}
+	return C.miln_handle(addGame(g))
+}
+
+//export miln_destroy
+func miln_destroy(h C.miln_handle) C.int32_t {
+	if !deleteGame(uint64(h)) {
+		return C.MILN_ERR_HANDLE
+	}
+	return C.MILN_OK
+}
+
+// miln_clone returns a new game in the same state as h, or 0 if h is not
+// valid.
+//
+//export miln_clone
+func miln_clone(h C.miln_handle) C.miln_handle {
+	g := getGame(uint64(h))
+	if g == nil {
+		return 0
+	}
+	return C.miln_handle(addGame(g.clone()))
+}
+
+// miln_step returns the MILN_STATUS_* of the game after the step, or an error.
+//
+//export miln_step
+func miln_step(h C.miln_handle, in C.miln_input) C.int32_t {
+	status := C.int32_t(0)
+	res := withGame(h, func(g *game) error {
+		err := g.step(input{int32(in.action), int32(in.x), int32(in.y),
+			int32(in.frames)})
+		status = C.int32_t(statusCode[g.world.Status()])
+		return err
+	})
+	if res != C.MILN_OK {
+		return res
+	}
+	return status
+}
+
+// miln_observe fills buf with MILN_OBS_SIZE values and returns
+// MILN_OBS_SIZE, or an error.
+//
+//export miln_observe
+func miln_observe(h C.miln_handle, buf *C.int32_t, bufLen C.int64_t) C.int64_t {
+	var n int64
+	res := withGame(h, func(g *game) error {
+		var obs []int32
+		if buf != nil && bufLen > 0 {
+			obs = unsafe.Slice((*int32)(unsafe.Pointer(buf)), int(bufLen))
+		}
+		return catch(func() { n = g.observe(obs) })
+	})
+	if res != C.MILN_OK {
+		return C.int64_t(res)
+	}
+	return C.int64_t(n)
+}
+
+// miln_serialize fills buf with the playthrough so far, in the same format
+// as the playthrough files saved by the game, and returns its size, or an
+// error.
+//
+//export miln_serialize
+func miln_serialize(h C.miln_handle, buf *C.uint8_t, bufLen C.int64_t) C.int64_t {
+	var n int64
+	res := withGame(h, func(g *game) error {
+		return catch(func() {
+			n = copyBytes(bytesFromC(unsafe.Pointer(buf), bufLen),
+				g.playthrough.Serialize())
+		})
+	})
+	if res != C.MILN_OK {
+		return C.int64_t(res)
+	}
+	return C.int64_t(n)
+}
+
+// miln_regression_id fills buf with the RegressionId of the playthrough so
+// far, as a NUL-terminated string, and returns its size including the NUL,
+// or an error.
+//
+//export miln_regression_id
+func miln_regression_id(h C.miln_handle, buf *C.char, bufLen C.int64_t) C.int64_t {
+	var n int64
+	res := withGame(h, func(g *game) error {
+		return catch(func() {
+			n = copyString(bytesFromC(unsafe.Pointer(buf), bufLen),
+				RegressionId(&g.playthrough))
+		})
+	})
+	if res != C.MILN_OK {
+		return C.int64_t(res)
+	}
+	return C.int64_t(n)
+}
+
+// miln_last_error fills buf with the message of the last error of h, as a
+// NUL-terminated string, and returns its size including the NUL.
+//
+//export miln_last_error
+func miln_last_error(h C.miln_handle, buf *C.char, bufLen C.int64_t) C.int64_t {
+	g := getGame(uint64(h))
+	if g == nil {
+		return C.MILN_ERR_HANDLE
+	}
+	return C.int64_t(copyString(bytesFromC(unsafe.Pointer(buf), bufLen),
+		g.lastError))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"sync"
+	"testing"
+)
+
+// testLevelYaml returns a generated level, so that there are hounds and the
+// simulation has some work to do.
+func testLevelYaml(t *testing.T) []byte {
+	RSeed(I(0))
+	l := GenerateLevel(os.DirFS("..").(FS))
+	filename := t.TempDir() + "/level.yaml"
+	l.SaveToYAML(I(0), filename)
+	return ReadFile(filename)
+}
+
+func TestGame(t *testing.T) {
+	g, err := newGame([]byte("not a level"), 0)
+	assert.NotNil(t, err)
+	assert.Nil(t, g)
+
+	g, err = newGame(testLevelYaml(t), 3)
+	assert.Nil(t, err)
+	assert.Equal(t, I(3), g.playthrough.Seed)
+
+	assert.Nil(t, g.step(input{action: actionMove, x: 2, y: 3, frames: 10}))
+	assert.Equal(t, 10, len(g.playthrough.History))
+	assert.True(t, g.playthrough.History[0].Move)
+	assert.False(t, g.playthrough.History[1].Move)
+
+	obs := make([]int32, obsSize)
+	assert.Equal(t, int64(obsSize), g.observe(obs))
+	assert.Equal(t, int32(10), obs[obsFrame])
+	assert.Equal(t, int32(1), obs[obsActionOk])
+	assert.Equal(t, int32(1), obs[obsPlayerOnMap])
+	assert.Equal(t, int32(2), obs[obsPlayerX])
+	assert.Equal(t, int32(3), obs[obsPlayerY])
+
+	assert.NotNil(t, g.step(input{action: 100}))
+	assert.Equal(t, 10, len(g.playthrough.History))
+	if !g.world.TurnBased {
+		assert.NotNil(t, g.step(input{action: actionUndo}))
+	}
+
+	p := DeserializePlaythrough(g.playthrough.Serialize())
+	assert.Equal(t, g.playthrough.History, p.History)
+}
+
+func TestGame_Buffers(t *testing.T) {
+	g, err := newGame(testLevelYaml(t), 0)
+	assert.Nil(t, err)
+
+	// A buffer that is too small is not touched.
+	small := make([]int32, obsSize-1)
+	small[0] = 7
+	assert.Equal(t, int64(obsSize), g.observe(small))
+	assert.Equal(t, int32(7), small[0])
+	assert.Equal(t, int64(obsSize), g.observe(nil))
+
+	// Only the observation is written into a buffer that is too big.
+	big := make([]int32, obsSize+1)
+	big[obsSize] = 7
+	assert.Equal(t, int64(obsSize), g.observe(big))
+	assert.Equal(t, int32(7), big[obsSize])
+
+	data := g.playthrough.Serialize()
+	assert.Equal(t, int64(len(data)), copyBytes(nil, data))
+	buf := make([]byte, len(data)-1)
+	assert.Equal(t, int64(len(data)), copyBytes(buf, data))
+	assert.Equal(t, make([]byte, len(data)-1), buf)
+	buf = make([]byte, len(data))
+	assert.Equal(t, int64(len(data)), copyBytes(buf, data))
+	assert.Equal(t, data, buf)
+
+	id := RegressionId(&g.playthrough)
+	buf = make([]byte, len(id)+1)
+	assert.Equal(t, int64(len(id)+1), copyString(buf, id))
+	assert.Equal(t, id+"\x00", string(buf))
+	assert.Equal(t, int64(len(id)+1), copyString(buf[:len(id)], id))
+}
+
+func TestHandles(t *testing.T) {
+	g, err := newGame(testLevelYaml(t), 0)
+	assert.Nil(t, err)
+	h1 := addGame(g)
+	h2 := addGame(g.clone())
+	assert.NotEqual(t, uint64(0), h1)
+	assert.NotEqual(t, h1, h2)
+
+	// A clone doesn't share anything with the original.
+	assert.Nil(t, getGame(h1).step(input{action: actionMove, x: 1, y: 1}))
+	assert.Equal(t, 1, len(getGame(h1).playthrough.History))
+	assert.Equal(t, 0, len(getGame(h2).playthrough.History))
+	assert.NotEqual(t, getGame(h1).playthrough.Id, getGame(h2).playthrough.Id)
+
+	// Destroyed handles stay invalid, even after new games are added.
+	assert.True(t, deleteGame(h1))
+	assert.False(t, deleteGame(h1))
+	assert.Nil(t, getGame(h1))
+	h3 := addGame(g.clone())
+	assert.NotEqual(t, h1, h3)
+	assert.Nil(t, getGame(h1))
+	assert.True(t, deleteGame(h2))
+	assert.True(t, deleteGame(h3))
+}
+
+// TestHandles_Concurrent runs independent games in parallel and checks that
+// they get the same results as when they run one after the other. Run it with
+// -race to also check that they don't share any memory.
+func TestHandles_Concurrent(t *testing.T) {
+	levelYaml := testLevelYaml(t)
+	play := func(seed int64) string {
+		g, err := newGame(levelYaml, seed)
+		Check(err)
+		h := addGame(g)
+		defer deleteGame(h)
+		obs := make([]int32, obsSize)
+		for i := range 50 {
+			g = getGame(h)
+			Check(g.step(input{action: actionMove, x: int32(i % NCols),
+				y: int32(i / NCols % NRows), frames: 20}))
+			g.observe(obs)
+			if obs[obsStatus] != statusCode[Ongoing] {
+				break
+			}
+		}
+		return RegressionId(&g.playthrough)
+	}
+
+	const n = 8
+	expected := make([]string, n)
+	for i := range n {
+		expected[i] = play(int64(i))
+	}
+
+	actual := make([]string, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			actual[i] = play(int64(i))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, expected, actual)
+}
@@ -0,0 +1,41 @@
+// Command milnbot lets external agents play Miln. It speaks the protocol of
+// package bot over stdin/stdout, or over a local TCP socket if -tcp is given,
+// in which case every connection gets its own session.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/marisvali/miln/bot"
+	. "github.com/marisvali/miln/gamelib"
+	"net"
+	"os"
+)
+
+func main() {
+	addr := flag.String("tcp", "", "listen on this address (e.g. localhost:7700) instead of stdin/stdout")
+	out := flag.String("out", ".", "folder where finished games are saved")
+	flag.Parse()
+	Check(os.MkdirAll(*out, os.ModePerm))
+
+	if *addr == "" {
+		s := bot.NewSession(*out)
+		Check(s.Serve(os.Stdin, os.Stdout))
+		return
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	Check(err)
+	fmt.Fprintf(os.Stderr, "listening on %s\n", listener.Addr())
+	for {
+		conn, err := listener.Accept()
+		Check(err)
+		go func() {
+			defer func() { Check(conn.Close()) }()
+			s := bot.NewSession(*out)
+			if err := s.Serve(conn, conn); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,82 @@
+package render
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// Thumbnail draws a level as it looks before the player enters it. Shadows
+// are not drawn because, with the player off the map, they would cover
+// everything.
+func (r *Renderer) Thumbnail(seed Int, l Level) *image.RGBA {
+	w := NewWorld(seed, l)
+	drawShadows := r.DrawShadows
+	r.DrawShadows = false
+	img := r.Draw(&w, 0)
+	r.DrawShadows = drawShadows
+	return img
+}
+
+// Frames replays the playthrough and draws the World after each input in
+// the range [start, end), keeping one frame out of every skip frames.
+func (r *Renderer) Frames(p Playthrough, start, end, skip int) (imgs []*image.RGBA) {
+	if end > len(p.History) {
+		end = len(p.History)
+	}
+	if start < 0 || start > end || skip <= 0 {
+		Check(fmt.Errorf("invalid frame range [%d, %d) with skip %d",
+			start, end, skip))
+	}
+	w := p.ReplayUpTo(start)
+	for i := start; i < end; i++ {
+		p.StepFrame(&w, i)
+		if (i-start)%skip == 0 {
+			imgs = append(imgs, r.Draw(&w, i))
+		}
+	}
+	return
+}
+
+func WritePNG(filename string, img image.Image) {
+	f, err := os.Create(filename)
+	Check(err)
+	defer CloseFile(f)
+	Check(png.Encode(f, img))
+}
+
+// WriteFrames writes each image as a numbered PNG file in dir.
+func WriteFrames(dir string, imgs []*image.RGBA) {
+	Check(os.MkdirAll(dir, os.ModePerm))
+	for i, img := range imgs {
+		WritePNG(filepath.Join(dir, fmt.Sprintf("frame-%05d.png", i)), img)
+	}
+}
+
+// WriteGIF writes the images as an animated GIF. The game runs at 60 frames
+// per second, so if only one frame out of every skip frames was kept, each
+// image is shown for skip/60 seconds to keep the real speed of the
+// playthrough.
+func WriteGIF(filename string, imgs []*image.RGBA, skip int) {
+	// GIF delays are in hundredths of a second.
+	delay := max(skip*100/60, 1)
+	var anim gif.GIF
+	for _, img := range imgs {
+		paletted := image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(paletted, img.Bounds(), img, image.Point{})
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	f, err := os.Create(filename)
+	Check(err)
+	defer CloseFile(f)
+	Check(gif.EncodeAll(f, &anim))
+}
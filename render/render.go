@@ -0,0 +1,347 @@
+// Package render draws a World into an image.RGBA using only the standard
+// library, so that levels and playthroughs can be turned into pictures on
+// machines that can't open a window. It mirrors what Gui.DrawPlayRegion does,
+// minus the parts that only make sense for a live player (mouse highlights,
+// buttons, text).
+package render
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+type Renderer struct {
+	Sprites
+	// Size of a tile in pixels.
+	BlockSize int
+	// If false, the tiles that the player can't see are drawn like the
+	// visible ones. Useful for thumbnails, where the player isn't on the map
+	// yet and everything would be in the shadow.
+	DrawShadows bool
+	// scaled caches the sprites resized to the sizes they were drawn at.
+	scaled map[scaledKey]*image.RGBA
+}
+
+type scaledKey struct {
+	img  image.Image
+	size image.Point
+}
+
+func NewRenderer(s Sprites, blockSize int) (r Renderer) {
+	r.Sprites = s
+	r.BlockSize = blockSize
+	r.DrawShadows = true
+	r.scaled = map[scaledKey]*image.RGBA{}
+	return
+}
+
+// Size returns the size of the images produced by Draw: a bar with the
+// player's health on top of the play region.
+func (r *Renderer) Size() image.Point {
+	return image.Pt(NCols*r.BlockSize, NRows*r.BlockSize+r.barHeight())
+}
+
+func (r *Renderer) barHeight() int {
+	return r.BlockSize / 2
+}
+
+// Draw draws the World as it is at frame frameIdx of a playthrough. The frame
+// index is only used to pick the images of animations.
+func (r *Renderer) Draw(w *World, frameIdx int) *image.RGBA {
+	size := r.Size()
+	img := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	draw.Draw(img, img.Bounds(), image.NewUniform(Col(0, 0, 0, 255)),
+		image.Point{}, draw.Src)
+
+	// Draw player health.
+	bar := image.Rect(0, 0, size.X, r.barHeight())
+	r.drawScaled(img, r.Background, bar, 255)
+	for i := range w.Player.Health.ToInt() {
+		x := i * bar.Dy()
+		r.drawScaled(img, r.PlayerHealth,
+			image.Rect(x, 0, x+bar.Dy(), bar.Dy()), 255)
+	}
+
+	play := img.SubImage(image.Rect(0, r.barHeight(), size.X, size.Y)).(*image.RGBA)
+	r.drawPlayRegion(play, w, frameIdx)
+	return img
+}
+
+func (r *Renderer) drawPlayRegion(img *image.RGBA, w *World, frameIdx int) {
+	// Draw ground, terrain and trees.
+	var pt Pt
+	for pt.Y = ZERO; pt.Y.Lt(I(NRows)); pt.Y.Inc() {
+		for pt.X = ZERO; pt.X.Lt(I(NCols)); pt.X.Inc() {
+			switch w.Terrain.Get(pt) {
+			case Mud:
+				r.drawTile(img, r.Mud, pt)
+			case Bush:
+				r.drawTile(img, r.Ground, pt)
+				r.drawTile(img, r.Bush, pt)
+			case Rock:
+				r.drawTile(img, r.Ground, pt)
+				r.drawTile(img, r.Rock, pt)
+			case Crate:
+				r.drawTile(img, r.Ground, pt)
+				r.drawTile(img, r.Crate, pt)
+			default:
+				r.drawTile(img, r.Ground, pt)
+			}
+			if w.Obstacles.At(pt) {
+				r.drawTile(img, r.Tree, pt)
+			}
+		}
+	}
+
+	// Draw ammo.
+	for i := range w.Ammos.N {
+		r.drawTile(img, r.Ammo, w.Ammos.V[i].Pos)
+	}
+
+	// Draw objectives, pickups and hounds.
+	for i := range w.Objectives.N {
+		r.drawObject(img, &w.Objectives.V[i], frameIdx)
+	}
+	for i := range w.Pickups.N {
+		r.drawObject(img, &w.Pickups.V[i], frameIdx)
+	}
+	for i := range w.Enemies.N {
+		e := &w.Enemies.V[i]
+		r.drawObject(img, e, frameIdx)
+		if e.Marked() {
+			r.drawTile(img, r.TargetMark, e.Pos())
+		}
+		r.drawEnemyHealth(img, e)
+	}
+
+	// Mark tiles that the player can't see.
+	if r.DrawShadows {
+		for pt.Y = ZERO; pt.Y.Lt(I(NRows)); pt.Y.Inc() {
+			for pt.X = ZERO; pt.X.Lt(I(NCols)); pt.X.Inc() {
+				if !w.Player.OnMap || !w.VisibleTiles.At(pt) {
+					r.drawTile(img, r.Shadow, pt)
+				}
+			}
+		}
+	}
+
+	// Draw beam.
+	if w.Beam.Idx.Gt(ZERO) {
+		from := r.tileCenter(w.Player.Pos())
+		to := image.Pt(
+			w.Beam.End.X.Times(I(r.BlockSize)).DivBy(w.BlockSize).ToInt(),
+			w.Beam.End.Y.Times(I(r.BlockSize)).DivBy(w.BlockSize).ToInt())
+		alpha := uint8(w.Beam.Idx.Times(I(255)).DivBy(w.BeamMax).ToInt())
+		r.drawLine(img, from, to, withAlpha(r.BeamColor, alpha))
+	}
+
+	// Show what the enemies will do in the next turn.
+	if w.TurnBased {
+		intents := w.EnemyIntents()
+		for i := range intents.N {
+			intent := intents.V[i]
+			if intent.Kind == NoIntent {
+				continue
+			}
+			col := r.IntentMoveColor
+			if intent.Kind == AttackIntent {
+				col = r.IntentAttackColor
+			}
+			from := r.tileCenter(intent.From)
+			to := r.tileCenter(intent.To)
+			head := from.Add(to.Sub(from).Mul(2).Div(3))
+			r.drawLine(img, from, head, col)
+			half := r.BlockSize / 16
+			draw.Draw(img, image.Rect(head.X-half, head.Y-half, head.X+half,
+				head.Y+half).Add(img.Rect.Min), image.NewUniform(col),
+				image.Point{}, draw.Over)
+		}
+	}
+
+	// Draw player.
+	if w.Player.OnMap {
+		r.drawObject(img, &w.Player, frameIdx)
+		r.drawPlayerAmmo(img, w)
+	}
+
+	// Draw hit effect.
+	p := &w.Player
+	if p.CooldownAfterGettingHitIdx.IsPositive() {
+		i := p.CooldownAfterGettingHitIdx
+		t := p.CooldownAfterGettingHit
+		alpha := uint8(i.Times(I(100)).DivBy(t).ToInt()) + 30
+		r.drawScaled(img, r.PlayerHitEffect,
+			image.Rect(0, 0, img.Rect.Dx(), img.Rect.Dy()), alpha)
+	}
+}
+
+// animation returns the name of the animation the GUI shows for an object
+// (see NewWorldObjectAnimation).
+func animation(o WorldObject) string {
+	switch o.(type) {
+	case *Hound:
+		switch o.State() {
+		case "Searching":
+			return "hound-searching"
+		case "PreparingToAttack":
+			return "hound-preparing-to-attack"
+		case "Attacking":
+			return "hound-attacking"
+		case "Hit":
+			return "hound-hit"
+		case "Dead":
+			return "hound-dead"
+		}
+	case *Player:
+		switch o.State() {
+		case "Resting":
+			return "player1"
+		case "Shooting":
+			return "player2"
+		}
+	case *Pickup:
+		switch o.State() {
+		case "Health":
+			return "pickup-health"
+		case "Energy":
+			return "pickup-energy"
+		case "DoubleDamage":
+			return "pickup-double-damage"
+		case "FasterCooldown":
+			return "pickup-faster-cooldown"
+		}
+	case *Objective:
+		switch o.State() {
+		case "Key":
+			return "key"
+		case "Exit":
+			return "exit"
+		case "ExitLocked":
+			return "exit-locked"
+		case "Ally":
+			return "ally"
+		}
+	}
+	return ""
+}
+
+func (r *Renderer) drawObject(img *image.RGBA, o WorldObject, frameIdx int) {
+	frames := r.Animations[animation(o)]
+	if len(frames) == 0 {
+		return
+	}
+	// The GUI restarts an animation when the state of its object changes, but
+	// the renderer doesn't keep track of states between frames. Pick the
+	// image from the frame index instead, at the same speed.
+	framesPerImage := 60 / AnimationFps.ToInt()
+	r.drawTile(img, frames[frameIdx/framesPerImage%len(frames)], o.Pos())
+}
+
+func (r *Renderer) drawEnemyHealth(img *image.RGBA, e *Hound) {
+	// The health sprite is scaled in the same proportion as the tile.
+	bounds := r.EnemyHealth.Bounds()
+	height := bounds.Dy() * r.BlockSize / bounds.Dx()
+	origin := r.tileOrigin(e.Pos())
+	for i := range e.Health().ToInt() {
+		x := origin.X + int(float64(height*i)*1.3)
+		r.drawScaled(img, r.EnemyHealth,
+			image.Rect(x, origin.Y, x+height, origin.Y+height), 255)
+	}
+}
+
+// drawPlayerAmmo draws the ammo as a circle around the player.
+func (r *Renderer) drawPlayerAmmo(img *image.RGBA, w *World) {
+	size := r.BlockSize / 4
+	center := r.tileCenter(w.Player.Pos())
+	radius := float64(r.BlockSize) / 2 * 85 / 100
+	totalPositions := w.AmmoLimit.ToFloat64()
+	for i := range w.Player.AmmoCount.ToInt() {
+		angle := (2*math.Pi/totalPositions)*float64(i) - (math.Pi / 2)
+		x := center.X + int(radius*math.Cos(angle)) - size/2
+		y := center.Y + int(radius*math.Sin(angle)) - size/2
+		r.drawScaled(img, r.PlayerAmmo, image.Rect(x, y, x+size, y+size), 255)
+	}
+}
+
+// tileOrigin returns the upper-left corner of a tile, relative to the play
+// region.
+func (r *Renderer) tileOrigin(pos Pt) image.Point {
+	return image.Pt(pos.X.ToInt()*r.BlockSize, pos.Y.ToInt()*r.BlockSize)
+}
+
+func (r *Renderer) tileCenter(pos Pt) image.Point {
+	return r.tileOrigin(pos).Add(image.Pt(r.BlockSize/2, r.BlockSize/2))
+}
+
+func (r *Renderer) drawTile(img *image.RGBA, sprite image.Image, pos Pt) {
+	margin := 1
+	origin := r.tileOrigin(pos)
+	rect := image.Rect(origin.X+margin, origin.Y+margin,
+		origin.X+r.BlockSize-margin, origin.Y+r.BlockSize-margin)
+	r.drawScaled(img, sprite, rect, 255)
+}
+
+// drawScaled draws the sprite stretched over rect, which is relative to the
+// upper-left corner of img.
+func (r *Renderer) drawScaled(img *image.RGBA, sprite image.Image,
+	rect image.Rectangle, alpha uint8) {
+	rect = rect.Add(img.Rect.Min)
+	scaled := r.scale(sprite, rect.Size())
+	if alpha == 255 {
+		draw.Draw(img, rect, scaled, image.Point{}, draw.Over)
+	} else {
+		mask := image.NewUniform(color.Alpha{A: alpha})
+		draw.DrawMask(img, rect, scaled, image.Point{}, mask, image.Point{},
+			draw.Over)
+	}
+}
+
+// scale resizes the sprite using the nearest neighbor, which keeps the pixel
+// art sharp.
+func (r *Renderer) scale(sprite image.Image, size image.Point) *image.RGBA {
+	key := scaledKey{sprite, size}
+	if scaled, ok := r.scaled[key]; ok {
+		return scaled
+	}
+	scaled := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	b := sprite.Bounds()
+	for y := range size.Y {
+		for x := range size.X {
+			scaled.Set(x, y, sprite.At(b.Min.X+x*b.Dx()/size.X,
+				b.Min.Y+y*b.Dy()/size.Y))
+		}
+	}
+	r.scaled[key] = scaled
+	return scaled
+}
+
+// drawLine draws a line between two points relative to the upper-left corner
+// of img, blending col over what is already there.
+func (r *Renderer) drawLine(img *image.RGBA, from, to image.Point, col color.Color) {
+	src := image.NewUniform(col)
+	dx := to.X - from.X
+	dy := to.Y - from.Y
+	n := max(abs(dx), abs(dy))
+	for i := range n {
+		x := from.X + dx*i/n
+		y := from.Y + dy*i/n
+		pixel := image.Rect(x, y, x+1, y+1).Add(img.Rect.Min)
+		draw.Draw(img, pixel, src, image.Point{}, draw.Over)
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func withAlpha(c color.Color, alpha uint8) color.Color {
+	r, g, b, _ := c.RGBA()
+	return Col(uint8(r>>8), uint8(g>>8), uint8(b>>8), alpha)
+}
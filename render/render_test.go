@@ -0,0 +1,38 @@
+package render
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"os"
+	"testing"
+)
+
+func testRenderer() Renderer {
+	return NewRenderer(LoadSprites(os.DirFS("..").(FS), "data/gui"), 20)
+}
+
+func TestRenderer_Thumbnail(t *testing.T) {
+	var l Level
+	l.Obstacles.Set(IPt(2, 3))
+	r := testRenderer()
+	img := r.Thumbnail(I(0), l)
+	assert.Equal(t, image.Pt(NCols*20, NRows*20+10), img.Bounds().Size())
+	assert.True(t, r.DrawShadows)
+
+	// Ground and trees are drawn differently.
+	ground := img.At(10, 10+10)
+	tree := img.At(2*20+10, 3*20+10+10)
+	assert.NotEqual(t, ground, tree)
+}
+
+func TestRenderer_Frames(t *testing.T) {
+	p := DeserializePlaythrough(ReadFile("../world/playthroughs/average-playthrough.mln999-999"))
+	r := testRenderer()
+	imgs := r.Frames(p, 100, 200, 10)
+	assert.Equal(t, 10, len(imgs))
+	for _, img := range imgs {
+		assert.Equal(t, r.Size(), img.Bounds().Size())
+	}
+}
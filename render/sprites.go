@@ -0,0 +1,110 @@
+package render
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"image"
+	"image/color"
+	_ "image/png"
+	"strconv"
+)
+
+// Sprites are the images from data/gui, loaded as plain image.Image values so
+// that they can be drawn without Ebiten.
+type Sprites struct {
+	Ground            image.Image
+	Tree              image.Image
+	Bush              image.Image
+	Rock              image.Image
+	Mud               image.Image
+	Crate             image.Image
+	Ammo              image.Image
+	SpawnPortal       image.Image
+	Shadow            image.Image
+	TargetMark        image.Image
+	PlayerHealth      image.Image
+	PlayerAmmo        image.Image
+	EnemyHealth       image.Image
+	PlayerHitEffect   image.Image
+	Background        image.Image
+	BeamColor         color.Color
+	IntentMoveColor   color.Color
+	IntentAttackColor color.Color
+	// Animations are indexed by the name of their files, without the frame
+	// number and extension (e.g. "hound-searching", "player1").
+	Animations map[string][]image.Image
+}
+
+var animationNames = []string{
+	"player1",
+	"player2",
+	"hound-searching",
+	"hound-preparing-to-attack",
+	"hound-attacking",
+	"hound-hit",
+	"hound-dead",
+	"pickup-health",
+	"pickup-energy",
+	"pickup-double-damage",
+	"pickup-faster-cooldown",
+	"key",
+	"exit",
+	"exit-locked",
+	"ally",
+}
+
+func loadImage(fsys FS, filename string) image.Image {
+	file, err := fsys.Open(filename)
+	Check(err)
+	defer func() { Check(file.Close()) }()
+	img, _, err := image.Decode(file)
+	Check(err)
+	return img
+}
+
+// loadAnimation loads the frames of an animation the same way
+// gamelib.NewAnimation does: "name1.png", "name2.png" ... or just "name.png"
+// if there are no numbered frames.
+func loadAnimation(fsys FS, name string) (frames []image.Image) {
+	for i := 1; ; i++ {
+		filename := name + strconv.Itoa(i) + ".png"
+		if !FileExists(fsys, filename) {
+			break
+		}
+		frames = append(frames, loadImage(fsys, filename))
+	}
+	if len(frames) == 0 {
+		frames = append(frames, loadImage(fsys, name+".png"))
+	}
+	return
+}
+
+// LoadSprites loads the images from the dir folder of fsys, which is
+// normally "data/gui".
+func LoadSprites(fsys FS, dir string) (s Sprites) {
+	load := func(name string) image.Image {
+		return loadImage(fsys, dir+"/"+name+".png")
+	}
+	s.Ground = load("ground")
+	s.Tree = load("tree")
+	s.Bush = load("bush")
+	s.Rock = load("rock")
+	s.Mud = load("mud")
+	s.Crate = load("crate")
+	s.Ammo = load("ammo")
+	s.SpawnPortal = load("spawn-portal")
+	s.Shadow = load("shadow")
+	s.TargetMark = load("target-mark")
+	s.PlayerHealth = load("player-health")
+	s.PlayerAmmo = load("player-ammo")
+	s.EnemyHealth = load("enemy-health")
+	s.PlayerHitEffect = load("player-hit-effect")
+	s.Background = load("text-background")
+	s.BeamColor = load("beam").At(0, 0)
+	s.IntentMoveColor = load("intent-move").At(0, 0)
+	s.IntentAttackColor = load("intent-attack").At(0, 0)
+	s.Animations = map[string][]image.Image{}
+	for _, name := range animationNames {
+		s.Animations[name] = loadAnimation(fsys, dir+"/"+name)
+	}
+	return
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"strings"
+)
+
+// ANSI 256-color codes.
+const (
+	colGround     = 22
+	colShadow     = 236
+	colMud        = 94
+	colTree       = 46
+	colBush       = 34
+	colRock       = 250
+	colCrate      = 180
+	colPlayer     = 231
+	colAmmo       = 226
+	colPickup     = 201
+	colObjective  = 45
+	colCursor     = 33
+	colTextNormal = 252
+)
+
+var houndStateColor = map[string]int{
+	"Searching":         220,
+	"PreparingToAttack": 208,
+	"Attacking":         196,
+	"Hit":               129,
+	"Dead":              240,
+}
+
+// cell is one tile of the board, drawn as two characters.
+type cell struct {
+	text string
+	fg   int
+	bg   int
+}
+
+// DrawBoard returns the 8x8 board as lines of text with ANSI colors. Each
+// tile takes two columns so that the board looks square. The tile under the
+// cursor gets a different background.
+func DrawBoard(w *World, cursor Pt) (lines []string) {
+	var cells [NRows][NCols]cell
+	var pt Pt
+	for pt.Y = ZERO; pt.Y.Lt(I(NRows)); pt.Y.Inc() {
+		for pt.X = ZERO; pt.X.Lt(I(NCols)); pt.X.Inc() {
+			c := cell{"  ", colTextNormal, colGround}
+			switch w.Terrain.Get(pt) {
+			case Mud:
+				c = cell{"~~", colTextNormal, colMud}
+			case Bush:
+				c.text, c.fg = "\"\"", colBush
+			case Rock:
+				c.text, c.fg = "/\\", colRock
+			case Crate:
+				c.text, c.fg = "[]", colCrate
+			}
+			if w.Obstacles.At(pt) {
+				c.text, c.fg = "##", colTree
+			}
+			if w.Player.OnMap && !w.VisibleTiles.At(pt) || !w.Player.OnMap {
+				c.bg = colShadow
+			}
+			cells[pt.Y.ToInt()][pt.X.ToInt()] = c
+		}
+	}
+
+	set := func(pos Pt, text string, fg int) {
+		c := &cells[pos.Y.ToInt()][pos.X.ToInt()]
+		c.text, c.fg = text, fg
+	}
+	for i := range w.Ammos.N {
+		set(w.Ammos.V[i].Pos, "**", colAmmo)
+	}
+	for i := range w.Pickups.N {
+		set(w.Pickups.V[i].Pos(), "++", colPickup)
+	}
+	for i := range w.Objectives.N {
+		o := &w.Objectives.V[i]
+		switch o.State() {
+		case "Key":
+			set(o.Pos(), "k ", colObjective)
+		case "Exit":
+			set(o.Pos(), "E ", colObjective)
+		case "ExitLocked":
+			set(o.Pos(), "e ", colObjective)
+		case "Ally":
+			set(o.Pos(), "A ", colObjective)
+		}
+	}
+	for i := range w.Enemies.N {
+		h := &w.Enemies.V[i]
+		set(h.Pos(), fmt.Sprintf("H%d", h.Health().ToInt()),
+			houndStateColor[h.State()])
+	}
+	if w.Player.OnMap {
+		set(w.Player.Pos(), "@"+fmt.Sprint(w.Player.AmmoCount.ToInt()%10), colPlayer)
+	}
+	if cursor.X.Geq(ZERO) && cursor.X.Lt(I(NCols)) &&
+		cursor.Y.Geq(ZERO) && cursor.Y.Lt(I(NRows)) {
+		cells[cursor.Y.ToInt()][cursor.X.ToInt()].bg = colCursor
+	}
+
+	for y := range NRows {
+		var sb strings.Builder
+		for x := range NCols {
+			c := cells[y][x]
+			sb.WriteString(fmt.Sprintf("\x1b[38;5;%dm\x1b[48;5;%dm%s", c.fg, c.bg, c.text))
+		}
+		sb.WriteString("\x1b[0m")
+		lines = append(lines, sb.String())
+	}
+	return
+}
+
+// DrawStatus returns a line with the player's health and ammo.
+func DrawStatus(w *World) string {
+	s := "Health: " + strings.Repeat("♥", max(w.Player.Health.ToInt(), 0))
+	if w.UseAmmo {
+		s += fmt.Sprintf("  Ammo: %d/%d", w.Player.AmmoCount.ToInt(),
+			w.AmmoLimit.ToInt())
+	}
+	return s
+}
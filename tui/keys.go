@@ -0,0 +1,69 @@
+package main
+
+// KeyCode identifies the keys that the terminal sends as escape sequences.
+// All other keys are reported as runes.
+type KeyCode int
+
+const (
+	KeyRune KeyCode = iota
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyEscape
+)
+
+type Key struct {
+	Code  KeyCode
+	Rune  rune
+	Shift bool
+	Alt   bool
+}
+
+var arrowCodes = map[byte]KeyCode{
+	'A': KeyUp,
+	'B': KeyDown,
+	'C': KeyRight,
+	'D': KeyLeft,
+}
+
+// ParseKeys turns the bytes read from a terminal in raw mode into keys.
+// It understands the escape sequences that xterm-like terminals send for
+// arrows, including the ones with Shift and Alt:
+// - ESC [ A, ESC O A: arrow
+// - ESC [ 1 ; 2 A: Shift + arrow
+// - ESC [ 1 ; 3 A, ESC ESC [ A: Alt + arrow
+// A lone ESC is reported as KeyEscape.
+func ParseKeys(b []byte) (keys []Key) {
+	for i := 0; i < len(b); {
+		if b[i] != 0x1b {
+			keys = append(keys, Key{Code: KeyRune, Rune: rune(b[i])})
+			i++
+			continue
+		}
+
+		alt := false
+		j := i + 1
+		if j < len(b) && b[j] == 0x1b {
+			alt = true
+			j++
+		}
+		if j+1 < len(b) && (b[j] == '[' || b[j] == 'O') {
+			j++
+			shift := false
+			if j+3 < len(b) && b[j] == '1' && b[j+1] == ';' {
+				shift = b[j+2] == '2'
+				alt = alt || b[j+2] == '3'
+				j += 3
+			}
+			if code, ok := arrowCodes[b[j]]; ok {
+				keys = append(keys, Key{Code: code, Shift: shift, Alt: alt})
+				i = j + 1
+				continue
+			}
+		}
+		keys = append(keys, Key{Code: KeyEscape})
+		i++
+	}
+	return
+}
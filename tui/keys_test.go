@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	keys := ParseKeys([]byte("a\x1b[A\x1bOD\x1b[1;2C\x1b[1;3D\x1b\x1b[B\x1b"))
+	assert.Equal(t, []Key{
+		{Code: KeyRune, Rune: 'a'},
+		{Code: KeyUp},
+		{Code: KeyLeft},
+		{Code: KeyRight, Shift: true},
+		{Code: KeyLeft, Alt: true},
+		{Code: KeyDown, Alt: true},
+		{Code: KeyEscape},
+	}, keys)
+}
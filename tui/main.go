@@ -0,0 +1,333 @@
+// Command tui plays or replays Miln in a terminal, for when the Ebiten window
+// is not available (e.g. over SSH). It runs the same simulation as the GUI and
+// records the same PlayerInput stream, so its playthroughs can be replayed and
+// analyzed like the ones recorded by the GUI.
+//
+// Usage:
+//
+//	tui                  play a random level (needs data/levelgenerator)
+//	tui <level.yaml>     play a level
+//	tui <playthrough>    replay a playthrough
+package main
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"golang.org/x/term"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The cursor always sits in the middle of a tile.
+var aim = TileAimParams
+
+// Seek controls for the replay, same as the defaults in data/gui/gui.yaml.
+var (
+	frameSkipArrow      = I(1)
+	frameSkipShiftArrow = I(10)
+	frameSkipAltArrow   = I(1)
+)
+
+type Tui struct {
+	playthrough Playthrough
+	world       World
+	cursor      Pt
+	paused      bool
+	frameIdx    Int
+	message     string
+	quit        bool
+	start       time.Time
+	saved       bool
+}
+
+func main() {
+	var t Tui
+	t.playthrough.InputVersion = I(InputVersion)
+	t.playthrough.SimulationVersion = I(SimulationVersion)
+	t.playthrough.ChecksumInterval = I(DefaultChecksumInterval)
+	playback := false
+	if len(os.Args) == 2 {
+		inputFile := os.Args[1]
+		if IsYamlLevel(inputFile) {
+			fs := os.DirFS(filepath.Dir(inputFile)).(FS)
+			t.startLevel(LoadLevelFromYAML(fs, filepath.Base(inputFile)))
+		} else {
+			playback = true
+			t.playthrough = DeserializePlaythrough(ReadFile(inputFile))
+			t.world = NewWorldFromPlaythrough(t.playthrough)
+		}
+	} else {
+		t.startLevel(RInt(I(0), I(1000000)), GenerateLevel(os.DirFS(".").(FS)))
+	}
+
+	fd := int(os.Stdin.Fd())
+	oldState, err := term.MakeRaw(fd)
+	Check(err)
+	// Use the alternate screen and hide the cursor while running.
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		Check(term.Restore(fd, oldState))
+	}()
+
+	keys := make(chan []Key, 100)
+	readErr := make(chan error, 1)
+	go readKeys(keys, readErr)
+
+	// The simulation runs at 60 frames per second, like in the GUI, but the
+	// screen is redrawn less often to be gentle with slow connections.
+	ticker := time.NewTicker(time.Second / 60)
+	defer ticker.Stop()
+	for frame := 0; !t.quit; frame++ {
+		<-ticker.C
+		select {
+		case err := <-readErr:
+			// Panic here and not in readKeys, so that the deferred
+			// function above gets to restore the terminal.
+			Check(err)
+		default:
+		}
+		var pressed []Key
+		for len(keys) > 0 {
+			pressed = append(pressed, <-keys...)
+		}
+		if playback {
+			t.updatePlayback(pressed)
+		} else {
+			t.updateGame(pressed)
+		}
+		if frame%4 == 0 || len(pressed) > 0 {
+			t.draw(playback)
+		}
+	}
+}
+
+// readKeys sends the keys read from stdin to keys until reading fails. Then
+// it sends the error to errs and stops.
+func readKeys(keys chan []Key, errs chan error) {
+	buf := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			errs <- err
+			return
+		}
+		keys <- ParseKeys(buf[:n])
+	}
+}
+
+func (t *Tui) startLevel(seed Int, l Level) {
+	t.playthrough.Seed = seed
+	t.playthrough.Level = l
+	t.playthrough.Id = uuid.New()
+	t.playthrough.History = t.playthrough.History[:0]
+	t.playthrough.Checksums = t.playthrough.Checksums[:0]
+	t.start = time.Now().UTC()
+	t.world = NewWorldFromPlaythrough(t.playthrough)
+	t.cursor = IPt(NCols/2, NRows/2)
+	t.message = ""
+	t.saved = false
+}
+
+// save writes the playthrough next to the executable, named after the current
+// time, like the GUI's recordings.
+func (t *Tui) save() string {
+	filename := fmt.Sprintf("%s.mln%03d-%03d",
+		time.Now().Format("20060102-150405"), InputVersion, SimulationVersion)
+	h := NewPlaythroughHeader(&t.playthrough, &t.world)
+	h.Start = t.start
+	h.Tags = []string{"tui"}
+	WriteFile(filename, SerializePlaythroughFile(h, &t.playthrough))
+	t.saved = true
+	return filename
+}
+
+// saveIfUnsaved saves a game that was played but not saved yet, e.g. when
+// quitting in the middle of a level. A finished level is saved right away, so
+// quitting or restarting afterwards doesn't save it again.
+func (t *Tui) saveIfUnsaved() {
+	if !t.saved && len(t.playthrough.History) > 0 {
+		t.save()
+	}
+}
+
+// moveCursor applies the arrow keys (or h, j, k, l) to the cursor.
+func (t *Tui) moveCursor(k Key) {
+	d := Pt{}
+	switch {
+	case k.Code == KeyUp || k.Code == KeyRune && k.Rune == 'k':
+		d = IPt(0, -1)
+	case k.Code == KeyDown || k.Code == KeyRune && k.Rune == 'j':
+		d = IPt(0, 1)
+	case k.Code == KeyLeft || k.Code == KeyRune && k.Rune == 'h':
+		d = IPt(-1, 0)
+	case k.Code == KeyRight || k.Code == KeyRune && k.Rune == 'l':
+		d = IPt(1, 0)
+	}
+	c := t.cursor.Plus(d)
+	if c.X.Geq(ZERO) && c.X.Lt(I(NCols)) && c.Y.Geq(ZERO) && c.Y.Lt(I(NRows)) {
+		t.cursor = c
+	}
+}
+
+func (t *Tui) updateGame(pressed []Key) {
+	var input PlayerInput
+	undoRequested := false
+	restartRequested := false
+	for _, k := range pressed {
+		t.moveCursor(k)
+		if k.Code != KeyRune {
+			continue
+		}
+		switch k.Rune {
+		case ' ', 'm':
+			input.LeftButtonPressed = true
+		case 'f', 's':
+			input.RightButtonPressed = true
+		case 'u':
+			undoRequested = true
+		case 'p':
+			t.paused = !t.paused
+		case 'r':
+			restartRequested = true
+		case 'q', 3: // 3 is Ctrl+C
+			t.saveIfUnsaved()
+			t.quit = true
+			return
+		}
+	}
+
+	if restartRequested {
+		t.saveIfUnsaved()
+		t.startLevel(t.playthrough.Seed, t.playthrough.Level)
+		return
+	}
+
+	if t.world.Status() != Ongoing {
+		return
+	}
+	if t.paused {
+		if !input.LeftButtonPressed && !input.RightButtonPressed {
+			return
+		}
+		// Like in the GUI, a click gets the game going again.
+		t.paused = false
+	}
+
+	input.MousePt = aim.TileToScreen(t.cursor)
+	input = aim.ResolveClicks(&t.world, input)
+	input.Undo = undoRequested && t.world.TurnBased && !input.Move && !input.Shoot
+	Step(&t.playthrough, &t.world, input)
+	t.saved = false
+
+	if t.world.Status() == Won {
+		t.message = "You won. Saved " + t.save() + ". r - restart, q - quit"
+	} else if t.world.Status() == Lost {
+		t.message = "You lost. Saved " + t.save() + ". r - restart, q - quit"
+	}
+}
+
+// updatePlayback has the same seek controls as Gui.UpdatePlayback. Terminals
+// only report key presses, not keys being held, so holding an arrow relies on
+// the terminal's key repeat. Digits jump to a tenth of the playthrough, like
+// clicking on the GUI's play bar.
+func (t *Tui) updatePlayback(pressed []Key) {
+	nFrames := I(len(t.playthrough.History))
+	targetFrameIdx := t.frameIdx
+	for _, k := range pressed {
+		switch {
+		case k.Code == KeyRune && (k.Rune == ' ' || k.Rune == 'p'):
+			t.paused = !t.paused
+		case k.Code == KeyRune && (k.Rune == 'q' || k.Rune == 3):
+			t.quit = true
+			return
+		case k.Code == KeyRune && k.Rune >= '0' && k.Rune <= '9':
+			targetFrameIdx = I(int(k.Rune - '0')).Times(nFrames).DivBy(I(10))
+		case k.Code == KeyLeft && k.Alt:
+			targetFrameIdx.Subtract(frameSkipAltArrow)
+		case k.Code == KeyRight && k.Alt:
+			targetFrameIdx.Add(frameSkipAltArrow)
+		case k.Code == KeyLeft && k.Shift:
+			targetFrameIdx.Subtract(frameSkipShiftArrow)
+		case k.Code == KeyRight && k.Shift:
+			targetFrameIdx.Add(frameSkipShiftArrow)
+		case k.Code == KeyLeft:
+			if t.paused {
+				targetFrameIdx.Subtract(frameSkipArrow)
+			} else {
+				targetFrameIdx.Subtract(frameSkipArrow.Times(TWO))
+			}
+		case k.Code == KeyRight:
+			targetFrameIdx.Add(frameSkipArrow)
+		}
+	}
+
+	if targetFrameIdx.Lt(ZERO) {
+		targetFrameIdx = ZERO
+	}
+	if targetFrameIdx.Gt(nFrames) {
+		targetFrameIdx = nFrames
+	}
+	if targetFrameIdx != t.frameIdx {
+		// Rewind and replay the world.
+		t.world = t.playthrough.ReplayUpTo(targetFrameIdx.ToInt())
+		t.frameIdx = targetFrameIdx
+	}
+
+	// The world has all the inputs before frameIdx applied to it. Once the
+	// end is reached, keep showing the last input.
+	if t.frameIdx.Lt(nFrames) && !t.paused {
+		t.playthrough.StepFrame(&t.world, t.frameIdx.ToInt())
+		t.frameIdx.Inc()
+	}
+	var input PlayerInput
+	if nFrames.Gt(ZERO) {
+		input = t.playthrough.History[max(t.frameIdx.ToInt()-1, 0)]
+	}
+	t.cursor = aim.ScreenToTile(input.MousePt)
+
+	t.message = fmt.Sprintf("Playing back frame %d / %d.",
+		t.frameIdx.ToInt64(), nFrames.ToInt64())
+	if input.LeftButtonPressed {
+		t.message += " Left button pressed."
+	}
+	if input.RightButtonPressed {
+		t.message += " Right button pressed."
+	}
+	if input.Undo {
+		t.message += " Undo."
+	}
+	if t.world.Status() == Won {
+		t.message += " Won."
+	}
+	if t.world.Status() == Lost {
+		t.message += " Lost."
+	}
+	if warning := t.playthrough.DesyncWarning(); warning != "" {
+		t.message += " " + warning
+	}
+}
+
+func (t *Tui) draw(playback bool) {
+	lines := []string{DrawStatus(&t.world)}
+	lines = append(lines, DrawBoard(&t.world, t.cursor)...)
+	if playback {
+		lines = append(lines, t.message,
+			"space - pause, arrows - seek (Shift/Alt for bigger/smaller "+
+				"steps), 0-9 - jump, q - quit")
+	} else {
+		lines = append(lines, t.world.ObjectivesText(), t.message,
+			"arrows/hjkl - cursor, space/m - move, f/s - shoot, "+
+				"u - undo, p - pause, r - restart, q - quit")
+		if t.paused {
+			lines = append(lines, "Paused.")
+		}
+	}
+	// Raw mode doesn't turn \n into \r\n. Clear each line in case the new
+	// text is shorter than the old one.
+	fmt.Print("\x1b[H" + strings.Join(lines, "\x1b[K\r\n") + "\x1b[K\x1b[J")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	. "github.com/marisvali/miln/world"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func testTui() (t Tui) {
+	t.playthrough.InputVersion = I(InputVersion)
+	t.playthrough.SimulationVersion = I(SimulationVersion)
+	var l Level
+	l.Objectives.N = 1
+	l.Objectives.V[0] = ObjectiveParams{Type: Survive, Seconds: I(1)}
+	t.startLevel(I(0), l)
+	return
+}
+
+func TestTui_UpdatePlayback_Empty(t *testing.T) {
+	tui := testTui()
+	assert.NotPanics(t, func() { tui.updatePlayback(nil) })
+}
+
+func TestTui_SavesOnce(t *testing.T) {
+	wd, err := os.Getwd()
+	Check(err)
+	Check(os.Chdir(t.TempDir()))
+	defer func() { Check(os.Chdir(wd)) }()
+
+	tui := testTui()
+	for tui.world.Status() == Ongoing {
+		tui.updateGame(nil)
+	}
+	files, err := os.ReadDir(".")
+	Check(err)
+	assert.Equal(t, 1, len(files))
+
+	// The name of the file only has seconds in it, so a second save would
+	// overwrite the first one. Remove the first one to see the second one.
+	DeleteFile(files[0].Name())
+	tui.updateGame([]Key{{Code: KeyRune, Rune: 'r'}})
+	tui.updateGame([]Key{{Code: KeyRune, Rune: 'q'}})
+	files, err = os.ReadDir(".")
+	Check(err)
+	assert.Empty(t, files)
+}
@@ -14,6 +14,8 @@ type WorldObjectAnimation struct {
 
 type TemporaryAnimation struct {
 	ScreenPos   Pt
+	OnTile      bool // if true, the animation is drawn at TilePos instead
+	TilePos     Pt
 	Animation   Animation
 	NFramesLeft Int
 }
@@ -65,6 +67,28 @@ func NewWorldObjectAnimation(wo WorldObject, anims Animations) *WorldObjectAnima
 		case "Shooting":
 			woa.Animation = anims.animPlayer2
 		}
+	case *Pickup:
+		switch wo.State() {
+		case "Health":
+			woa.Animation = anims.animPickupHealth
+		case "Energy":
+			woa.Animation = anims.animPickupEnergy
+		case "DoubleDamage":
+			woa.Animation = anims.animPickupDoubleDamage
+		case "FasterCooldown":
+			woa.Animation = anims.animPickupFasterCooldown
+		}
+	case *Objective:
+		switch wo.State() {
+		case "Key":
+			woa.Animation = anims.animKey
+		case "Exit":
+			woa.Animation = anims.animExit
+		case "ExitLocked":
+			woa.Animation = anims.animExitLocked
+		case "Ally":
+			woa.Animation = anims.animAlly
+		}
 	// case *SpawnPortal:
 	// 	woa.Animation = anims.animMoveFailed
 	default:
@@ -92,6 +116,14 @@ func (v *VisWorld) UpdateWhichObjectsExist(w *World) {
 	for i := range w.SpawnPortals.N {
 		objs = append(objs, &w.SpawnPortals.V[i])
 	}
+	for i := range w.Pickups.N {
+		objs = append(objs, &w.Pickups.V[i])
+	}
+	for i := range w.Objectives.N {
+		if w.Objectives.V[i].State() != "" {
+			objs = append(objs, &w.Objectives.V[i])
+		}
+	}
 
 	// Create animations for objects that don't have them.
 	// Either an animation wasn't created for this object or the object's
@@ -128,6 +160,21 @@ func (v *VisWorld) Step(w *World, input PlayerInput, guiData GuiData) {
 		v.Temporary = append(v.Temporary, &attackFailed)
 	}
 
+	// Show what happened in the world during this step.
+	for i := range w.StepEvents.N {
+		e := w.StepEvents.V[i]
+		if e.Type == EnemyKilled {
+			// The hound is removed from the world in the same step in which
+			// it dies, so its death is only visible as a temporary animation.
+			houndDead := TemporaryAnimation{}
+			houndDead.Animation = v.Animations.animHoundDead
+			houndDead.NFramesLeft = I(20)
+			houndDead.OnTile = true
+			houndDead.TilePos = e.Pos
+			v.Temporary = append(v.Temporary, &houndDead)
+		}
+	}
+
 	v.UpdateWhichObjectsExist(w)
 
 	// Remove obsolete animations.
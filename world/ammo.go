@@ -1,10 +1,211 @@
 package world
 
 import (
+	"fmt"
 	. "github.com/marisvali/miln/gamelib"
+	"strings"
 )
 
 type Ammo struct {
 	Pos   Pt
 	Count Int
 }
+
+// AmmoSpawnStrategy decides where new ammo appears on the map.
+// It is an int64 and not an int because it is part of the Level, which gets
+// serialized using encoding/binary, which only accepts fixed-size values.
+type AmmoSpawnStrategy int64
+
+const (
+	// AmmoAnywhere places ammo on a random free tile.
+	AmmoAnywhere AmmoSpawnStrategy = iota
+	// AmmoAtSpawnPoints places ammo on a random free spawn point from
+	// AmmoParams.SpawnPoints. If all spawn points are taken, no ammo spawns.
+	AmmoAtSpawnPoints
+	// AmmoFarFromPlayer places ammo on a random free tile that is at least
+	// AmmoParams.Distance tiles away from the player.
+	AmmoFarFromPlayer
+	// AmmoNearPortals places ammo on a random free tile that is at most
+	// AmmoParams.Distance tiles away from a spawn portal.
+	AmmoNearPortals
+)
+
+var ammoSpawnStrategyName = map[AmmoSpawnStrategy]string{
+	AmmoAnywhere:      "Anywhere",
+	AmmoAtSpawnPoints: "AtSpawnPoints",
+	AmmoFarFromPlayer: "FarFromPlayer",
+	AmmoNearPortals:   "NearPortals",
+}
+
+const defaultAmmoCount = 3
+
+type AmmoSpawnPoint struct {
+	Pos   Pt  `yaml:"Pos"`
+	Count Int `yaml:"Count"` // AmmoParams.Count is used if this is zero
+}
+
+// AmmoParams describes the ammo economy of a level. The zero value keeps the
+// original behavior: top up the ammo to the player's AmmoLimit right away,
+// with pickups of 3 placed anywhere on the map.
+type AmmoParams struct {
+	Strategy    AmmoSpawnStrategy    `yaml:"Strategy"`
+	SpawnPoints AmmoSpawnPointsArray `yaml:"SpawnPoints"`
+	Count       Int                  `yaml:"Count"` // ammo in a pickup, 3 if zero
+	// RespawnDelay is the number of frames between the player collecting ammo
+	// and new ammo appearing on the map.
+	RespawnDelay Int `yaml:"RespawnDelay"`
+	// Distance is in tiles, measured along the 8 directions the player sees.
+	// It is the minimum distance for AmmoFarFromPlayer and the maximum
+	// distance for AmmoNearPortals.
+	Distance Int `yaml:"Distance"`
+	// Limit caps the total ammo in the world, carried by the player or lying
+	// on the map. WorldParams.AmmoLimit is used if this is zero.
+	Limit Int `yaml:"Limit"`
+}
+
+func (p *AmmoParams) countOrDefault(count Int) Int {
+	if count.IsPositive() {
+		return count
+	}
+	if p.Count.IsPositive() {
+		return p.Count
+	}
+	return I(defaultAmmoCount)
+}
+
+func tileDist(a, b Pt) Int {
+	return Max(a.X.Minus(b.X).Abs(), a.Y.Minus(b.Y).Abs())
+}
+
+// SpawnAmmos tops up the ammo on the map according to the level's AmmoParams.
+func (w *World) SpawnAmmos() {
+	if !w.AmmoRespawnTimer.Ready() {
+		w.AmmoRespawnTimer.Update()
+		return
+	}
+
+	limit := w.Player.AmmoLimit
+	if w.AmmoParams.Limit.IsPositive() {
+		limit = w.AmmoParams.Limit
+	}
+
+	// Spawn new ammos
+	for w.Ammos.N < int64(len(w.Ammos.V)) {
+		// Count ammo available in the world now.
+		available := ZERO
+		for i := range w.Ammos.N {
+			available.Add(w.Ammos.V[i].Count)
+		}
+
+		// Count total ammo.
+		totalAmmo := w.Player.AmmoCount.Plus(available)
+
+		if totalAmmo.Geq(limit) {
+			// There's enough ammo available.
+			return
+		}
+
+		// Build matrix with positions occupied everywhere we don't want to
+		// spawn ammo.
+		occ := w.SolidTiles
+		for i := range w.Ammos.N {
+			occ.Set(w.Ammos.V[i].Pos)
+		}
+		occ.Set(w.Player.Pos())
+		for i := range w.Enemies.N {
+			occ.Set(w.Enemies.V[i].Pos())
+		}
+
+		ammo, ok := w.chooseAmmo(occ)
+		if !ok {
+			// There is no place left for ammo right now.
+			return
+		}
+
+		// Spawn ammo.
+		w.Ammos.V[w.Ammos.N] = ammo
+		w.Ammos.N++
+	}
+}
+
+func (w *World) chooseAmmo(occ MatBool) (ammo Ammo, ok bool) {
+	p := &w.AmmoParams
+	ammo.Count = p.countOrDefault(p.Count)
+
+	switch p.Strategy {
+	case AmmoAtSpawnPoints:
+		var free AmmoSpawnPointsArray
+		for i := range p.SpawnPoints.N {
+			if !occ.At(p.SpawnPoints.V[i].Pos) {
+				free.V[free.N] = p.SpawnPoints.V[i]
+				free.N++
+			}
+		}
+		if free.N == 0 {
+			return ammo, false
+		}
+		sp := free.V[w.RInt(ZERO, I64(free.N-1)).ToInt64()]
+		ammo.Pos = sp.Pos
+		ammo.Count = p.countOrDefault(sp.Count)
+		return ammo, true
+	case AmmoFarFromPlayer, AmmoNearPortals:
+		// Only keep the free tiles that match the strategy.
+		var candidates MatBool
+		for y := 0; y < NRows; y++ {
+			for x := 0; x < NCols; x++ {
+				pt := IPt(x, y)
+				if !occ.At(pt) && w.matchesAmmoStrategy(pt) {
+					candidates.Set(pt)
+				}
+			}
+		}
+		arr := candidates.ToArray()
+		if arr.N > 0 {
+			ammo.Pos = arr.V[w.RInt(ZERO, I64(arr.N-1)).ToInt64()]
+			return ammo, true
+		}
+		// No tile matches the strategy, so fall back to any free tile rather
+		// than starving the player.
+	}
+
+	free := occ
+	free.Negate()
+	if free.ToArray().N == 0 {
+		return ammo, false
+	}
+	ammo.Pos = occ.OccupyRandomPos(&w.Rand)
+	return ammo, true
+}
+
+func (w *World) matchesAmmoStrategy(pt Pt) bool {
+	switch w.AmmoParams.Strategy {
+	case AmmoFarFromPlayer:
+		if !w.Player.OnMap {
+			return true
+		}
+		return tileDist(pt, w.Player.Pos()).Geq(w.AmmoParams.Distance)
+	case AmmoNearPortals:
+		for i := range w.SpawnPortals.N {
+			if tileDist(pt, w.SpawnPortals.V[i].Pos()).Leq(w.AmmoParams.Distance) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (s AmmoSpawnStrategy) MarshalYAML() ([]byte, error) {
+	return []byte(ammoSpawnStrategyName[s]), nil
+}
+
+func (s *AmmoSpawnStrategy) UnmarshalYAML(b []byte) error {
+	str := strings.TrimSpace(string(b))
+	for k, v := range ammoSpawnStrategyName {
+		if v == str {
+			*s = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown ammo spawn strategy: %s", str)
+}
@@ -0,0 +1,73 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func Test_AmmoParamsYaml(t *testing.T) {
+	var p AmmoParams
+	p.Strategy = AmmoAtSpawnPoints
+	p.SpawnPoints.N = 2
+	p.SpawnPoints.V[0] = AmmoSpawnPoint{Pos: IPt(1, 2), Count: I(5)}
+	p.SpawnPoints.V[1] = AmmoSpawnPoint{Pos: IPt(6, 7)}
+	p.Count = I(2)
+	p.RespawnDelay = I(120)
+	p.Limit = I(8)
+
+	filename := "ammo.yaml"
+	SaveYAML(filename, p)
+	var p2 AmmoParams
+	LoadYAML(os.DirFS(".").(FS), filename, &p2)
+	DeleteFile(filename)
+	assert.Equal(t, p, p2)
+}
+
+func TestWorld_AmmoRespawnsAtSpawnPointAfterDelay(t *testing.T) {
+	var l Level
+	l.UseAmmo = true
+	l.AmmoLimit = I(10)
+	l.AmmoParams.Strategy = AmmoAtSpawnPoints
+	l.AmmoParams.SpawnPoints.N = 1
+	l.AmmoParams.SpawnPoints.V[0] = AmmoSpawnPoint{Pos: IPt(3, 3), Count: I(4)}
+	l.AmmoParams.RespawnDelay = I(5)
+	w := NewWorld(I(0), l)
+
+	w.Step(PlayerInput{})
+	assert.Equal(t, int64(1), w.Ammos.N)
+	assert.Equal(t, IPt(3, 3), w.Ammos.V[0].Pos)
+	assert.Equal(t, I(4), w.Ammos.V[0].Count)
+
+	w.Step(PlayerInput{Move: true, MovePt: IPt(3, 3)})
+	assert.Equal(t, I(4), w.Player.AmmoCount)
+
+	// The player moves away, but the spawn point stays empty until
+	// RespawnDelay frames have passed since the ammo was picked up.
+	w.Step(PlayerInput{Move: true, MovePt: IPt(4, 4)})
+	for range 3 {
+		w.Step(PlayerInput{})
+		assert.Equal(t, int64(0), w.Ammos.N)
+	}
+	w.Step(PlayerInput{})
+	assert.Equal(t, int64(1), w.Ammos.N)
+}
+
+func TestWorld_AmmoLimitCapsTotalAmmo(t *testing.T) {
+	var l Level
+	l.UseAmmo = true
+	l.AmmoLimit = I(10)
+	l.AmmoParams.Strategy = AmmoFarFromPlayer
+	l.AmmoParams.Count = I(1)
+	l.AmmoParams.Distance = I(3)
+	l.AmmoParams.Limit = I(4)
+	w := NewWorld(I(0), l)
+	w.Step(PlayerInput{Move: true, MovePt: IPt(0, 0)})
+	w.Step(PlayerInput{})
+
+	assert.Equal(t, int64(4), w.Ammos.N)
+	for i := range w.Ammos.N {
+		assert.True(t, tileDist(w.Ammos.V[i].Pos, IPt(0, 0)).Geq(I(3)))
+	}
+}
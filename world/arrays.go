@@ -19,6 +19,51 @@ type SpawnPortalsArray struct {
 	V [30]SpawnPortal
 }
 
+type PickupsArray struct {
+	N int64
+	V [30]Pickup
+}
+
+type PickupSpawnersArray struct {
+	N int64
+	V [10]PickupSpawner
+}
+
+type PickupParamsArray struct {
+	N int64
+	V [10]PickupParams
+}
+
+type AmmoSpawnPointsArray struct {
+	N int64
+	V [20]AmmoSpawnPoint
+}
+
+type ObjectivesArray struct {
+	N int64
+	V [10]Objective
+}
+
+type ObjectiveParamsArray struct {
+	N int64
+	V [10]ObjectiveParams
+}
+
+type LevelEventsArray struct {
+	N int64
+	V [30]LevelEvent
+}
+
+type EventsArray struct {
+	N int64
+	V [30]Event
+}
+
+type PendingSpawnsArray struct {
+	N int64
+	V [10]PendingSpawn
+}
+
 type PlayerInputArray struct {
 	N int64
 	V [20000]PlayerInput
@@ -65,3 +110,67 @@ func (a *WavesArray) UnmarshalYAML(b []byte) error {
 	copy(a.V[:], v)
 	return err
 }
+
+// MarshalYAML turns the array into a string.
+// Useful because if I just let the YAML library do the default marshalling, it
+// will turn the N field into "n" and it will output all the elements in the
+// array, not limit to the N.
+func (a PickupParamsArray) MarshalYAML() ([]byte, error) {
+	return yaml.Marshal(a.V[0:a.N])
+}
+
+func (a *PickupParamsArray) UnmarshalYAML(b []byte) error {
+	var v []PickupParams
+	err := yaml.Unmarshal(b, &v)
+	a.N = int64(len(v))
+	copy(a.V[:], v)
+	return err
+}
+
+// MarshalYAML turns the array into a string.
+// Useful because if I just let the YAML library do the default marshalling, it
+// will turn the N field into "n" and it will output all the elements in the
+// array, not limit to the N.
+func (a AmmoSpawnPointsArray) MarshalYAML() ([]byte, error) {
+	return yaml.Marshal(a.V[0:a.N])
+}
+
+func (a *AmmoSpawnPointsArray) UnmarshalYAML(b []byte) error {
+	var v []AmmoSpawnPoint
+	err := yaml.Unmarshal(b, &v)
+	a.N = int64(len(v))
+	copy(a.V[:], v)
+	return err
+}
+
+// MarshalYAML turns the array into a string.
+// Useful because if I just let the YAML library do the default marshalling, it
+// will turn the N field into "n" and it will output all the elements in the
+// array, not limit to the N.
+func (a ObjectiveParamsArray) MarshalYAML() ([]byte, error) {
+	return yaml.Marshal(a.V[0:a.N])
+}
+
+func (a *ObjectiveParamsArray) UnmarshalYAML(b []byte) error {
+	var v []ObjectiveParams
+	err := yaml.Unmarshal(b, &v)
+	a.N = int64(len(v))
+	copy(a.V[:], v)
+	return err
+}
+
+// MarshalYAML turns the array into a string.
+// Useful because if I just let the YAML library do the default marshalling, it
+// will turn the N field into "n" and it will output all the elements in the
+// array, not limit to the N.
+func (a LevelEventsArray) MarshalYAML() ([]byte, error) {
+	return yaml.Marshal(a.V[0:a.N])
+}
+
+func (a *LevelEventsArray) UnmarshalYAML(b []byte) error {
+	var v []LevelEvent
+	err := yaml.Unmarshal(b, &v)
+	a.N = int64(len(v))
+	copy(a.V[:], v)
+	return err
+}
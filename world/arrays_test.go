@@ -4,6 +4,7 @@ import (
 	. "github.com/marisvali/miln/gamelib"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -24,9 +25,10 @@ func Test_ArrayYaml(t *testing.T) {
 	a.V[1].Waves.V[1].NHounds = I(6)
 	a.V[1].Waves.V[2].NHounds = I(7)
 	a.V[1].Waves.V[2].SecondsAfterLastWave = I(404)
-	SaveYAML("try.yaml", a)
+	dir := t.TempDir()
+	SaveYAML(filepath.Join(dir, "try.yaml"), a)
 
 	var a2 SpawnPortalParamsArray
-	LoadYAML(os.DirFS(".").(FS), "try.yaml", &a2)
+	LoadYAML(os.DirFS(dir).(FS), "try.yaml", &a2)
 	assert.Equal(t, a, a2)
 }
@@ -0,0 +1,190 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+)
+
+// AimParams decide how a click of the mouse is turned into a move or a shot.
+// The GUI records the raw mouse position and buttons in each PlayerInput,
+// alongside the resolved Move and Shoot. Keeping the resolution here, away
+// from the GUI, means recorded clicks can be resolved again with different
+// settings and the playthrough re-simulated (see Reaim).
+type AimParams struct {
+	// Size of a tile on the screen, in pixels.
+	BlockSize Int
+	// Distance from the top-left corner of the screen to the top-left corner
+	// of the first tile, in pixels.
+	Margin Int
+	// If auto-aim is on, a click goes to the closest valid tile, as long as it
+	// is closer than BlockSize * Factor / 100 pixels.
+	AutoAimAttack       bool
+	AutoAimAttackFactor Int
+	AutoAimMove         bool
+	AutoAimMoveFactor   Int
+}
+
+// TileAimParams are for front ends that pick tiles directly instead of
+// pointing with a mouse, like the terminal and bots. They record the clicks
+// in the middle of the chosen tile, in the layout of the GUI (see
+// data/gui/gui.yaml), so that their playthroughs look like the ones recorded
+// by the GUI. Auto-aim is off because the clicks are never off target.
+var TileAimParams = AimParams{BlockSize: I(100), Margin: I(50)}
+
+// WithoutAutoAim returns the same params with auto-aim turned off, so a click
+// only counts if it is right on top of a valid tile.
+func (a AimParams) WithoutAutoAim() AimParams {
+	a.AutoAimAttack = false
+	a.AutoAimMove = false
+	return a
+}
+
+func (a AimParams) TileToScreen(pos Pt) Pt {
+	half := a.BlockSize.DivBy(TWO)
+	return pos.Times(a.BlockSize).Plus(Pt{half, half}).Plus(Pt{a.Margin, a.Margin})
+}
+
+func (a AimParams) ScreenToTile(pos Pt) Pt {
+	return pos.Minus(Pt{a.Margin, a.Margin}).DivBy(a.BlockSize)
+}
+
+// ClosestTile returns the tile whose center is closest to the mouse and the
+// distance from the mouse to that center, in pixels.
+func (a AimParams) ClosestTile(tiles []Pt, mousePt Pt) (tile Pt, dist Int) {
+	opt := []Pt{}
+	for _, pt := range tiles {
+		opt = append(opt, a.TileToScreen(pt))
+	}
+	_, closestPt := GetClosestPoint(opt, mousePt)
+	tile = a.ScreenToTile(closestPt)
+	dist = closestPt.To(mousePt).Len()
+	return
+}
+
+// AttackTarget returns the tile that a right click at mousePt shoots at and
+// if the shot is valid. Vulnerable enemies, crates and the portals that can be
+// destroyed can be shot.
+func (a AimParams) AttackTarget(w *World, mousePt Pt) (valid bool, target Pt) {
+	attackablePositions := w.VulnerableEnemyPositions()
+	attackablePositions.Add(w.CrateTiles())
+	attackablePositions.Add(w.DestructiblePortalPositions())
+	attackablePositions.IntersectWith(w.VisibleTiles)
+	if a.AutoAimAttack {
+		pos := attackablePositions.ToArray()
+		tilePos, dist := a.ClosestTile(pos.V[:pos.N], mousePt)
+		closeEnough := dist.Lt(a.BlockSize.Times(a.AutoAimAttackFactor).DivBy(I(100)))
+		attackOk := w.Player.OnMap && closeEnough
+		return attackOk, tilePos
+	} else {
+		tilePos := a.ScreenToTile(mousePt)
+		mouseCursorIsOverATarget :=
+			attackablePositions.InBounds(tilePos) &&
+				attackablePositions.At(tilePos)
+		attackOk := w.Player.OnMap && mouseCursorIsOverATarget
+		return attackOk, tilePos
+	}
+}
+
+// MoveTarget returns the tile that a left click at mousePt moves the player to
+// and if the move is valid.
+func (a AimParams) MoveTarget(w *World, mousePt Pt) (valid bool, target Pt) {
+	if a.AutoAimMove {
+		freePositions := w.Player.ComputeFreePositions(w).ToArray()
+		if freePositions.N == 0 {
+			// Nowhere to move, for example while waiting for a move cooldown.
+			return false, a.ScreenToTile(mousePt)
+		}
+		tilePos, dist := a.ClosestTile(freePositions.V[:freePositions.N], mousePt)
+		closeEnough := dist.Lt(a.BlockSize.Times(a.AutoAimMoveFactor).DivBy(I(100)))
+		return closeEnough, tilePos
+	} else {
+		freePositions := w.Player.ComputeFreePositions(w)
+		tilePos := a.ScreenToTile(mousePt)
+		mouseCursorIsOverAFreePosition :=
+			freePositions.InBounds(tilePos) &&
+				freePositions.At(tilePos)
+		return mouseCursorIsOverAFreePosition, tilePos
+	}
+}
+
+// ResolveClicks fills in the Move and Shoot parts of the input based on the
+// mouse position and buttons of the input and the current state of w.
+func (a AimParams) ResolveClicks(w *World, input PlayerInput) PlayerInput {
+	input.Move, input.MovePt = false, Pt{}
+	input.Shoot, input.ShootPt = false, Pt{}
+	if input.LeftButtonPressed {
+		input.Move, input.MovePt = a.MoveTarget(w, input.MousePt)
+	}
+	if input.RightButtonPressed {
+		input.Shoot, input.ShootPt = a.AttackTarget(w, input.MousePt)
+	}
+	return input
+}
+
+// ReaimStats describe how the clicks of a playthrough were resolved when it
+// was replayed with different AimParams.
+type ReaimStats struct {
+	NMoveClicks int64
+	NMoves      int64
+	// Moves that would have failed without auto-aim.
+	NMovesRescued int64
+	NShotClicks   int64
+	NShots        int64
+	// Shots that would have failed without auto-aim.
+	NShotsRescued int64
+	// Clicks that resolved differently than in the original playthrough.
+	NChangedActions int64
+	Status          WorldStatus
+	NFrames         int64
+}
+
+// Reaim resolves every recorded click of the playthrough again, using a
+// instead of the settings that were active when the playthrough was recorded,
+// and re-simulates the World. Once one click resolves differently, the World
+// diverges from the original one, so later clicks are resolved against the
+// new World and not the recorded one. Recording stops when the level ends,
+// even if the original playthrough went on.
+func Reaim(p Playthrough, a AimParams) (reaimed Playthrough, s ReaimStats) {
+	noAim := a.WithoutAutoAim()
+	reaimed = p
+	reaimed.History = make([]PlayerInput, 0, len(p.History))
+	// The checksums are of the original inputs, Step records new ones.
+	reaimed.Checksums = nil
+	reaimed.DesyncFrame = ZERO
+	w := NewWorldFromPlaythrough(p)
+	for i := range p.History {
+		if w.Status() != Ongoing {
+			break
+		}
+		original := p.History[i]
+		input := a.ResolveClicks(&w, original)
+		input.Undo = original.Undo && !input.Move && !input.Shoot
+		if input.LeftButtonPressed {
+			s.NMoveClicks++
+			if input.Move {
+				s.NMoves++
+				if valid, _ := noAim.MoveTarget(&w, input.MousePt); !valid {
+					s.NMovesRescued++
+				}
+			}
+		}
+		if input.RightButtonPressed {
+			s.NShotClicks++
+			if input.Shoot {
+				s.NShots++
+				if valid, _ := noAim.AttackTarget(&w, input.MousePt); !valid {
+					s.NShotsRescued++
+				}
+			}
+		}
+		if input.Move != original.Move || input.Shoot != original.Shoot ||
+			input.Move && input.MovePt != original.MovePt ||
+			input.Shoot && input.ShootPt != original.ShootPt {
+			s.NChangedActions++
+		}
+
+		Step(&reaimed, &w, input)
+	}
+	s.Status = w.Status()
+	s.NFrames = int64(len(reaimed.History))
+	return
+}
@@ -0,0 +1,87 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func testAimParams() AimParams {
+	return AimParams{
+		BlockSize:           I(100),
+		Margin:              I(50),
+		AutoAimAttack:       true,
+		AutoAimAttackFactor: I(150),
+		AutoAimMove:         true,
+		AutoAimMoveFactor:   I(150),
+	}
+}
+
+func TestAimParams_MoveTarget(t *testing.T) {
+	var l Level
+	w := NewWorld(I(0), l)
+	a := testAimParams()
+
+	// A click right on a tile goes to that tile, with or without auto-aim.
+	valid, target := a.MoveTarget(&w, a.TileToScreen(IPt(2, 3)))
+	assert.True(t, valid)
+	assert.Equal(t, IPt(2, 3), target)
+	valid, target = a.WithoutAutoAim().MoveTarget(&w, a.TileToScreen(IPt(2, 3)))
+	assert.True(t, valid)
+	assert.Equal(t, IPt(2, 3), target)
+
+	// A click just outside the map only counts with auto-aim.
+	mousePt := a.TileToScreen(IPt(7, 7)).Plus(IPt(100, 0))
+	valid, target = a.MoveTarget(&w, mousePt)
+	assert.True(t, valid)
+	assert.Equal(t, IPt(7, 7), target)
+	valid, _ = a.WithoutAutoAim().MoveTarget(&w, mousePt)
+	assert.False(t, valid)
+}
+
+func TestAimParams_ResolveClicksBreaksCrate(t *testing.T) {
+	var l Level
+	l.CrateMaxHealth = I(2)
+	l.Terrain.Set(IPt(2, 0), Crate)
+	w := NewWorld(I(0), l)
+	w.Step(PlayerInput{Move: true, MovePt: IPt(0, 0)})
+
+	for _, a := range []AimParams{testAimParams(), TileAimParams} {
+		input := PlayerInput{MousePt: a.TileToScreen(IPt(2, 0)),
+			RightButtonPressed: true}
+		input = a.ResolveClicks(&w, input)
+		assert.True(t, input.Shoot)
+		assert.Equal(t, IPt(2, 0), input.ShootPt)
+		w.Step(input)
+	}
+	assert.Equal(t, Ground, w.Terrain.Get(IPt(2, 0)))
+}
+
+func TestReaim(t *testing.T) {
+	// A level without goals is won right away, so give it one.
+	var p Playthrough
+	p.SimulationVersion = I(SimulationVersion)
+	p.Level.Objectives.N = 1
+	p.Level.Objectives.V[0] = ObjectiveParams{Type: Survive, Seconds: I(10)}
+	a := testAimParams()
+	mousePt := a.TileToScreen(IPt(7, 7)).Plus(IPt(100, 0))
+	p.History = []PlayerInput{
+		{MousePt: mousePt, LeftButtonPressed: true},
+		{},
+	}
+
+	reaimed, s := Reaim(p, a)
+	assert.Equal(t, int64(1), s.NMoveClicks)
+	assert.Equal(t, int64(1), s.NMoves)
+	assert.Equal(t, int64(1), s.NMovesRescued)
+	assert.Equal(t, int64(1), s.NChangedActions)
+	assert.True(t, reaimed.History[0].Move)
+	assert.Equal(t, IPt(7, 7), reaimed.History[0].MovePt)
+	w := reaimed.ReplayUpTo(len(reaimed.History))
+	assert.Equal(t, IPt(7, 7), w.Player.Pos())
+
+	_, s = Reaim(p, a.WithoutAutoAim())
+	assert.Equal(t, int64(1), s.NMoveClicks)
+	assert.Equal(t, int64(0), s.NMoves)
+	assert.Equal(t, int64(0), s.NChangedActions)
+}
@@ -0,0 +1,81 @@
+package world
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	"hash/crc32"
+)
+
+// DefaultChecksumInterval is how often the GUI records a checksum of the
+// World: once per second.
+const DefaultChecksumInterval = 60
+
+// Checksums let a replay find out exactly when it stopped matching the game
+// that was recorded. This matters when the game is recorded by one build
+// (e.g. the .wasm running in a browser) and replayed by another (e.g. the
+// desktop build), where the simulation should be identical but might not be.
+//
+// If Playthrough.ChecksumInterval is N, Step records the StateChecksum of the
+// World every N frames. Checksums[k] is the checksum of the World after the
+// first (k+1)*N inputs. StepFrame compares these checksums to the World it
+// steps and remembers the first frame where they differ in DesyncFrame.
+
+// StateChecksum is a compact version of World.State.
+func StateChecksum(w *World) uint32 {
+	return crc32.ChecksumIEEE(w.State())
+}
+
+// checksumIdx returns the index in Checksums of the checksum for the World
+// after nFrames inputs, or -1 if no checksum is recorded at that frame.
+func (p *Playthrough) checksumIdx(nFrames int) int {
+	interval := p.ChecksumInterval.ToInt()
+	if interval <= 0 || nFrames == 0 || nFrames%interval != 0 {
+		return -1
+	}
+	return nFrames/interval - 1
+}
+
+// recordChecksum records the checksum of w, which must be the World after all
+// the inputs in History, if a checksum is due at this frame.
+func (p *Playthrough) recordChecksum(w *World) {
+	idx := p.checksumIdx(len(p.History))
+	if idx < 0 {
+		return
+	}
+	// Drop checksums of frames that were replaced since they were recorded.
+	p.Checksums = append(p.Checksums[:min(idx, len(p.Checksums))],
+		StateChecksum(w))
+}
+
+// verifyChecksum checks w, which must be the World after the first nFrames
+// inputs, against the recorded checksum, if there is one.
+func (p *Playthrough) verifyChecksum(w *World, nFrames int) {
+	idx := p.checksumIdx(nFrames)
+	if idx < 0 || idx >= len(p.Checksums) {
+		return
+	}
+	if StateChecksum(w) == p.Checksums[idx] {
+		return
+	}
+	if p.DesyncFrame.IsZero() || I(nFrames).Lt(p.DesyncFrame) {
+		p.DesyncFrame = I(nFrames)
+	}
+}
+
+// DesyncWarning describes the desync found by StepFrame, if there is one.
+func (p *Playthrough) DesyncWarning() string {
+	if p.DesyncFrame.IsZero() {
+		return ""
+	}
+	first := p.DesyncFrame.Minus(p.ChecksumInterval).Plus(ONE)
+	return fmt.Sprintf("Warning: the replay stopped matching the recording "+
+		"between frames %d and %d.", first.ToInt64(), p.DesyncFrame.ToInt64())
+}
+
+// FindDesync replays the whole playthrough and returns the first frame where
+// the World doesn't match the recorded checksums, or 0 if it always matches
+// (or there are no checksums).
+func (p *Playthrough) FindDesync() int {
+	p.ReplayUpTo(len(p.History))
+	return p.DesyncFrame.ToInt()
+}
@@ -0,0 +1,65 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// recordWithChecksums plays the inputs of the playthrough again, recording
+// checksums like the GUI does.
+func recordWithChecksums(p Playthrough) (r Playthrough) {
+	r = p
+	r.History = nil
+	r.ChecksumInterval = I(60)
+	w := NewWorldFromPlaythrough(r)
+	for _, input := range p.History {
+		Step(&r, &w, input)
+	}
+	return
+}
+
+func TestChecksums(t *testing.T) {
+	// Playthroughs saved before checksums existed (InputVersion 999) still
+	// load.
+	old := DeserializePlaythrough(ReadFile("playthroughs/average-playthrough.mln999-999"))
+	assert.Equal(t, I(InputVersion), old.InputVersion)
+	assert.True(t, old.ChecksumInterval.IsZero())
+	assert.Equal(t, 0, len(old.Checksums))
+	assert.Equal(t, 0, old.FindDesync())
+	assert.Equal(t, "", old.DesyncWarning())
+
+	p := recordWithChecksums(old)
+	assert.Equal(t, len(p.History)/60, len(p.Checksums))
+	p = DeserializePlaythrough(p.Serialize())
+	assert.Equal(t, I(60), p.ChecksumInterval)
+	assert.Equal(t, len(p.History)/60, len(p.Checksums))
+	assert.Equal(t, 0, p.FindDesync())
+
+	// Pretend the recording was made by a different simulation.
+	p.Checksums[3]++
+	assert.Equal(t, 240, p.FindDesync())
+	assert.Equal(t, "Warning: the replay stopped matching the recording "+
+		"between frames 181 and 240.", p.DesyncWarning())
+	p.Checksums[1]++
+	p.ReplayUpTo(200)
+	assert.Equal(t, 120, p.DesyncFrame.ToInt())
+}
+
+func TestChecksums_Restart(t *testing.T) {
+	p := recordWithChecksums(
+		DeserializePlaythrough(ReadFile("playthroughs/average-playthrough.mln999-999")))
+
+	// Restart the level, like the GUI does, but without clearing the
+	// checksums. The old ones get replaced.
+	history := p.History
+	p.History = p.History[:0:0]
+	p.Seed.Inc()
+	w := NewWorldFromPlaythrough(p)
+	for i := range 130 {
+		Step(&p, &w, history[i])
+	}
+	assert.True(t, p.DesyncFrame.IsZero())
+	assert.Equal(t, 2, len(p.Checksums))
+	assert.Equal(t, 0, p.FindDesync())
+}
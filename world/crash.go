@@ -0,0 +1,109 @@
+package world
+
+import (
+	"bytes"
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	"runtime/debug"
+)
+
+// Crash is what the game saves when it panics, so that the crash can be
+// reproduced later by replaying the playthrough that was being played.
+// The versions are the ones of the build that crashed. They usually match the
+// versions of the Playthrough, but a playthrough loaded from a file and played
+// further might have been recorded by an older release.
+type Crash struct {
+	Message           string
+	Stack             string
+	ReleaseVersion    Int
+	SimulationVersion Int
+	InputVersion      Int
+	Playthrough       Playthrough
+}
+
+// NewCrash builds a Crash from the value returned by recover(). It must be
+// called from the deferred function that recovered the panic, otherwise the
+// stack doesn't show where the panic happened.
+// Step records the input before stepping the World, so if the World panics
+// the input that caused the panic is already part of the playthrough.
+func NewCrash(p *Playthrough, releaseVersion Int, r any) Crash {
+	return Crash{
+		Message:           fmt.Sprint(r),
+		Stack:             string(debug.Stack()),
+		ReleaseVersion:    releaseVersion,
+		SimulationVersion: I(SimulationVersion),
+		InputVersion:      I(InputVersion),
+		Playthrough:       *p.Clone(),
+	}
+}
+
+// CrashSuffix ends the names of crash files, so that the tools that look for
+// playthroughs (*.mln*) can leave them out. Crashes are uploaded for the user
+// name followed by CrashSuffix, so that downloads can tell them apart from
+// playthroughs.
+const CrashSuffix = "-crash"
+
+// Filename is the name under which a crash is saved, e.g.
+// crash-<playthrough id>.mln1000-999-crash.
+func (c *Crash) Filename() string {
+	return fmt.Sprintf("crash-%s.mln%03d-%03d%s", c.Playthrough.Id,
+		c.InputVersion.ToInt64(), c.SimulationVersion.ToInt64(), CrashSuffix)
+}
+
+func (c *Crash) Serialize() []byte {
+	buf := new(bytes.Buffer)
+	Serialize(buf, c.ReleaseVersion)
+	Serialize(buf, c.SimulationVersion)
+	Serialize(buf, c.InputVersion)
+	SerializeSlice(buf, []byte(c.Message))
+	SerializeSlice(buf, []byte(c.Stack))
+	SerializeSlice(buf, c.Playthrough.Serialize())
+	return Zip(buf.Bytes())
+}
+
+func DeserializeCrash(data []byte) (c Crash) {
+	c, err := TryDeserializeCrash(data)
+	Check(err)
+	return
+}
+
+// TryDeserializeCrash returns the errors of TryDeserializePlaythrough. It
+// doesn't check that the crash can be reproduced by this build, see
+// Reproduce.
+func TryDeserializeCrash(data []byte) (c Crash, err error) {
+	unzipped, err := TryUnzip(data)
+	if err != nil {
+		return
+	}
+	buf := bytes.NewBuffer(unzipped)
+	for _, field := range []any{&c.ReleaseVersion, &c.SimulationVersion,
+		&c.InputVersion} {
+		if err = TryDeserialize(buf, field); err != nil {
+			return
+		}
+	}
+	var message, stack, playthrough []byte
+	for _, field := range []*[]byte{&message, &stack, &playthrough} {
+		if err = TryDeserializeSlice(buf, field); err != nil {
+			return
+		}
+	}
+	c.Message = string(message)
+	c.Stack = string(stack)
+	c.Playthrough, err = TryDeserializePlaythrough(playthrough)
+	return
+}
+
+// Reproduce replays the playthrough of the crash and returns the failure it
+// leads to. The crash is reproduced if the failure has the same Message.
+// Only a build with the same SimulationVersion as the one that crashed can
+// reproduce the crash.
+func (c *Crash) Reproduce() (f Failure, failed bool, err error) {
+	if c.SimulationVersion.ToInt64() != SimulationVersion {
+		err = &VersionError{"SimulationVersion",
+			c.SimulationVersion.ToInt64(), SimulationVersion}
+		return
+	}
+	f, failed = ReplayForFailure(c.Playthrough, nil)
+	return
+}
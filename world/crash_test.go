@@ -0,0 +1,44 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCrash(t *testing.T) {
+	p := DeserializePlaythrough(ReadFile("playthroughs/average-playthrough.mln999-999"))
+	// A portal outside the map, which panics when the World marks the tiles
+	// around it as visible.
+	p.SpawnPortalsParams.V[0].Pos = IPt(100, 100)
+	history := p.History
+	p.History = nil
+
+	// Play like the GUI does, until the World panics.
+	var c Crash
+	func() {
+		w := NewWorldFromPlaythrough(p)
+		defer func() {
+			c = NewCrash(&p, I(123), recover())
+		}()
+		for _, input := range history {
+			Step(&p, &w, input)
+		}
+	}()
+	assert.Contains(t, c.Message, "index out of range")
+	assert.Contains(t, c.Stack, "computeVisibleTiles")
+	assert.Equal(t, I(123), c.ReleaseVersion)
+	assert.Equal(t, 2, len(c.Playthrough.History))
+
+	c = DeserializeCrash(c.Serialize())
+	assert.Equal(t, 2, len(c.Playthrough.History))
+	f, failed, err := c.Reproduce()
+	assert.Nil(t, err)
+	assert.True(t, failed)
+	assert.Equal(t, c.Message, f.Message)
+	assert.Equal(t, 1, f.Frame)
+
+	c.SimulationVersion = I(SimulationVersion + 1)
+	_, _, err = c.Reproduce()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,225 @@
+package world
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+)
+
+// StateTrace is the State of the World at every frame of a playthrough.
+// Element 0 is the State before any input, element i is the State after the
+// input at index i-1 in the playthrough's History.
+// RegressionId only says that two runs of a playthrough differ, a StateTrace
+// makes it possible to find out where (see FindDivergence). To compare two
+// builds, save the trace of one build and compare it to the trace of the
+// other.
+type StateTrace [][]byte
+
+// ComputeStateTrace runs the playthrough the same way RegressionId does and
+// keeps every State instead of hashing it.
+func ComputeStateTrace(p *Playthrough) (t StateTrace) {
+	w := NewWorldFromPlaythrough(*p)
+	t = append(t, w.State())
+	for i := range p.History {
+		p.StepFrame(&w, i)
+		t = append(t, w.State())
+	}
+	return
+}
+
+func (t StateTrace) Serialize() []byte {
+	buf := new(bytes.Buffer)
+	Serialize(buf, int64(len(t)))
+	for _, s := range t {
+		Serialize(buf, int64(len(s)))
+		Serialize(buf, s)
+	}
+	return Zip(buf.Bytes())
+}
+
+func DeserializeStateTrace(data []byte) (t StateTrace) {
+	buf := bytes.NewBuffer(Unzip(data))
+	var n int64
+	Deserialize(buf, &n)
+	t = make(StateTrace, n)
+	for i := range t {
+		var size int64
+		Deserialize(buf, &size)
+		t[i] = make([]byte, size)
+		Deserialize(buf, t[i])
+	}
+	return
+}
+
+// FrameState is a World.State() taken apart again, so that two states can be
+// compared field by field.
+type FrameState struct {
+	PlayerHealth Int
+	PlayerPos    Pt
+	Hounds       []HoundFrameState
+	Obstacles    []Pt
+	// Only the tiles that aren't Ground.
+	Terrain []TerrainFrameState
+}
+
+type HoundFrameState struct {
+	Health Int
+	Pos    Pt
+}
+
+type TerrainFrameState struct {
+	Pos         Pt
+	Type        TerrainType
+	CrateHealth Int
+}
+
+// DecodeState is the reverse of World.State.
+func DecodeState(state []byte) (s FrameState) {
+	buf := bytes.NewBuffer(state)
+	Deserialize(buf, &s.PlayerHealth)
+	Deserialize(buf, &s.PlayerPos)
+	var nHounds int64
+	Deserialize(buf, &nHounds)
+	s.Hounds = make([]HoundFrameState, nHounds)
+	for i := range s.Hounds {
+		Deserialize(buf, &s.Hounds[i].Health)
+		Deserialize(buf, &s.Hounds[i].Pos)
+	}
+	// The obstacles are the rest of the bytes, their number is not stored,
+	// unless there is terrain after them. The terrain ends with its number
+	// of tiles, which is what makes the rest not a whole number of Pts.
+	rest := buf.Bytes()
+	var nTerrain int64
+	ptSize := binary.Size(Pt{})
+	if len(rest)%ptSize != 0 {
+		countSize := binary.Size(nTerrain)
+		Deserialize(bytes.NewBuffer(rest[len(rest)-countSize:]), &nTerrain)
+		rest = rest[:len(rest)-countSize]
+	}
+	s.Terrain = make([]TerrainFrameState, nTerrain)
+	terrainSize := binary.Size(s.Terrain)
+	s.Obstacles = make([]Pt, (len(rest)-terrainSize)/ptSize)
+	buf = bytes.NewBuffer(rest)
+	Deserialize(buf, s.Obstacles)
+	Deserialize(buf, s.Terrain)
+	return
+}
+
+// String has the same format as World.StateStr.
+func (s *FrameState) String() string {
+	str := fmt.Sprintf("%02d %02d %02d  ", s.PlayerHealth.ToInt(),
+		s.PlayerPos.X.ToInt(), s.PlayerPos.Y.ToInt())
+	for _, h := range s.Hounds {
+		str += fmt.Sprintf("%02d %02d %02d  ", h.Health.ToInt(),
+			h.Pos.X.ToInt(), h.Pos.Y.ToInt())
+	}
+	return str
+}
+
+func ptStr(pt Pt) string {
+	return fmt.Sprintf("(%d, %d)", pt.X.ToInt(), pt.Y.ToInt())
+}
+
+// DiffStates describes every field that is different between two states,
+// e.g. "hound 2 pos: (3, 4) != (3, 5)". The expected value comes first.
+func DiffStates(expected, actual FrameState) (diffs []string) {
+	if !expected.PlayerHealth.Eq(actual.PlayerHealth) {
+		diffs = append(diffs, fmt.Sprintf("player health: %d != %d",
+			expected.PlayerHealth.ToInt(), actual.PlayerHealth.ToInt()))
+	}
+	if !expected.PlayerPos.Eq(actual.PlayerPos) {
+		diffs = append(diffs, fmt.Sprintf("player pos: %s != %s",
+			ptStr(expected.PlayerPos), ptStr(actual.PlayerPos)))
+	}
+	if len(expected.Hounds) != len(actual.Hounds) {
+		diffs = append(diffs, fmt.Sprintf("number of hounds: %d != %d",
+			len(expected.Hounds), len(actual.Hounds)))
+	}
+	for i := range min(len(expected.Hounds), len(actual.Hounds)) {
+		e, a := expected.Hounds[i], actual.Hounds[i]
+		if !e.Health.Eq(a.Health) {
+			diffs = append(diffs, fmt.Sprintf("hound %d health: %d != %d",
+				i, e.Health.ToInt(), a.Health.ToInt()))
+		}
+		if !e.Pos.Eq(a.Pos) {
+			diffs = append(diffs, fmt.Sprintf("hound %d pos: %s != %s",
+				i, ptStr(e.Pos), ptStr(a.Pos)))
+		}
+	}
+	var expectedObstacles, actualObstacles MatBool
+	expectedObstacles.FromSlice(expected.Obstacles)
+	actualObstacles.FromSlice(actual.Obstacles)
+	var pt Pt
+	for pt.Y = ZERO; pt.Y.Lt(I(NRows)); pt.Y.Inc() {
+		for pt.X = ZERO; pt.X.Lt(I(NCols)); pt.X.Inc() {
+			e, a := expectedObstacles.At(pt), actualObstacles.At(pt)
+			if e && !a {
+				diffs = append(diffs, "obstacle missing at "+ptStr(pt))
+			} else if !e && a {
+				diffs = append(diffs, "extra obstacle at "+ptStr(pt))
+			}
+		}
+	}
+	expectedTerrain := terrainByPos(expected.Terrain)
+	actualTerrain := terrainByPos(actual.Terrain)
+	for pt.Y = ZERO; pt.Y.Lt(I(NRows)); pt.Y.Inc() {
+		for pt.X = ZERO; pt.X.Lt(I(NCols)); pt.X.Inc() {
+			e, a := expectedTerrain[pt], actualTerrain[pt]
+			if e.Type != a.Type {
+				diffs = append(diffs, fmt.Sprintf("terrain at %s: %s != %s",
+					ptStr(pt), terrainTypeChar[e.Type],
+					terrainTypeChar[a.Type]))
+			} else if !e.CrateHealth.Eq(a.CrateHealth) {
+				diffs = append(diffs, fmt.Sprintf("crate health at %s: "+
+					"%d != %d", ptStr(pt), e.CrateHealth.ToInt(),
+					a.CrateHealth.ToInt()))
+			}
+		}
+	}
+	return
+}
+
+// terrainByPos returns the terrain of each tile, tiles that are missing are
+// Ground.
+func terrainByPos(terrain []TerrainFrameState) map[Pt]TerrainFrameState {
+	m := map[Pt]TerrainFrameState{}
+	for _, t := range terrain {
+		m[t.Pos] = t
+	}
+	return m
+}
+
+// Divergence is the first frame where two traces of the same playthrough
+// differ.
+type Divergence struct {
+	// Index in the traces, see StateTrace for what it means.
+	Frame int
+	// What is different. If one trace ends before the other, Diffs says so.
+	Diffs []string
+}
+
+// FindDivergence compares two traces of the same playthrough and returns the
+// first frame where they differ. It returns false if they are the same.
+func FindDivergence(expected, actual StateTrace) (d Divergence, found bool) {
+	for i := range min(len(expected), len(actual)) {
+		if bytes.Equal(expected[i], actual[i]) {
+			continue
+		}
+		d.Frame = i
+		d.Diffs = DiffStates(DecodeState(expected[i]), DecodeState(actual[i]))
+		if len(d.Diffs) == 0 {
+			// Should not happen, unless State() got new fields that
+			// FrameState doesn't know about yet.
+			d.Diffs = []string{"states differ in a field FrameState doesn't have"}
+		}
+		return d, true
+	}
+	if len(expected) != len(actual) {
+		d.Frame = min(len(expected), len(actual))
+		d.Diffs = []string{fmt.Sprintf("number of frames: %d != %d",
+			len(expected), len(actual))}
+		return d, true
+	}
+	return d, false
+}
@@ -0,0 +1,95 @@
+package world
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStateTrace(t *testing.T) {
+	p := DeserializePlaythrough(ReadFile("playthroughs/average-playthrough.mln999-999"))
+	trace := ComputeStateTrace(&p)
+	assert.Equal(t, len(p.History)+1, len(trace))
+	assert.Equal(t, trace, DeserializeStateTrace(trace.Serialize()))
+
+	w := NewWorldFromPlaythrough(p)
+	for i := range p.History {
+		p.StepFrame(&w, i)
+		s := DecodeState(trace[i+1])
+		assert.Equal(t, w.StateStr(), s.String())
+		assert.Equal(t, int(w.Obstacles.ToArray().N), len(s.Obstacles))
+	}
+}
+
+func TestFindDivergence(t *testing.T) {
+	p := DeserializePlaythrough(ReadFile("playthroughs/average-playthrough.mln999-999"))
+	expected := ComputeStateTrace(&p)
+	_, found := FindDivergence(expected, ComputeStateTrace(&p))
+	assert.False(t, found)
+
+	// Change the player and a hound at some frame.
+	frame := len(p.History) / 2
+	w := p.ReplayUpTo(frame)
+	assert.True(t, w.Enemies.N > 0)
+	w.Player.Health.Inc()
+	w.Enemies.V[0].pos = w.Enemies.V[0].pos.Plus(IPt(1, 0))
+	actual := ComputeStateTrace(&p)
+	actual[frame] = w.State()
+
+	d, found := FindDivergence(expected, actual)
+	assert.True(t, found)
+	assert.Equal(t, frame, d.Frame)
+	s := DecodeState(expected[frame])
+	h := s.Hounds[0].Pos
+	assert.Contains(t, d.Diffs, fmt.Sprintf("player health: %d != %d",
+		s.PlayerHealth.ToInt(), s.PlayerHealth.ToInt()+1))
+	assert.Contains(t, d.Diffs, "hound 0 pos: "+ptStr(h)+" != "+
+		ptStr(h.Plus(IPt(1, 0))))
+
+	// A trace that ends early.
+	d, found = FindDivergence(expected, expected[:10])
+	assert.True(t, found)
+	assert.Equal(t, 10, d.Frame)
+	assert.Equal(t, 1, len(d.Diffs))
+}
+
+func TestDiffStates(t *testing.T) {
+	e := FrameState{Obstacles: []Pt{IPt(0, 0), IPt(1, 0)}}
+	a := FrameState{Obstacles: []Pt{IPt(1, 0), IPt(2, 5)},
+		Hounds: []HoundFrameState{{}}}
+	assert.Equal(t, []string{
+		"number of hounds: 0 != 1",
+		"obstacle missing at (0, 0)",
+		"extra obstacle at (2, 5)",
+	}, DiffStates(e, a))
+	assert.Empty(t, DiffStates(e, e))
+}
+
+func TestDecodeState_Terrain(t *testing.T) {
+	var l Level
+	l.Obstacles.Set(IPt(0, 0))
+	l.CrateMaxHealth = I(2)
+	l.Terrain.Set(IPt(3, 3), Crate)
+	l.Terrain.Set(IPt(5, 1), Rock)
+	w := NewWorld(I(0), l)
+	s := DecodeState(w.State())
+	assert.Equal(t, []Pt{IPt(0, 0)}, s.Obstacles)
+	assert.Equal(t, []TerrainFrameState{{IPt(5, 1), Rock, ZERO},
+		{IPt(3, 3), Crate, I(2)}}, s.Terrain)
+
+	w.HitCrate(IPt(3, 3))
+	assert.Equal(t, []string{"crate health at (3, 3): 2 != 1"},
+		DiffStates(s, DecodeState(w.State())))
+	w.HitCrate(IPt(3, 3))
+	assert.Equal(t, []string{"terrain at (3, 3): C != ."},
+		DiffStates(s, DecodeState(w.State())))
+
+	// Without terrain the State is what it was before terrain existed.
+	l.Terrain = Terrain{}
+	w = NewWorld(I(0), l)
+	s = DecodeState(w.State())
+	assert.Empty(t, s.Terrain)
+	// Player health and pos, number of hounds, one obstacle.
+	assert.Equal(t, 8+16+8+16, len(w.State()))
+}
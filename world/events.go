@@ -0,0 +1,338 @@
+package world
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	"strings"
+)
+
+// TriggerType decides when a LevelEvent fires.
+// It is an int64 and not an int because it is part of the Level, which gets
+// serialized using encoding/binary, which only accepts fixed-size values.
+type TriggerType int64
+
+const (
+	// AtStart fires when the level starts.
+	AtStart TriggerType = iota
+	// PlayerEntersMap fires when the player first appears on the map.
+	PlayerEntersMap
+	// HoundsKilled fires when LevelEvent.Count hounds are dead.
+	HoundsKilled
+	// PortalDestroyed fires when the spawn portal at LevelEvent.TriggerPos is
+	// destroyed, see SpawnPortalParams.Health.
+	PortalDestroyed
+)
+
+var triggerTypeName = map[TriggerType]string{
+	AtStart:         "AtStart",
+	PlayerEntersMap: "PlayerEntersMap",
+	HoundsKilled:    "HoundsKilled",
+	PortalDestroyed: "PortalDestroyed",
+}
+
+// EventAction is what a LevelEvent does once it fires.
+type EventAction int64
+
+const (
+	// SpawnWave sends LevelEvent.NHounds hounds out of the spawn portal at
+	// LevelEvent.Portal.
+	SpawnWave EventAction = iota
+	// ActivatePortal lets the spawn portal at LevelEvent.Portal spawn its
+	// waves.
+	ActivatePortal
+	// DeactivatePortal stops the spawn portal at LevelEvent.Portal from
+	// spawning its waves.
+	DeactivatePortal
+)
+
+var eventActionName = map[EventAction]string{
+	SpawnWave:        "SpawnWave",
+	ActivatePortal:   "ActivatePortal",
+	DeactivatePortal: "DeactivatePortal",
+}
+
+// EnemyType selects the kind of hound a SpawnWave event sends.
+type EnemyType int64
+
+const (
+	// RegularHound behaves as described by the WorldParams.
+	RegularHound EnemyType = iota
+	// BlockerHound chases the player like a regular hound, but never hits
+	// him. It only gets in the way.
+	BlockerHound
+)
+
+var enemyTypeName = map[EnemyType]string{
+	RegularHound: "Hound",
+	BlockerHound: "Blocker",
+}
+
+// LevelEvent is one entry of the level's event timeline. The action happens
+// Seconds seconds after the trigger fires. Each event fires at most once.
+type LevelEvent struct {
+	Trigger    TriggerType `yaml:"Trigger"`
+	Count      Int         `yaml:"Count"`      // for HoundsKilled
+	TriggerPos Pt          `yaml:"TriggerPos"` // for PortalDestroyed
+	Seconds    Int         `yaml:"Seconds"`
+	Action     EventAction `yaml:"Action"`
+	Portal     Pt          `yaml:"Portal"`
+	// The rest only matter for SpawnWave.
+	NHounds Int `yaml:"NHounds"`
+	// Burst spawns all hounds at once, instead of one every time the portal's
+	// cooldown is ready.
+	Burst     bool      `yaml:"Burst"`
+	EnemyType EnemyType `yaml:"EnemyType"`
+	// Modifiers for the hounds in this wave. Zero values keep what the
+	// WorldParams of the portal say. HoundMaxHealth replaces the health of
+	// the portal's hounds, the cooldown multipliers multiply theirs, e.g. a
+	// HoundMoveCooldownMultiplier of 2 makes the hounds twice as slow.
+	HoundMaxHealth                Int `yaml:"HoundMaxHealth"`
+	HoundMoveCooldownMultiplier   Int `yaml:"HoundMoveCooldownMultiplier"`
+	HoundAttackCooldownMultiplier Int `yaml:"HoundAttackCooldownMultiplier"`
+}
+
+// Event holds the progress of a LevelEvent while the World runs.
+type Event struct {
+	LevelEvent
+	Triggered    bool
+	TriggerFrame Int
+	Done         bool
+}
+
+// PendingSpawn is a group of hounds that a spawn portal must send out
+// because of a SpawnWave event.
+type PendingSpawn struct {
+	NHounds     Int
+	WorldParams WorldParams
+}
+
+// houndParams applies the wave modifiers of the event to the portal's params.
+func (e *LevelEvent) houndParams(w WorldParams) WorldParams {
+	if e.HoundMaxHealth.IsPositive() {
+		w.HoundMaxHealth = e.HoundMaxHealth
+	}
+	if e.HoundMoveCooldownMultiplier.IsPositive() {
+		w.HoundMoveCooldownMultiplier = w.HoundMoveCooldownMultiplier.Times(
+			e.HoundMoveCooldownMultiplier)
+	}
+	if e.HoundAttackCooldownMultiplier.IsPositive() {
+		w.HoundAttackCooldownMultiplier = w.HoundAttackCooldownMultiplier.Times(
+			e.HoundAttackCooldownMultiplier)
+	}
+	if e.EnemyType == BlockerHound {
+		w.HoundHitsPlayer = false
+	}
+	return w
+}
+
+func (w *World) spawnPortalAt(pos Pt) *SpawnPortal {
+	if i := w.spawnPortalIdx(pos); i >= 0 {
+		return &w.SpawnPortals.V[i]
+	}
+	return nil
+}
+
+// spawnPortalIdx returns the index of the spawn portal at pos, or -1.
+func (w *World) spawnPortalIdx(pos Pt) int64 {
+	for i := range w.SpawnPortals.N {
+		if w.SpawnPortals.V[i].Pos() == pos {
+			return i
+		}
+	}
+	return -1
+}
+
+func (w *World) eventTriggered(e *Event) bool {
+	switch e.Trigger {
+	case AtStart:
+		return true
+	case PlayerEntersMap:
+		return w.Player.EnteredMap
+	case HoundsKilled:
+		return w.NHoundsKilled.Geq(e.Count)
+	case PortalDestroyed:
+		return w.DestroyedPortals.At(e.TriggerPos)
+	}
+	return false
+}
+
+// stepEvents fires the events whose triggers happened and whose delay
+// passed. It only depends on the World, so replays stay deterministic.
+func (w *World) stepEvents() {
+	for i := range w.Events.N {
+		e := &w.Events.V[i]
+		if e.Done {
+			continue
+		}
+		if !e.Triggered {
+			if !w.eventTriggered(e) {
+				continue
+			}
+			e.Triggered = true
+			e.TriggerFrame = w.TimeStep
+		}
+		if w.TimeStep.Minus(e.TriggerFrame).Lt(e.Seconds.Times(I(framesPerSecond))) {
+			continue
+		}
+
+		e.Done = true
+		portal := w.spawnPortalAt(e.Portal)
+		if portal == nil {
+			// The portal was destroyed, there's nothing left to do.
+			continue
+		}
+		switch e.Action {
+		case SpawnWave:
+			params := e.houndParams(portal.worldParams)
+			w.emit(WaveStarted, portal.pos, e.NHounds)
+			if e.Burst {
+				for range e.NHounds.ToInt64() {
+					portal.spawnHound(w, params)
+				}
+			} else {
+				portal.addPending(e.NHounds, params)
+			}
+		case ActivatePortal:
+			portal.Inactive = false
+		case DeactivatePortal:
+			portal.Inactive = true
+		}
+	}
+}
+
+// possibleEvents marks the events that didn't fire yet but still can, see
+// canTrigger. The events that are possible can make others possible, by
+// spawning hounds or by activating portals, so this goes on until nothing
+// changes.
+func (w *World) possibleEvents() (possible [len(EventsArray{}.V)]bool) {
+	// How many hounds can be killed, in the end.
+	supply := w.NHoundsKilled
+	for i := range w.Enemies.N {
+		if w.Enemies.V[i].Alive() {
+			supply.Inc()
+		}
+	}
+	var activated [len(SpawnPortalsArray{}.V)]bool
+	for i := range w.SpawnPortals.N {
+		p := &w.SpawnPortals.V[i]
+		supply.Add(p.pendingHounds())
+		if !p.Inactive {
+			activated[i] = true
+			supply.Add(p.waveHoundsLeft())
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := range w.Events.N {
+			e := &w.Events.V[i]
+			if e.Done || possible[i] {
+				continue
+			}
+			if !e.Triggered && !w.canTrigger(e, supply) {
+				continue
+			}
+			possible[i] = true
+			changed = true
+			idx := w.spawnPortalIdx(e.Portal)
+			if idx < 0 {
+				continue
+			}
+			switch e.Action {
+			case SpawnWave:
+				supply.Add(Max(e.NHounds, ZERO))
+			case ActivatePortal:
+				if !activated[idx] {
+					activated[idx] = true
+					supply.Add(w.SpawnPortals.V[idx].waveHoundsLeft())
+				}
+			}
+		}
+	}
+	return
+}
+
+// canTrigger returns false if the trigger of e can never fire. An event that
+// waits for more hounds to be killed than the supply that can ever spawn
+// doesn't fire, and neither does one that waits for a portal to be destroyed
+// if there is no such portal that can be destroyed.
+func (w *World) canTrigger(e *Event, supply Int) bool {
+	switch e.Trigger {
+	case HoundsKilled:
+		return supply.Geq(e.Count)
+	case PortalDestroyed:
+		i := w.spawnPortalIdx(e.TriggerPos)
+		return w.DestroyedPortals.At(e.TriggerPos) ||
+			i >= 0 && w.SpawnPortals.V[i].Destructible
+	}
+	return true
+}
+
+// eventsWillSpawn returns true if some event may still spawn hounds, either
+// by itself or by activating a portal that has hounds left in its waves.
+func (w *World) eventsWillSpawn() bool {
+	possible := w.possibleEvents()
+	for i := range w.Events.N {
+		e := &w.Events.V[i]
+		if !possible[i] {
+			continue
+		}
+		idx := w.spawnPortalIdx(e.Portal)
+		if idx < 0 {
+			// The portal is gone, the event does nothing.
+			continue
+		}
+		p := &w.SpawnPortals.V[idx]
+		if e.Action == SpawnWave && e.NHounds.IsPositive() ||
+			e.Action == ActivatePortal && p.Inactive &&
+				p.waveHoundsLeft().IsPositive() {
+			return true
+		}
+	}
+	return false
+}
+
+func (t TriggerType) MarshalYAML() ([]byte, error) {
+	return []byte(triggerTypeName[t]), nil
+}
+
+func (t *TriggerType) UnmarshalYAML(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	for k, v := range triggerTypeName {
+		if v == s {
+			*t = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown trigger type: %s", s)
+}
+
+func (a EventAction) MarshalYAML() ([]byte, error) {
+	return []byte(eventActionName[a]), nil
+}
+
+func (a *EventAction) UnmarshalYAML(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	for k, v := range eventActionName {
+		if v == s {
+			*a = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown event action: %s", s)
+}
+
+func (t EnemyType) MarshalYAML() ([]byte, error) {
+	return []byte(enemyTypeName[t]), nil
+}
+
+func (t *EnemyType) UnmarshalYAML(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	for k, v := range enemyTypeName {
+		if v == s {
+			*t = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown enemy type: %s", s)
+}
@@ -0,0 +1,161 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func Test_LevelEventsYaml(t *testing.T) {
+	var a LevelEventsArray
+	a.N = 2
+	a.V[0] = LevelEvent{Trigger: HoundsKilled, Count: I(3), Action: SpawnWave,
+		Portal: IPt(1, 1), NHounds: I(4), Burst: true, EnemyType: BlockerHound,
+		HoundMaxHealth: I(2)}
+	a.V[1] = LevelEvent{Trigger: PortalDestroyed, TriggerPos: IPt(1, 1),
+		Seconds: I(5), Action: ActivatePortal, Portal: IPt(6, 6)}
+
+	filename := "events.yaml"
+	SaveYAML(filename, a)
+	var a2 LevelEventsArray
+	LoadYAML(os.DirFS(".").(FS), filename, &a2)
+	DeleteFile(filename)
+	assert.Equal(t, a, a2)
+}
+
+func TestWorld_BurstWhenPlayerEntersMap(t *testing.T) {
+	var l Level
+	l.HoundMaxHealth = I(3)
+	l.SpawnPortalsParams.N = 1
+	l.SpawnPortalsParams.V[0].Pos = IPt(6, 6)
+	l.Events.N = 1
+	l.Events.V[0] = LevelEvent{Trigger: PlayerEntersMap, Action: SpawnWave,
+		Portal: IPt(6, 6), NHounds: I(3), Burst: true, HoundMaxHealth: I(5)}
+	w := NewWorld(I(0), l)
+
+	w.Step(PlayerInput{})
+	assert.Equal(t, int64(0), w.Enemies.N)
+	assert.NotEqual(t, Won, w.Status())
+
+	w.Step(PlayerInput{Move: true, MovePt: IPt(1, 1)})
+	assert.Equal(t, int64(3), w.Enemies.N)
+	for i := range w.Enemies.N {
+		assert.Equal(t, I(5), w.Enemies.V[i].Health())
+	}
+}
+
+func TestWorld_PortalDestroyed(t *testing.T) {
+	var l Level
+	l.HoundMaxHealth = I(1)
+	l.SpawnPortalsParams.N = 2
+	l.SpawnPortalsParams.V[0].Pos = IPt(6, 6)
+	l.SpawnPortalsParams.V[0].Health = TWO
+	l.SpawnPortalsParams.V[1].Pos = IPt(0, 6)
+	l.Events.N = 1
+	l.Events.V[0] = LevelEvent{Trigger: PortalDestroyed, TriggerPos: IPt(6, 6),
+		Action: SpawnWave, Portal: IPt(0, 6), NHounds: ONE, Burst: true}
+	w := NewWorld(I(0), l)
+	w.Step(PlayerInput{Move: true, MovePt: IPt(1, 1)})
+	assert.Equal(t, Ongoing, w.Status())
+
+	// The portal can be shot like a hound.
+	shoot := PlayerInput{MousePt: TileAimParams.TileToScreen(IPt(6, 6)),
+		RightButtonPressed: true}
+	shoot = TileAimParams.ResolveClicks(&w, shoot)
+	assert.True(t, shoot.Shoot)
+	w.Step(shoot)
+	assert.Equal(t, int64(2), w.SpawnPortals.N)
+	assert.Equal(t, int64(0), w.Enemies.N)
+	w.Step(shoot)
+	assert.Equal(t, int64(1), w.SpawnPortals.N)
+	assert.True(t, w.StepEvents.Contains(SpawnPortalDestroyed))
+	assert.Equal(t, int64(1), w.Enemies.N)
+	assert.Equal(t, IPt(0, 6), w.Enemies.V[0].Pos())
+
+	// A portal without Health can't be destroyed, so an event waiting for it
+	// never fires.
+	l.SpawnPortalsParams.V[0].Health = ZERO
+	w = NewWorld(I(0), l)
+	assert.False(t, w.HitSpawnPortal(IPt(6, 6)))
+	assert.True(t, w.AllEnemiesDead())
+}
+
+func TestLevelEvent_HoundParams(t *testing.T) {
+	var params WorldParams
+	params.HoundMaxHealth = I(3)
+	params.HoundMoveCooldownMultiplier = TWO
+	params.HoundAttackCooldownMultiplier = TWO
+	e := LevelEvent{HoundMaxHealth: ONE, HoundMoveCooldownMultiplier: I(3)}
+	params = e.houndParams(params)
+	assert.Equal(t, ONE, params.HoundMaxHealth)
+	assert.Equal(t, I(6), params.HoundMoveCooldownMultiplier)
+	assert.Equal(t, TWO, params.HoundAttackCooldownMultiplier)
+}
+
+func TestWorld_DelayedWaveAndDeactivatedPortal(t *testing.T) {
+	var l Level
+	l.HoundMaxHealth = I(1)
+	l.SpawnPortalsParams.N = 2
+	l.SpawnPortalsParams.V[0].Pos = IPt(6, 6)
+	l.SpawnPortalsParams.V[1].Pos = IPt(0, 6)
+	l.SpawnPortalsParams.V[1].Waves.N = 1
+	l.SpawnPortalsParams.V[1].Waves.V[0].NHounds = I(5)
+	l.Events.N = 2
+	l.Events.V[0] = LevelEvent{Trigger: AtStart, Seconds: I(1),
+		Action: SpawnWave, Portal: IPt(6, 6), NHounds: I(2)}
+	l.Events.V[1] = LevelEvent{Trigger: AtStart, Action: DeactivatePortal,
+		Portal: IPt(0, 6)}
+	w := NewWorld(I(0), l)
+
+	for range 60 {
+		w.Step(PlayerInput{})
+	}
+	assert.Equal(t, int64(0), w.Enemies.N)
+	w.Step(PlayerInput{})
+	assert.Equal(t, int64(1), w.Enemies.N)
+	w.Step(PlayerInput{})
+	assert.Equal(t, int64(2), w.Enemies.N)
+	assert.Equal(t, IPt(6, 6), w.Enemies.V[0].Pos())
+	for range 10 {
+		w.Step(PlayerInput{})
+	}
+	assert.Equal(t, int64(2), w.Enemies.N)
+}
+
+func TestWorld_ImpossibleEventsDontKeepTheLevelGoing(t *testing.T) {
+	var l Level
+	l.SpawnPortalsParams.N = 2
+	l.SpawnPortalsParams.V[0].Pos = IPt(6, 6)
+	l.SpawnPortalsParams.V[1].Pos = IPt(0, 6)
+	l.SpawnPortalsParams.V[1].Inactive = true
+	l.SpawnPortalsParams.V[1].Waves.N = 1
+	l.SpawnPortalsParams.V[1].Waves.V[0].NHounds = I(2)
+	l.Events.N = 3
+	l.Events.V[0] = LevelEvent{Trigger: AtStart, Action: SpawnWave,
+		Portal: IPt(6, 6), NHounds: I(1)}
+	// Only 1 hound can ever be killed, so this never fires.
+	l.Events.V[1] = LevelEvent{Trigger: HoundsKilled, Count: I(2),
+		Action: SpawnWave, Portal: IPt(6, 6), NHounds: I(1)}
+	l.Events.V[2] = LevelEvent{Trigger: HoundsKilled, Count: I(3),
+		Action: ActivatePortal, Portal: IPt(0, 6)}
+	w := NewWorld(I(0), l)
+	assert.False(t, w.AllEnemiesDead())
+
+	w.Events.V[0].Done = true
+	w.NHoundsKilled = ONE
+	assert.True(t, w.AllEnemiesDead())
+
+	// Another event makes them possible, one after the other.
+	w.Events.N = 4
+	w.Events.V[3].LevelEvent = LevelEvent{Trigger: HoundsKilled, Count: ONE,
+		Action: SpawnWave, Portal: IPt(6, 6), NHounds: ONE}
+	assert.False(t, w.AllEnemiesDead())
+	w.Events.V[3].Done = true
+	w.Events.V[1].Done = true
+	w.NHoundsKilled = I(3)
+	// The portal it activates still has hounds to send.
+	assert.False(t, w.AllEnemiesDead())
+	w.Events.V[2].Done = true
+	assert.True(t, w.AllEnemiesDead())
+}
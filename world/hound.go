@@ -41,6 +41,8 @@ type Hound struct {
 	hitsPlayer                   bool
 	aggroDistance                Int
 	randomTarget                 Pt
+	marked                       bool  // the player must kill this hound
+	targetIdx                    int64 // index of the KillTarget objective
 }
 
 func NewHound(seed Int, w WorldParams, pos Pt) Hound {
@@ -98,12 +100,14 @@ func (h *Hound) searching(justEnteredState bool, w *World) {
 	// towards.
 	if justEnteredState {
 		h.moveCooldownIdx = h.moveCooldownMultiplier
-		h.randomTarget = w.Obstacles.RandomUnoccupiedPos(&h.Rand)
+		h.randomTarget = w.SolidTiles.RandomUnoccupiedPos(&h.Rand)
 	}
 
 	// React to being hit.
 	if h.beamJustHit(w) {
-		h.health.Dec()
+		damage := Min(w.Player.BeamDamage(), h.health)
+		h.health.Subtract(damage)
+		w.emit(EnemyHit, h.pos, damage)
 		if h.health.IsZero() {
 			h.state = Dead
 			return
@@ -140,7 +144,9 @@ func (h *Hound) preparingToAttack(justEnteredState bool, w *World) {
 
 	// React to being hit.
 	if h.beamJustHit(w) {
-		h.health.Dec()
+		damage := Min(w.Player.BeamDamage(), h.health)
+		h.health.Subtract(damage)
+		w.emit(EnemyHit, h.pos, damage)
 		if h.health.IsZero() {
 			h.state = Dead
 			return
@@ -181,7 +187,9 @@ func (h *Hound) attacking(justEnteredState bool, w *World) {
 
 	// React to being hit.
 	if h.beamJustHit(w) {
-		h.health.Dec()
+		damage := Min(w.Player.BeamDamage(), h.health)
+		h.health.Subtract(damage)
+		w.emit(EnemyHit, h.pos, damage)
 		if h.health.IsZero() {
 			h.state = Dead
 			return
@@ -247,16 +255,29 @@ func (h *Hound) MoveCooldownIdx() Int {
 	return h.moveCooldownIdx
 }
 
+func (h *Hound) PreparingToAttackCooldownIdx() Int {
+	return h.preparingToAttackCooldownIdx
+}
+
+func (h *Hound) AttackCooldownIdx() Int {
+	return h.attackCooldownIdx
+}
+
+func (h *Hound) HitCooldownIdx() Int {
+	return h.hitCooldownIdx
+}
+
 func (h *Hound) State() string { return enemyStateName[h.state] }
 
 func (h *Hound) goToPlayer(w *World, m MatBool) {
-	path := ComputePath(h.pos, w.Player.Pos(), m)
+	path := w.ComputePath(h.pos, w.Player.Pos(), m)
 	if path.N > 1 {
 		if h.hitsPlayer {
 			// Move to the position either way and hit player if necessary.
 			h.pos = path.V[1]
 			if path.V[1].Eq(w.Player.Pos()) {
 				w.Player.Hit()
+				w.emit(PlayerHit, h.pos, ONE)
 			}
 		} else {
 			// Move to the position only if not occupied by the player.
@@ -271,7 +292,7 @@ func (h *Hound) moveRandomly(w *World) {
 	m := getObstaclesAndEnemies(w)
 	// Try to move a few times before giving up.
 	for i := 0; i < 10; i++ {
-		path := ComputePath(h.pos, h.randomTarget, m)
+		path := w.ComputePath(h.pos, h.randomTarget, m)
 
 		if path.N > 1 {
 			// Can go towards the current random target.
@@ -298,12 +319,16 @@ func (h *Hound) MaxHealth() Int {
 	return h.maxHealth
 }
 
+func (h *Hound) Marked() bool {
+	return h.marked
+}
+
 func (h *Hound) Alive() bool {
 	return h.health.IsPositive()
 }
 
 func getObstaclesAndEnemies(w *World) (m MatBool) {
-	m = w.Obstacles
+	m = w.SolidTiles
 	m.Add(w.EnemyPositions())
 	return
 }
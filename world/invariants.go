@@ -0,0 +1,99 @@
+package world
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+)
+
+// The invariants are the things that must be true about a World no matter
+// what the player does. They are checked by the fuzz tests after every step
+// and by World.Step itself in builds with the world_debug_info_enabled tag.
+
+// checkArray returns an error if an array of the World has a size that
+// doesn't fit its capacity.
+func checkArray(name string, n int64, capacity int) error {
+	if n < 0 || n > int64(capacity) {
+		return fmt.Errorf("%s.N is %d, capacity is %d", name, n, capacity)
+	}
+	return nil
+}
+
+// CheckInvariants returns an error if the World is in a state it should never
+// be in.
+func CheckInvariants(w *World) error {
+	arrays := []struct {
+		name     string
+		n        int64
+		capacity int
+	}{
+		{"Enemies", w.Enemies.N, len(w.Enemies.V)},
+		{"Ammos", w.Ammos.N, len(w.Ammos.V)},
+		{"SpawnPortals", w.SpawnPortals.N, len(w.SpawnPortals.V)},
+		{"Pickups", w.Pickups.N, len(w.Pickups.V)},
+	}
+	for _, a := range arrays {
+		if err := checkArray(a.name, a.n, a.capacity); err != nil {
+			return err
+		}
+	}
+
+	// Hounds are allowed to be on the same tile only where they spawn. A
+	// portal can send several hounds at once and they leave it one by one.
+	portals := w.SpawnPortalPositions()
+	portals.Add(w.DestroyedPortals)
+	var hounds MatBool
+	for i := range w.Enemies.N {
+		pos := w.Enemies.V[i].Pos()
+		if w.SolidTiles.At(pos) {
+			return fmt.Errorf("hound %d is on an obstacle at %s", i, ptStr(pos))
+		}
+		if hounds.At(pos) && !portals.At(pos) {
+			return fmt.Errorf("hound %d is on the same tile as another hound "+
+				"at %s", i, ptStr(pos))
+		}
+		hounds.Set(pos)
+	}
+
+	if w.Player.OnMap && w.SolidTiles.At(w.Player.Pos()) {
+		return fmt.Errorf("player is on an obstacle at %s",
+			ptStr(w.Player.Pos()))
+	}
+
+	if w.Player.AmmoCount.IsNegative() ||
+		w.Player.AmmoCount.Gt(w.Player.AmmoLimit) {
+		return fmt.Errorf("player has %d ammo, limit is %d",
+			w.Player.AmmoCount.ToInt64(), w.Player.AmmoLimit.ToInt64())
+	}
+	return nil
+}
+
+// checkHealth returns an error if the player gained health during a step
+// without collecting a health pickup. pickupsBefore are the pickups that were
+// on the map before the step.
+func checkHealth(healthBefore Int, pickupsBefore *PickupsArray, w *World) error {
+	if w.Player.Health.Gt(w.Player.MaxHealth) {
+		return fmt.Errorf("player has %d health, max is %d",
+			w.Player.Health.ToInt64(), w.Player.MaxHealth.ToInt64())
+	}
+	if w.Player.Health.Leq(healthBefore) {
+		return nil
+	}
+	for i := range pickupsBefore.N {
+		p := &pickupsBefore.V[i]
+		if p.Type == HealthPickup && p.Pos() == w.Player.Pos() {
+			return nil
+		}
+	}
+	return fmt.Errorf("player health went from %d to %d without a pickup",
+		healthBefore.ToInt64(), w.Player.Health.ToInt64())
+}
+
+// CheckStepInvariants returns an error if going from the World before to the
+// World after a single World.Step broke a rule, or if after is in a state it
+// should never be in.
+func CheckStepInvariants(before *World, after *World) error {
+	if err := CheckInvariants(after); err != nil {
+		return err
+	}
+	return checkHealth(before.Player.Health, &before.Pickups, after)
+}
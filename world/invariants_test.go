@@ -0,0 +1,146 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestCheckInvariants(t *testing.T) {
+	var l Level
+	l.Obstacles.Set(IPt(2, 2))
+	l.SpawnPortalsParams.N = 1
+	l.SpawnPortalsParams.V[0].Pos = IPt(6, 6)
+	w := NewWorld(I(0), l)
+	assert.Nil(t, CheckInvariants(&w))
+
+	// Hounds may share the tile of a portal, but not any other tile.
+	w.Enemies.N = 2
+	w.Enemies.V[0].pos = IPt(6, 6)
+	w.Enemies.V[1].pos = IPt(6, 6)
+	assert.Nil(t, CheckInvariants(&w))
+	w.Enemies.V[0].pos = IPt(5, 5)
+	w.Enemies.V[1].pos = IPt(5, 5)
+	assert.NotNil(t, CheckInvariants(&w))
+	w.Enemies.V[1].pos = IPt(2, 2)
+	assert.NotNil(t, CheckInvariants(&w))
+	w.Enemies.N = 1
+
+	w.Player.OnMap = true
+	w.Player.pos = IPt(2, 2)
+	assert.NotNil(t, CheckInvariants(&w))
+	w.Player.pos = IPt(1, 1)
+	assert.Nil(t, CheckInvariants(&w))
+
+	w.Player.AmmoCount = w.Player.AmmoLimit.Plus(ONE)
+	assert.NotNil(t, CheckInvariants(&w))
+	w.Player.AmmoCount = ZERO
+
+	w.Ammos.N = int64(len(w.Ammos.V)) + 1
+	assert.NotNil(t, CheckInvariants(&w))
+	w.Ammos.N = 0
+
+	// Health only goes up by collecting a health pickup.
+	before := w
+	before.Player.Health = I(1)
+	assert.NotNil(t, CheckStepInvariants(&before, &w))
+	before.Pickups.N = 1
+	before.Pickups.V[0] = Pickup{pos: IPt(1, 1), Type: HealthPickup}
+	assert.Nil(t, CheckStepInvariants(&before, &w))
+}
+
+// fuzzPlaythrough generates a level from levelSeed and changes the rules of
+// the level according to the bits in flags, so that the fuzzer gets to all
+// the variations of the simulation.
+func fuzzPlaythrough(levelSeed int64, seed int64, flags uint8) (p Playthrough) {
+	RSeed(I64(levelSeed))
+	p.Level = GenerateLevel(os.DirFS("..").(FS))
+	p.TurnBased = flags&1 != 0
+	p.Boardgame = flags&2 != 0
+	p.UseAmmo = flags&4 == 0
+	p.HoundHitsPlayer = flags&8 == 0
+	if flags&16 != 0 {
+		p.MovementMode = Walk
+	}
+	if flags&32 != 0 {
+		p.ForceReentry = true
+		w := NewWorld(ZERO, p.Level)
+		p.ReentryPos = w.SolidTiles.RandomUnoccupiedPos(&DefaultRand)
+	}
+	if flags&64 != 0 {
+		p.PickupsParams.N = 2
+		p.PickupsParams.V[0] = PickupParams{Type: HealthPickup,
+			SpawnRule: SpawnTimed, Amount: ONE, SpawnCooldown: I(100)}
+		p.PickupsParams.V[1] = PickupParams{Type: DoubleDamagePickup,
+			SpawnRule: SpawnHoundDrop, Duration: I(200), DropChance: I(50)}
+	}
+	p.InputVersion = I(InputVersion)
+	p.SimulationVersion = I(SimulationVersion)
+	p.Seed = I64(seed)
+	p.ChecksumInterval = I(DefaultChecksumInterval)
+	return
+}
+
+// fuzzInputs turns every pair of bytes into an input. The first byte says
+// what the input does and the second one where on the map it does it. Idle
+// inputs are repeated, so that the enemies get to act between the actions of
+// the player.
+func fuzzInputs(data []byte) (inputs []PlayerInput) {
+	for i := 0; i+1 < len(data); i += 2 {
+		pt := IPt(int(data[i+1])%NCols, int(data[i+1])/NCols%NRows)
+		switch data[i] % 4 {
+		case 0:
+			for range int(data[i]/4) + 1 {
+				inputs = append(inputs, PlayerInput{})
+			}
+		case 1:
+			inputs = append(inputs, PlayerInput{Move: true, MovePt: pt})
+		case 2:
+			inputs = append(inputs, PlayerInput{Shoot: true, ShootPt: pt})
+		case 3:
+			inputs = append(inputs, PlayerInput{Undo: true})
+		}
+	}
+	return
+}
+
+func FuzzWorld_Step(f *testing.F) {
+	f.Add(int64(0), int64(0), uint8(0), []byte{})
+	f.Add(int64(1), int64(2), uint8(8), []byte{
+		1, 9, 252, 0, 2, 36, 1, 18, 252, 0, 2, 45, 2, 27, 252, 0, 1, 63})
+	f.Add(int64(3), int64(4), uint8(1|64), []byte{
+		1, 0, 2, 12, 3, 0, 1, 7, 2, 21, 2, 21, 1, 56, 3, 0, 2, 30})
+	f.Add(int64(5), int64(6), uint8(16|32|64), []byte{
+		1, 0, 1, 9, 1, 18, 252, 0, 2, 27, 1, 27, 252, 0, 252, 0, 2, 36})
+
+	f.Fuzz(func(t *testing.T, levelSeed int64, seed int64, flags uint8,
+		data []byte) {
+		p := fuzzPlaythrough(levelSeed, seed, flags)
+		w := NewWorldFromPlaythrough(p)
+		if err := CheckInvariants(&w); err != nil {
+			t.Fatalf("after creating the world: %v", err)
+		}
+		for i, input := range fuzzInputs(data) {
+			before := w
+			Step(&p, &w, input)
+			// An undo rebuilds the World from the start, so it can undo
+			// damage.
+			var err error
+			if input.Undo {
+				err = CheckInvariants(&w)
+			} else {
+				err = CheckStepInvariants(&before, &w)
+			}
+			if err != nil {
+				t.Fatalf("frame %d, input %+v: %v", i, input, err)
+			}
+		}
+
+		id := RegressionId(&p)
+		assert.Equal(t, id, RegressionId(p.Clone()))
+		p2 := DeserializePlaythrough(p.Serialize())
+		assert.Equal(t, id, RegressionId(&p2))
+		assert.Equal(t, 0, p2.FindDesync())
+	})
+}
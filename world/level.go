@@ -1,7 +1,9 @@
 package world
 
 import (
+	"bytes"
 	"fmt"
+	"github.com/goccy/go-yaml"
 	. "github.com/marisvali/miln/gamelib"
 )
 
@@ -13,7 +15,7 @@ import (
 // However, I also want to copy/clone the Playthrough, which implies the Level.
 // And if Level has slices I have to write clone logic for that. That's annoying
 // for me. It's actually easier to write MarshalYAML and UnmarshalYAML for the
-// 2 arrays that need it. I prefer to have special code for serializing to YAML,
+// arrays that need it. I prefer to have special code for serializing to YAML,
 // and have a simpler version of a basic operation like copy/clone. This way I
 // can also easily include the Level in WorldDebugInfo if I want, and trust
 // that it gets copied quickly and correctly.
@@ -25,13 +27,23 @@ import (
 type Level struct {
 	WorldParams        `yaml:"WorldParams"`
 	Obstacles          MatBool                `yaml:"Obstacles"`
+	Terrain            Terrain                `yaml:"Terrain"`
 	SpawnPortalsParams SpawnPortalParamsArray `yaml:"SpawnPortalsParams"`
+	PickupsParams      PickupParamsArray      `yaml:"PickupsParams"`
+	AmmoParams         AmmoParams             `yaml:"AmmoParams"`
+	Objectives         ObjectiveParamsArray   `yaml:"Objectives"`
+	Events             LevelEventsArray       `yaml:"Events"`
 }
 
 type SpawnPortalParams struct {
 	Pos                 Pt         `yaml:"Pos"`
 	SpawnPortalCooldown Int        `yaml:"SpawnPortalCooldown"`
 	Waves               WavesArray `yaml:"Waves"`
+	// Inactive portals only spawn their waves after an ActivatePortal event.
+	Inactive bool `yaml:"Inactive"`
+	// Health is how many beam hits destroy the portal. A portal without
+	// Health can't be destroyed.
+	Health Int `yaml:"Health"`
 }
 
 type LevelYaml struct {
@@ -50,6 +62,14 @@ type VersionYaml struct {
 	InputVersion Int `yaml:"InputVersion"`
 }
 
+// Hash identifies the level. Two playthroughs of the same level have the
+// same hash, regardless of the seed or the player's input.
+func (l *Level) Hash() string {
+	buf := new(bytes.Buffer)
+	Serialize(buf, *l)
+	return HashBytes(buf.Bytes())
+}
+
 func (l *Level) SaveToYAML(seed Int, filename string) {
 	var lYaml LevelYaml
 	lYaml.InputVersion = I(InputVersion)
@@ -59,24 +79,104 @@ func (l *Level) SaveToYAML(seed Int, filename string) {
 }
 
 func LoadLevelFromYAML(fsys FS, filename string) (seed Int, l Level) {
+	seed, l, err := TryLoadLevelFromYAML(fsys, filename)
+	Check(err)
+	return
+}
+
+// TryLoadLevelFromYAML returns a MissingFileError or the errors of
+// TryLevelFromYAML.
+func TryLoadLevelFromYAML(fsys FS, filename string) (seed Int, l Level,
+	err error) {
+	data, err := TryReadFileFS(fsys, filename)
+	if err != nil {
+		return
+	}
+	return TryLevelFromYAML(data)
+}
+
+// LevelFromYAML is LoadLevelFromYAML for a level that is already in memory.
+func LevelFromYAML(data []byte) (seed Int, l Level) {
+	seed, l, err := TryLevelFromYAML(data)
+	Check(err)
+	return
+}
+
+// TryLevelFromYAML returns a CorruptDataError for invalid YAML and a
+// VersionError for another InputVersion. It doesn't check that the level
+// makes sense, see Validate. The one exception is a ReentryPos on an obstacle,
+// which is also a CorruptDataError, because a missing ReentryPos quietly
+// becomes (0, 0).
+// The version is checked before the whole level is loaded. If the version
+// doesn't match, it is very likely that loading would go on but it would
+// load the info wrong, silently, because that's how .yaml loading works. If
+// you feel confident that the level matches the exe that's trying to load
+// it, change the InputVersion manually in the .yaml file. This can happen if
+// the InputVersion changed but the Level stayed the same (currently this means
+// that the PlayerInput structure changed but not the Level structure).
+func TryLevelFromYAML(data []byte) (seed Int, l Level, err error) {
 	var vYaml VersionYaml
-	LoadYAML(fsys, filename, &vYaml)
+	if err = yaml.Unmarshal(data, &vYaml); err != nil {
+		err = &CorruptDataError{err}
+		return
+	}
 	if vYaml.InputVersion.ToInt64() != InputVersion {
-		Check(fmt.Errorf("can't load this level as it was generated using "+
-			"InputVersion %d and we are at InputVersion %d - it is very "+
-			"likely that if we continued deserialization the info would be "+
-			"loaded wrong, but silently, because that's how .yaml loading "+
-			"works; if you feel confident that the level matches the exe "+
-			"that's trying to load it, change the InputVersion manually in"+
-			" the .yaml file; this can happen if the InputVersion changed but "+
-			"the Level stayed the same (currently this means that the "+
-			"PlayerInput structure changed but not the Level structure)",
-			vYaml.InputVersion.ToInt64(), InputVersion))
+		err = &VersionError{"InputVersion", vYaml.InputVersion.ToInt64(),
+			InputVersion}
+		return
 	}
 
 	var lYaml LevelYaml
-	LoadYAML(fsys, filename, &lYaml)
-	return lYaml.Seed, lYaml.Level
+	if err = yaml.Unmarshal(data, &lYaml); err != nil {
+		err = &CorruptDataError{err}
+		return
+	}
+	l = lYaml.Level
+	blocked := blockedTiles(&l)
+	if l.ForceReentry && (!blocked.InBounds(l.ReentryPos) ||
+		blocked.At(l.ReentryPos)) {
+		err = &CorruptDataError{fmt.Errorf("ReentryPos %s is outside the "+
+			"map or on an obstacle", ptStr(l.ReentryPos))}
+		return
+	}
+	return lYaml.Seed, l, nil
+}
+
+// checkCapacity returns an error if one of the arrays of the Level has an N
+// that doesn't fit in the array. A Playthrough read from a corrupt file can
+// have any N, and NewWorld would crash with an index out of range.
+func (l *Level) checkCapacity() error {
+	fits := func(field string, n int64, capacity int) error {
+		if n < 0 || n > int64(capacity) {
+			return fmt.Errorf("%s has %d entries, it can hold between 0 and %d",
+				field, n, capacity)
+		}
+		return nil
+	}
+	if err := fits("SpawnPortalsParams", l.SpawnPortalsParams.N,
+		len(l.SpawnPortalsParams.V)); err != nil {
+		return err
+	}
+	for i := range l.SpawnPortalsParams.N {
+		waves := l.SpawnPortalsParams.V[i].Waves
+		if err := fits(fmt.Sprintf("SpawnPortalsParams[%d].Waves", i),
+			waves.N, len(waves.V)); err != nil {
+			return err
+		}
+	}
+	if err := fits("PickupsParams", l.PickupsParams.N,
+		len(l.PickupsParams.V)); err != nil {
+		return err
+	}
+	if err := fits("AmmoParams.SpawnPoints", l.AmmoParams.SpawnPoints.N,
+		len(l.AmmoParams.SpawnPoints.V)); err != nil {
+		return err
+	}
+	if err := fits("Objectives", l.Objectives.N,
+		len(l.Objectives.V)); err != nil {
+		return err
+	}
+	return fits("Events", l.Events.N, len(l.Events.V))
 }
 
 func IsYamlLevel(filename string) bool {
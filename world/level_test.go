@@ -1,9 +1,11 @@
 package world
 
 import (
+	"fmt"
 	. "github.com/marisvali/miln/gamelib"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -57,3 +59,33 @@ func Test_LevelYaml(t *testing.T) {
 	assert.Equal(t, I(10), seed)
 	assert.Equal(t, l2, l3)
 }
+
+func TestTryLevelFromYAML_ReentryPosOnObstacle(t *testing.T) {
+	var l Level
+	l.ForceReentry = true
+	l.Obstacles.Set(IPt(0, 0))
+	filename := filepath.Join(t.TempDir(), "level.yaml")
+	l.SaveToYAML(I(10), filename)
+	_, _, err := TryLevelFromYAML(ReadFile(filename))
+	var corrupt *CorruptDataError
+	assert.ErrorAs(t, err, &corrupt)
+
+	l.ReentryPos = IPt(1, 0)
+	l.SaveToYAML(I(10), filename)
+	_, l2, err := TryLevelFromYAML(ReadFile(filename))
+	assert.Nil(t, err)
+	assert.Equal(t, l, l2)
+}
+
+func TestTryLevelFromYAML_MalformedMap(t *testing.T) {
+	for _, field := range []string{"Obstacles", "Terrain"} {
+		for _, row := range []string{"- x", "- [., .]"} {
+			data := []byte(fmt.Sprintf("InputVersion: %d\nLevel:\n  %s:\n"+
+				"  %s\n", InputVersion, field, row))
+			_, _, err := TryLevelFromYAML(data)
+			var corrupt *CorruptDataError
+			assert.ErrorAs(t, err, &corrupt, field+" "+row)
+			assert.True(t, HasErrors(ValidateLevelYAML(data)))
+		}
+	}
+}
@@ -15,12 +15,27 @@ type SpawnPortalData struct {
 	Waves []WaveData `yaml:"Waves"`
 }
 
+// AmmoData describes the AmmoParams the generator emits. The zero value emits
+// the default ammo rule.
+type AmmoData struct {
+	Strategy        AmmoSpawnStrategy `yaml:"Strategy"`
+	NSpawnPointsMin Int               `yaml:"NSpawnPointsMin"`
+	NSpawnPointsMax Int               `yaml:"NSpawnPointsMax"`
+	CountMin        Int               `yaml:"CountMin"`
+	CountMax        Int               `yaml:"CountMax"`
+	RespawnDelayMin Int               `yaml:"RespawnDelayMin"`
+	RespawnDelayMax Int               `yaml:"RespawnDelayMax"`
+	Distance        Int               `yaml:"Distance"`
+	Limit           Int               `yaml:"Limit"`
+}
+
 type NEntities struct {
 	NumRows          Int               `yaml:"NumRows"`
 	NumCols          Int               `yaml:"NumCols"`
 	NObstaclesMin    Int               `yaml:"NObstaclesMin"`
 	NObstaclesMax    Int               `yaml:"NObstaclesMax"`
 	SpawnPortalDatas []SpawnPortalData `yaml:"SpawnPortalDatas"`
+	AmmoData         AmmoData          `yaml:"AmmoData"`
 }
 
 type LevelGeneratorParams struct {
@@ -116,10 +131,28 @@ func GenerateLevel(fsys FS) (l Level) {
 		waves.N = int64(len(portal.Waves))
 
 		// Build spawn portal using waves.
-		l.SpawnPortalsParams.V[idx] = SpawnPortalParams{occ.OccupyRandomPos(&DefaultRand),
-			RInt(p.SpawnPortalCooldownMin, p.SpawnPortalCooldownMax), waves}
+		l.SpawnPortalsParams.V[idx] = SpawnPortalParams{
+			Pos:                 occ.OccupyRandomPos(&DefaultRand),
+			SpawnPortalCooldown: RInt(p.SpawnPortalCooldownMin, p.SpawnPortalCooldownMax),
+			Waves:               waves}
 	}
 	l.SpawnPortalsParams.N = int64(len(p.SpawnPortalDatas))
+
+	// Build ammo rules from AmmoData.
+	a := p.AmmoData
+	l.AmmoParams.Strategy = a.Strategy
+	l.AmmoParams.Count = RInt(a.CountMin, a.CountMax)
+	l.AmmoParams.RespawnDelay = RInt(a.RespawnDelayMin, a.RespawnDelayMax)
+	l.AmmoParams.Distance = a.Distance
+	l.AmmoParams.Limit = a.Limit
+	if a.Strategy == AmmoAtSpawnPoints {
+		nSpawnPoints := RInt(a.NSpawnPointsMin, a.NSpawnPointsMax)
+		for i := ZERO; i.Lt(nSpawnPoints); i.Inc() {
+			sp := &l.AmmoParams.SpawnPoints.V[i.ToInt64()]
+			sp.Pos = occ.OccupyRandomPos(&DefaultRand)
+		}
+		l.AmmoParams.SpawnPoints.N = nSpawnPoints.ToInt64()
+	}
 	return
 }
 
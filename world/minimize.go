@@ -0,0 +1,188 @@
+package world
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Failure is what went wrong while replaying a playthrough: a panic (e.g. from
+// Check) or an error returned by an invariant.
+type Failure struct {
+	// Index of the input that was being applied, -1 if creating the World
+	// failed.
+	Frame int
+	// The panic value or the error.
+	Message string
+	// The function and line that panicked, or "invariant".
+	Location string
+}
+
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// Signature identifies the failure, so that a smaller playthrough can be
+// checked to fail in the same way. The numbers in the message are left out
+// because they often depend on the frame or on positions (e.g. "hound at
+// (3, 4) is on an obstacle"), which change while minimizing.
+func (f Failure) Signature() string {
+	return digits.ReplaceAllString(f.Message, "#") + " at " + f.Location
+}
+
+// Invariant is checked after every frame of a replay. It returns an error if
+// the World is in a state it should never be in.
+type Invariant func(w *World) error
+
+// panicLocation returns the function and line that called panic, leaving out
+// the runtime and the helpers that only pass errors along (e.g. Check). It
+// must be called from the deferred function that recovered the panic.
+func panicLocation() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	afterPanic := false
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.gopanic" {
+			afterPanic = true
+		} else if afterPanic && !strings.HasPrefix(frame.Function, "runtime.") &&
+			!strings.HasSuffix(frame.Function, "gamelib.Check") {
+			return fmt.Sprintf("%s:%d", frame.Function, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// ReplayForFailure replays the playthrough and returns the first failure. The
+// invariant is optional.
+func ReplayForFailure(p Playthrough, invariant Invariant) (f Failure, failed bool) {
+	f.Frame = -1
+	defer func() {
+		if r := recover(); r != nil {
+			f.Message = fmt.Sprint(r)
+			f.Location = panicLocation()
+			failed = true
+		}
+	}()
+
+	w := NewWorldFromPlaythrough(p)
+	for i := range p.History {
+		f.Frame = i
+		p.StepFrame(&w, i)
+		if invariant == nil {
+			continue
+		}
+		if err := invariant(&w); err != nil {
+			return Failure{Frame: i, Message: err.Error(), Location: "invariant"}, true
+		}
+	}
+	return Failure{}, false
+}
+
+func isNeutral(input PlayerInput) bool {
+	return !input.LeftButtonPressed && !input.RightButtonPressed &&
+		!input.Move && !input.Shoot && !input.Undo
+}
+
+// neutral returns an input that does nothing. It keeps the mouse where it was,
+// so that the playthrough can still be watched.
+func neutral(input PlayerInput) PlayerInput {
+	return PlayerInput{MousePt: input.MousePt}
+}
+
+// withHistory returns a copy of p with a new history. The checksums don't
+// apply to the new history.
+func withHistory(p Playthrough, history []PlayerInput) Playthrough {
+	p.History = history
+	p.Checksums = nil
+	p.DesyncFrame = ZERO
+	return p
+}
+
+// MinimizeStats says how much work Minimize did.
+type MinimizeStats struct {
+	NReplays       int
+	OriginalFrames int
+	Frames         int
+	// Inputs that are not neutral (they click or undo).
+	OriginalActions int
+	Actions         int
+}
+
+func nActions(history []PlayerInput) (n int) {
+	for _, input := range history {
+		if !isNeutral(input) {
+			n++
+		}
+	}
+	return
+}
+
+// Minimize returns a playthrough that fails in the same way as p (see
+// Failure.Signature) but is as small as Minimize could make it. It works like
+// delta debugging: it tries to remove chunks of the history or to replace
+// them with inputs that do nothing, keeps every change that still fails the
+// same way, and makes the chunks smaller when no change works anymore. After
+// each change the history is cut right after the failure.
+// It returns false if p doesn't fail at all.
+func Minimize(p Playthrough, invariant Invariant) (minimized Playthrough,
+	f Failure, s MinimizeStats, ok bool) {
+	f, ok = ReplayForFailure(p, invariant)
+	s.NReplays++
+	if !ok {
+		return
+	}
+	signature := f.Signature()
+	s.OriginalFrames = len(p.History)
+	s.OriginalActions = nActions(p.History)
+
+	// try keeps the candidate history if it still fails the same way.
+	history := slices.Clone(p.History[:f.Frame+1])
+	try := func(candidate []PlayerInput) bool {
+		s.NReplays++
+		cf, failed := ReplayForFailure(withHistory(p, candidate), invariant)
+		if !failed || cf.Signature() != signature {
+			return false
+		}
+		history = candidate[:cf.Frame+1]
+		f = cf
+		return true
+	}
+
+	n := 2
+	for len(history) > 0 {
+		chunk := (len(history) + n - 1) / n
+		progress := false
+		for start := 0; start < len(history) && !progress; start += chunk {
+			end := min(start+chunk, len(history))
+			removed := slices.Concat(history[:start], history[end:])
+			if try(removed) {
+				progress = true
+				break
+			}
+			if nActions(history[start:end]) == 0 {
+				continue
+			}
+			neutralized := slices.Clone(history)
+			for i := start; i < end; i++ {
+				neutralized[i] = neutral(neutralized[i])
+			}
+			progress = try(neutralized)
+		}
+		if progress {
+			n = max(n-1, 2)
+		} else if chunk == 1 {
+			break
+		} else {
+			n = n * 2
+		}
+	}
+
+	minimized = withHistory(p, history)
+	s.Frames = len(history)
+	s.Actions = nActions(history)
+	return
+}
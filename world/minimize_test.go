@@ -0,0 +1,53 @@
+package world
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMinimize(t *testing.T) {
+	p := DeserializePlaythrough(ReadFile("playthroughs/average-playthrough.mln999-999"))
+	twoKills := func(w *World) error {
+		if w.NHoundsKilled.Geq(TWO) {
+			return fmt.Errorf("%d hounds killed", w.NHoundsKilled.ToInt())
+		}
+		return nil
+	}
+
+	f, failed := ReplayForFailure(p, twoKills)
+	assert.True(t, failed)
+	assert.Equal(t, "# hounds killed at invariant", f.Signature())
+
+	minimized, mf, s, ok := Minimize(p, twoKills)
+	assert.True(t, ok)
+	assert.Equal(t, f.Signature(), mf.Signature())
+	assert.Equal(t, len(minimized.History)-1, mf.Frame)
+	assert.Less(t, s.Frames, f.Frame+1)
+	assert.Less(t, s.Actions, s.OriginalActions)
+
+	// The minimized playthrough fails by itself, also after being saved.
+	minimized = DeserializePlaythrough(minimized.Serialize())
+	f, failed = ReplayForFailure(minimized, twoKills)
+	assert.True(t, failed)
+	assert.Equal(t, mf, f)
+
+	// Nothing to minimize.
+	_, _, _, ok = Minimize(p, nil)
+	assert.False(t, ok)
+}
+
+func TestReplayForFailure_Panic(t *testing.T) {
+	p := DeserializePlaythrough(ReadFile("playthroughs/average-playthrough.mln999-999"))
+	f, failed := ReplayForFailure(p, func(w *World) error {
+		if w.Player.OnMap {
+			Check(errors.New("on map"))
+		}
+		return nil
+	})
+	assert.True(t, failed)
+	assert.Equal(t, "on map", f.Message)
+	assert.Contains(t, f.Location, "TestReplayForFailure_Panic")
+}
@@ -0,0 +1,74 @@
+package world
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	"strings"
+)
+
+// MovementMode is an int64 and not an int because it is part of the Level,
+// which gets serialized using encoding/binary, which only accepts fixed-size
+// values.
+type MovementMode int64
+
+const (
+	// Teleport lets the player jump to any visible tile, limited by
+	// WorldParams.MaxTeleportDistance if it is positive.
+	Teleport MovementMode = iota
+	// Walk lets the player step only to the 8 tiles around him.
+	Walk
+)
+
+var movementModeName = map[MovementMode]string{
+	Teleport: "Teleport",
+	Walk:     "Walk",
+}
+
+// MoveDistanceLimit returns how many tiles the player can cover in one move,
+// or zero if there is no limit.
+func (w *World) MoveDistanceLimit() Int {
+	if w.MovementMode == Walk {
+		return ONE
+	}
+	return w.MaxTeleportDistance
+}
+
+// MoveRange returns the tiles that are close enough to "from" to be reached
+// in one move, without taking obstacles or vision into account.
+func (w *World) MoveRange(from Pt) (m MatBool) {
+	limit := w.MoveDistanceLimit()
+	if !limit.IsPositive() {
+		m.SetAll()
+		return
+	}
+	for y := 0; y < NRows; y++ {
+		for x := 0; x < NCols; x++ {
+			pt := IPt(x, y)
+			if tileDist(from, pt).Leq(limit) {
+				m.Set(pt)
+			}
+		}
+	}
+	return
+}
+
+// MoveCooldown returns the number of frames the player has to wait after
+// moving from "from" to "to".
+func (w *World) MoveCooldown(from, to Pt) Int {
+	return w.MoveCooldownPerTile.Times(tileDist(from, to))
+}
+
+func (m MovementMode) MarshalYAML() ([]byte, error) {
+	return []byte(movementModeName[m]), nil
+}
+
+func (m *MovementMode) UnmarshalYAML(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	for k, v := range movementModeName {
+		if v == s {
+			*m = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown movement mode: %s", s)
+}
@@ -0,0 +1,63 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWorld_WalkingWithStepCooldown(t *testing.T) {
+	var l Level
+	l.MovementMode = Walk
+	l.MoveCooldownPerTile = I(3)
+	w := NewWorld(I(0), l)
+	w.Step(PlayerInput{Move: true, MovePt: IPt(1, 1)})
+
+	free := w.Player.ComputeFreePositions(&w)
+	assert.True(t, free.At(IPt(2, 2)))
+	assert.False(t, free.At(IPt(3, 3)))
+
+	w.Step(PlayerInput{Move: true, MovePt: IPt(2, 2)})
+	assert.Equal(t, IPt(2, 2), w.Player.Pos())
+
+	// The player has to wait before the next step.
+	w.Step(PlayerInput{Move: true, MovePt: IPt(3, 3)})
+	assert.Equal(t, IPt(2, 2), w.Player.Pos())
+	w.Step(PlayerInput{})
+	w.Step(PlayerInput{Move: true, MovePt: IPt(3, 3)})
+	assert.Equal(t, IPt(3, 3), w.Player.Pos())
+}
+
+func TestWorld_MaxTeleportDistanceAndReentry(t *testing.T) {
+	var l Level
+	l.MaxTeleportDistance = I(2)
+	l.ForceReentry = true
+	l.ReentryPos = IPt(7, 7)
+	w := NewWorld(I(0), l)
+	w.Step(PlayerInput{Move: true, MovePt: IPt(1, 1)})
+
+	free := w.Player.ComputeFreePositions(&w)
+	assert.True(t, free.At(IPt(3, 1)))
+	assert.False(t, free.At(IPt(4, 1)))
+
+	w.Player.Hit()
+	w.Player.CooldownAfterGettingHitIdx = ZERO
+	free = w.Player.ComputeFreePositions(&w)
+	assert.Equal(t, int64(1), free.ToArray().N)
+	assert.True(t, free.At(IPt(7, 7)))
+}
+
+func TestWorld_ReentryFallsBackToTheClosestFreeTile(t *testing.T) {
+	var l Level
+	l.ForceReentry = true
+	l.ReentryPos = IPt(7, 7)
+	l.Terrain.Set(IPt(7, 7), Crate)
+	w := NewWorld(I(0), l)
+	w.Step(PlayerInput{Move: true, MovePt: IPt(1, 1)})
+
+	w.Player.Hit()
+	w.Player.CooldownAfterGettingHitIdx = ZERO
+	free := w.Player.ComputeFreePositions(&w)
+	assert.Equal(t, int64(1), free.ToArray().N)
+	assert.Equal(t, ONE, free.ToArray().V[0].SquaredDistTo(IPt(7, 7)))
+}
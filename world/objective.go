@@ -0,0 +1,293 @@
+package world
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	"slices"
+	"strings"
+)
+
+// ObjectiveType is an int64 and not an int because it is part of the Level,
+// which gets serialized using encoding/binary, which only accepts fixed-size
+// values.
+type ObjectiveType int64
+
+const (
+	// KillAllEnemies is won when all hounds are dead and no spawn portal is
+	// active anymore. It is the objective of a level that lists no goals.
+	KillAllEnemies ObjectiveType = iota
+	// Survive is won after ObjectiveParams.Seconds seconds.
+	Survive
+	// ReachExit is won when the player stands on the exit at
+	// ObjectiveParams.Pos. The exit stays locked until all keys are collected.
+	ReachExit
+	// CollectKey is won when the player stands on the key at
+	// ObjectiveParams.Pos.
+	CollectKey
+	// KillTarget is won when the player kills the first hound spawned by the
+	// spawn portal at ObjectiveParams.Pos.
+	KillTarget
+	// ProtectAlly is lost when the ally standing at ObjectiveParams.Pos takes
+	// ObjectiveParams.Health hits from hounds.
+	ProtectAlly
+	// TimeLimit is lost if the level isn't won after ObjectiveParams.Seconds
+	// seconds.
+	TimeLimit
+)
+
+var objectiveTypeName = map[ObjectiveType]string{
+	KillAllEnemies: "KillAllEnemies",
+	Survive:        "Survive",
+	ReachExit:      "ReachExit",
+	CollectKey:     "CollectKey",
+	KillTarget:     "KillTarget",
+	ProtectAlly:    "ProtectAlly",
+	TimeLimit:      "TimeLimit",
+}
+
+const framesPerSecond = 60
+
+type ObjectiveParams struct {
+	Type    ObjectiveType `yaml:"Type"`
+	Seconds Int           `yaml:"Seconds"`
+	Pos     Pt            `yaml:"Pos"`
+	Health  Int           `yaml:"Health"`
+}
+
+// Objective holds the progress of an ObjectiveParams while the World runs.
+type Objective struct {
+	ObjectiveParams
+	Done          bool // a goal was reached
+	Failed        bool // a loss condition was triggered
+	TargetSpawned bool
+	AllyHealth    Int
+	Locked        bool // for ReachExit, true while keys are left to collect
+}
+
+func NewObjective(p ObjectiveParams) (o Objective) {
+	o.ObjectiveParams = p
+	o.AllyHealth = p.Health
+	return
+}
+
+// IsLossCondition returns true for the objectives that can only make the
+// player lose. The level is won when all the other objectives are done.
+func (o *Objective) IsLossCondition() bool {
+	return o.Type == ProtectAlly || o.Type == TimeLimit
+}
+
+// Pos and State make the objectives that are on the map visible as
+// WorldObjects.
+func (o *Objective) Pos() Pt {
+	return o.ObjectiveParams.Pos
+}
+
+func (o *Objective) State() string {
+	switch o.Type {
+	case ReachExit:
+		if o.Locked {
+			return "ExitLocked"
+		}
+		return "Exit"
+	case CollectKey:
+		if o.Done {
+			return ""
+		}
+		return "Key"
+	case ProtectAlly:
+		if o.Failed {
+			return ""
+		}
+		return "Ally"
+	}
+	return ""
+}
+
+func (w *World) keysCollected() bool {
+	for i := range w.Objectives.N {
+		o := &w.Objectives.V[i]
+		if o.Type == CollectKey && !o.Done {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *World) updateExitLocks() {
+	locked := !w.keysCollected()
+	for i := range w.Objectives.N {
+		o := &w.Objectives.V[i]
+		if o.Type == ReachExit {
+			o.Locked = locked
+		}
+	}
+}
+
+// markTarget marks h if it is the first hound spawned by a portal at pos
+// and a KillTarget objective is waiting for that portal.
+func (w *World) markTarget(h *Hound, pos Pt) {
+	for i := range w.Objectives.N {
+		o := &w.Objectives.V[i]
+		if o.Type == KillTarget && !o.TargetSpawned && o.Pos() == pos {
+			o.TargetSpawned = true
+			h.marked = true
+			h.targetIdx = i
+			return
+		}
+	}
+}
+
+// hitAllies makes every hound next to an ally hit it. It must be called when
+// the enemies move, while EnemyMoveCooldown is still ready.
+func (w *World) hitAllies() {
+	for i := range w.Objectives.N {
+		o := &w.Objectives.V[i]
+		if o.Type != ProtectAlly || o.Failed {
+			continue
+		}
+		for j := range w.Enemies.N {
+			if tileDist(w.Enemies.V[j].Pos(), o.Pos()).Leq(ONE) {
+				o.AllyHealth.Dec()
+			}
+		}
+		if !o.AllyHealth.IsPositive() {
+			o.AllyHealth = ZERO
+			o.Failed = true
+		}
+	}
+}
+
+func (w *World) stepObjectives() {
+	seconds := w.TimeStep.DivBy(I(framesPerSecond))
+	onMap := w.Player.OnMap
+	for i := range w.Objectives.N {
+		o := &w.Objectives.V[i]
+		switch o.Type {
+		case Survive:
+			o.Done = seconds.Geq(o.Seconds)
+		case CollectKey:
+			if onMap && w.Player.Pos() == o.Pos() {
+				o.Done = true
+			}
+		case TimeLimit:
+			o.Failed = seconds.Geq(o.Seconds)
+		}
+	}
+
+	// The exit can only be reached after the keys were collected, which may
+	// happen in the same frame.
+	w.updateExitLocks()
+	for i := range w.Objectives.N {
+		o := &w.Objectives.V[i]
+		if o.Type == ReachExit && !o.Locked && onMap && w.Player.Pos() == o.Pos() {
+			o.Done = true
+		}
+	}
+}
+
+// objectivesWon returns true if every goal of the level is reached.
+func (w *World) objectivesWon() bool {
+	hasGoal := false
+	for i := range w.Objectives.N {
+		o := &w.Objectives.V[i]
+		if o.IsLossCondition() {
+			continue
+		}
+		hasGoal = true
+		if o.Type == KillAllEnemies {
+			if !w.AllEnemiesDead() {
+				return false
+			}
+		} else if !o.Done {
+			return false
+		}
+	}
+	if !hasGoal {
+		return w.AllEnemiesDead()
+	}
+	return true
+}
+
+func (w *World) objectivesLost() bool {
+	for i := range w.Objectives.N {
+		if w.Objectives.V[i].Failed {
+			return true
+		}
+	}
+	return false
+}
+
+// ObjectivesText describes the progress towards the level's objectives, in a
+// form that can be shown to the player.
+func (w *World) ObjectivesText() string {
+	if w.Objectives.N == 0 {
+		return "Kill everyone!"
+	}
+
+	seconds := w.TimeStep.DivBy(I(framesPerSecond))
+	var parts []string
+	nKeys, nKeysCollected := 0, 0
+	for i := range w.Objectives.N {
+		o := &w.Objectives.V[i]
+		switch o.Type {
+		case KillAllEnemies:
+			parts = append(parts, "Kill everyone!")
+		case Survive:
+			left := Max(o.Seconds.Minus(seconds), ZERO)
+			parts = append(parts, fmt.Sprintf("Survive %ds.", left.ToInt64()))
+		case ReachExit:
+			parts = append(parts, "Reach the exit.")
+		case CollectKey:
+			nKeys++
+			if o.Done {
+				nKeysCollected++
+			}
+		case KillTarget:
+			if o.Done {
+				parts = append(parts, "Target killed.")
+			} else {
+				parts = append(parts, "Kill the target.")
+			}
+		case ProtectAlly:
+			parts = append(parts, fmt.Sprintf("Ally health %d.", o.AllyHealth.ToInt64()))
+		case TimeLimit:
+			left := Max(o.Seconds.Minus(seconds), ZERO)
+			parts = append(parts, fmt.Sprintf("%ds left.", left.ToInt64()))
+		}
+	}
+	if nKeys > 0 {
+		parts = append(parts, fmt.Sprintf("Keys %d/%d.", nKeysCollected, nKeys))
+	}
+	return strings.Join(parts, " ")
+}
+
+// ObjectiveSummary names the goal types of a level, so that playthroughs can
+// be grouped by what the player had to do, e.g. "ReachExit+CollectKey".
+func (l *Level) ObjectiveSummary() string {
+	if l.Objectives.N == 0 {
+		return objectiveTypeName[KillAllEnemies]
+	}
+	var names []string
+	for i := range l.Objectives.N {
+		name := objectiveTypeName[l.Objectives.V[i].Type]
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "+")
+}
+
+func (t ObjectiveType) MarshalYAML() ([]byte, error) {
+	return []byte(objectiveTypeName[t]), nil
+}
+
+func (t *ObjectiveType) UnmarshalYAML(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	for k, v := range objectiveTypeName {
+		if v == s {
+			*t = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown objective type: %s", s)
+}
@@ -0,0 +1,96 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func Test_ObjectivesYaml(t *testing.T) {
+	var a ObjectiveParamsArray
+	a.N = 3
+	a.V[0] = ObjectiveParams{Type: ReachExit, Pos: IPt(7, 7)}
+	a.V[1] = ObjectiveParams{Type: CollectKey, Pos: IPt(2, 3)}
+	a.V[2] = ObjectiveParams{Type: TimeLimit, Seconds: I(30)}
+
+	filename := "objectives.yaml"
+	SaveYAML(filename, a)
+	var a2 ObjectiveParamsArray
+	LoadYAML(os.DirFS(".").(FS), filename, &a2)
+	DeleteFile(filename)
+	assert.Equal(t, a, a2)
+}
+
+func TestWorld_KeyUnlocksExit(t *testing.T) {
+	var l Level
+	l.Objectives.N = 2
+	l.Objectives.V[0] = ObjectiveParams{Type: ReachExit, Pos: IPt(6, 6)}
+	l.Objectives.V[1] = ObjectiveParams{Type: CollectKey, Pos: IPt(2, 2)}
+	w := NewWorld(I(0), l)
+	assert.Equal(t, "ReachExit+CollectKey", l.ObjectiveSummary())
+
+	// The exit is locked.
+	w.Step(PlayerInput{Move: true, MovePt: IPt(6, 6)})
+	assert.Equal(t, Ongoing, w.Status())
+	assert.Equal(t, "ExitLocked", w.Objectives.V[0].State())
+
+	w.Step(PlayerInput{Move: true, MovePt: IPt(2, 2)})
+	assert.Equal(t, Ongoing, w.Status())
+	assert.Equal(t, "Exit", w.Objectives.V[0].State())
+
+	w.Step(PlayerInput{Move: true, MovePt: IPt(6, 6)})
+	assert.Equal(t, Won, w.Status())
+}
+
+func TestWorld_SurviveAndTimeLimit(t *testing.T) {
+	var l Level
+	l.Objectives.N = 1
+	l.Objectives.V[0] = ObjectiveParams{Type: Survive, Seconds: I(2)}
+	w := NewWorld(I(0), l)
+	for range 119 {
+		w.Step(PlayerInput{})
+	}
+	assert.Equal(t, Ongoing, w.Status())
+	assert.Equal(t, "Survive 1s.", w.ObjectivesText())
+	w.Step(PlayerInput{})
+	assert.Equal(t, Won, w.Status())
+
+	l.Objectives.V[0] = ObjectiveParams{Type: TimeLimit, Seconds: I(1)}
+	l.Objectives.V[1] = ObjectiveParams{Type: CollectKey, Pos: IPt(2, 2)}
+	l.Objectives.N = 2
+	w = NewWorld(I(0), l)
+	for range 60 {
+		w.Step(PlayerInput{})
+	}
+	assert.Equal(t, Lost, w.Status())
+}
+
+func TestWorld_ProtectAlly(t *testing.T) {
+	var l Level
+	l.EnemyMoveCooldownDuration = I(20)
+	l.HoundMaxHealth = I(3)
+	l.SpawnPortalsParams.N = 1
+	l.SpawnPortalsParams.V[0].Pos = IPt(5, 6)
+	l.Events.N = 1
+	l.Events.V[0] = LevelEvent{Trigger: AtStart, Action: SpawnWave,
+		Portal: IPt(5, 6), NHounds: ONE, Burst: true}
+	l.Objectives.N = 1
+	l.Objectives.V[0] = ObjectiveParams{Type: ProtectAlly, Pos: IPt(5, 5),
+		Health: I(2)}
+	w := NewWorld(I(0), l)
+
+	// The hound stays next to the ally and hits it once per enemy move,
+	// starting with the frame in which it spawns.
+	w.Step(PlayerInput{})
+	assert.Equal(t, int64(1), w.Enemies.N)
+	assert.Equal(t, ONE, w.Objectives.V[0].AllyHealth)
+	for range 19 {
+		w.Step(PlayerInput{})
+	}
+	assert.Equal(t, ONE, w.Objectives.V[0].AllyHealth)
+	assert.Equal(t, Ongoing, w.Status())
+	w.Step(PlayerInput{})
+	assert.Equal(t, ZERO, w.Objectives.V[0].AllyHealth)
+	assert.Equal(t, Lost, w.Status())
+}
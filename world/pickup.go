@@ -0,0 +1,250 @@
+package world
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	"strings"
+)
+
+// PickupType is an int64 and not an int because it is part of the Level,
+// which gets serialized using encoding/binary, which only accepts fixed-size
+// values.
+type PickupType int64
+
+const (
+	HealthPickup PickupType = iota
+	EnergyPickup
+	DoubleDamagePickup
+	FasterCooldownPickup
+)
+
+var pickupTypeName = map[PickupType]string{
+	HealthPickup:         "Health",
+	EnergyPickup:         "Energy",
+	DoubleDamagePickup:   "DoubleDamage",
+	FasterCooldownPickup: "FasterCooldown",
+}
+
+// PickupSpawnRule decides when and where a pickup appears on the map.
+type PickupSpawnRule int64
+
+const (
+	// SpawnFixed places the pickup at PickupParams.Pos when the level starts.
+	// If PickupParams.SpawnCooldown is positive, the pickup comes back to the
+	// same position that many frames after it was collected.
+	SpawnFixed PickupSpawnRule = iota
+	// SpawnTimed places the pickup on a random free tile every
+	// PickupParams.SpawnCooldown frames, as long as the previous pickup
+	// spawned by the same rule was collected.
+	SpawnTimed
+	// SpawnHoundDrop places the pickup where a hound dies, with a chance of
+	// PickupParams.DropChance percent.
+	SpawnHoundDrop
+)
+
+var pickupSpawnRuleName = map[PickupSpawnRule]string{
+	SpawnFixed:     "Fixed",
+	SpawnTimed:     "Timed",
+	SpawnHoundDrop: "HoundDrop",
+}
+
+type PickupParams struct {
+	Type          PickupType      `yaml:"Type"`
+	SpawnRule     PickupSpawnRule `yaml:"SpawnRule"`
+	Pos           Pt              `yaml:"Pos"`
+	Amount        Int             `yaml:"Amount"`   // health or energy points
+	Duration      Int             `yaml:"Duration"` // frames a power-up lasts
+	SpawnCooldown Int             `yaml:"SpawnCooldown"`
+	DropChance    Int             `yaml:"DropChance"`
+}
+
+type Pickup struct {
+	pos       Pt
+	Type      PickupType
+	Amount    Int
+	Duration  Int
+	SpawnerId int64 // index of the PickupSpawner that created this pickup
+}
+
+// PickupSpawner holds the state needed to apply a PickupParams rule while the
+// World runs.
+type PickupSpawner struct {
+	PickupParams
+	SpawnTimer Cooldown
+}
+
+func NewPickupSpawner(p PickupParams) (s PickupSpawner) {
+	s.PickupParams = p
+	s.SpawnTimer = NewCooldown(p.SpawnCooldown)
+	s.SpawnTimer.Reset()
+	return
+}
+
+func NewPickup(spawnerId int64, p PickupParams, pos Pt) (pk Pickup) {
+	pk.pos = pos
+	pk.Type = p.Type
+	pk.Amount = p.Amount
+	pk.Duration = p.Duration
+	pk.SpawnerId = spawnerId
+	return
+}
+
+func (p *Pickup) Pos() Pt {
+	return p.pos
+}
+
+func (p *Pickup) State() string { return pickupTypeName[p.Type] }
+
+// Apply gives the effect of the pickup to the player.
+func (p *Pickup) Apply(pl *Player) {
+	switch p.Type {
+	case HealthPickup:
+		pl.Health = Min(pl.Health.Plus(p.Amount), pl.MaxHealth)
+	case EnergyPickup:
+		pl.Energy.Add(p.Amount)
+	case DoubleDamagePickup:
+		pl.DoubleDamageIdx = p.Duration
+	case FasterCooldownPickup:
+		pl.FasterCooldownIdx = p.Duration
+	}
+}
+
+func (w *World) PickupPositions() (m MatBool) {
+	for i := range w.Pickups.N {
+		m.Set(w.Pickups.V[i].Pos())
+	}
+	return
+}
+
+func (w *World) addPickup(spawnerId int64, pos Pt) {
+	if w.Pickups.N == int64(len(w.Pickups.V)) {
+		// The map is full of pickups, the player should collect some.
+		return
+	}
+	occupied := w.PickupPositions()
+	if occupied.At(pos) {
+		// Don't stack pickups on top of each other.
+		return
+	}
+	w.Pickups.V[w.Pickups.N] = NewPickup(spawnerId, w.PickupSpawners.V[spawnerId].PickupParams, pos)
+	w.Pickups.N++
+}
+
+func (w *World) spawnerHasPickupOnMap(spawnerId int64) bool {
+	for i := range w.Pickups.N {
+		if w.Pickups.V[i].SpawnerId == spawnerId {
+			return true
+		}
+	}
+	return false
+}
+
+// SpawnFixedPickups places the pickups that are at a fixed position in the
+// level. It is called once, when the World is created.
+func (w *World) SpawnFixedPickups() {
+	for i := range w.PickupSpawners.N {
+		if w.PickupSpawners.V[i].SpawnRule == SpawnFixed {
+			w.addPickup(i, w.PickupSpawners.V[i].Pos)
+		}
+	}
+}
+
+// StepPickupSpawners spawns the pickups whose rules depend on time passing.
+func (w *World) StepPickupSpawners() {
+	for i := range w.PickupSpawners.N {
+		s := &w.PickupSpawners.V[i]
+		if s.SpawnRule == SpawnHoundDrop {
+			continue
+		}
+		if s.SpawnRule == SpawnFixed && !s.SpawnCooldown.IsPositive() {
+			// Only spawned once, at the start of the level.
+			continue
+		}
+
+		// The timer only starts once the previous pickup was collected.
+		if w.spawnerHasPickupOnMap(i) {
+			continue
+		}
+
+		s.SpawnTimer.Update()
+		if !s.SpawnTimer.Ready() {
+			continue
+		}
+
+		if s.SpawnRule == SpawnFixed {
+			w.addPickup(i, s.Pos)
+		} else {
+			// Build matrix with positions occupied everywhere we don't want to
+			// spawn the pickup.
+			occ := w.SolidTiles
+			occ.Add(w.PickupPositions())
+			occ.Add(w.EnemyPositions())
+			for j := range w.Ammos.N {
+				occ.Set(w.Ammos.V[j].Pos)
+			}
+			occ.Set(w.Player.Pos())
+			free := occ
+			free.Negate()
+			if free.ToArray().N == 0 {
+				// Try again on the next frame, a tile might be free then.
+				continue
+			}
+			w.addPickup(i, occ.RandomUnoccupiedPos(&w.Rand))
+		}
+		s.SpawnTimer.Reset()
+	}
+}
+
+func (w *World) DropPickups(houndPos Pt) {
+	for i := range w.PickupSpawners.N {
+		s := &w.PickupSpawners.V[i]
+		if s.SpawnRule != SpawnHoundDrop {
+			continue
+		}
+		if w.RInt(I(1), I(100)).Leq(s.DropChance) {
+			w.addPickup(i, houndPos)
+		}
+	}
+}
+
+func (w *World) CollectPickups() {
+	for i := int64(0); i < w.Pickups.N; {
+		if w.Pickups.V[i].Pos() == w.Player.Pos() {
+			w.Pickups.V[i].Apply(&w.Player)
+			w.Pickups.V[i] = w.Pickups.V[w.Pickups.N-1]
+			w.Pickups.N--
+		} else {
+			i++
+		}
+	}
+}
+
+func (t PickupType) MarshalYAML() ([]byte, error) {
+	return []byte(pickupTypeName[t]), nil
+}
+
+func (t *PickupType) UnmarshalYAML(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	for k, v := range pickupTypeName {
+		if v == s {
+			*t = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown pickup type: %s", s)
+}
+
+func (r PickupSpawnRule) MarshalYAML() ([]byte, error) {
+	return []byte(pickupSpawnRuleName[r]), nil
+}
+
+func (r *PickupSpawnRule) UnmarshalYAML(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	for k, v := range pickupSpawnRuleName {
+		if v == s {
+			*r = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown pickup spawn rule: %s", s)
+}
@@ -0,0 +1,75 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func Test_PickupsYaml(t *testing.T) {
+	var a PickupParamsArray
+	a.N = 3
+	a.V[0].Type = HealthPickup
+	a.V[0].SpawnRule = SpawnFixed
+	a.V[0].Pos = IPt(3, 4)
+	a.V[0].Amount = I(1)
+	a.V[1].Type = DoubleDamagePickup
+	a.V[1].SpawnRule = SpawnTimed
+	a.V[1].Duration = I(300)
+	a.V[1].SpawnCooldown = I(600)
+	a.V[2].Type = EnergyPickup
+	a.V[2].SpawnRule = SpawnHoundDrop
+	a.V[2].Amount = I(2)
+	a.V[2].DropChance = I(25)
+
+	filename := "pickups.yaml"
+	SaveYAML(filename, a)
+	var a2 PickupParamsArray
+	LoadYAML(os.DirFS(".").(FS), filename, &a2)
+	DeleteFile(filename)
+	assert.Equal(t, a, a2)
+}
+
+func TestWorld_HealthPickupIsCappedByMaxHealth(t *testing.T) {
+	var l Level
+	l.PickupsParams.N = 2
+	l.PickupsParams.V[0] = PickupParams{Type: HealthPickup, SpawnRule: SpawnFixed, Pos: IPt(2, 2), Amount: I(5)}
+	l.PickupsParams.V[1] = PickupParams{Type: DoubleDamagePickup, SpawnRule: SpawnFixed, Pos: IPt(5, 5), Duration: I(10)}
+	w := NewWorld(I(0), l)
+	assert.Equal(t, int64(2), w.Pickups.N)
+
+	w.Player.Health = ONE
+	w.Step(PlayerInput{Move: true, MovePt: IPt(2, 2)})
+	assert.Equal(t, w.Player.MaxHealth, w.Player.Health)
+	assert.Equal(t, int64(1), w.Pickups.N)
+
+	w.Step(PlayerInput{Move: true, MovePt: IPt(5, 5)})
+	assert.Equal(t, TWO, w.Player.BeamDamage())
+	assert.Equal(t, int64(0), w.Pickups.N)
+
+	// The power-up wears off.
+	for range 10 {
+		w.Step(PlayerInput{})
+	}
+	assert.Equal(t, ONE, w.Player.BeamDamage())
+}
+
+func TestWorld_TimedPickupWaitsForAFreeTile(t *testing.T) {
+	var l Level
+	l.PickupsParams.N = 1
+	l.PickupsParams.V[0] = PickupParams{Type: HealthPickup,
+		SpawnRule: SpawnTimed, SpawnCooldown: I(1), Amount: I(1)}
+	l.Obstacles.SetAll()
+	w := NewWorld(I(0), l)
+	for range 3 {
+		w.Step(PlayerInput{})
+	}
+	assert.Equal(t, int64(0), w.Pickups.N)
+
+	w.Obstacles.Clear(IPt(4, 4))
+	w.SolidTiles.Clear(IPt(4, 4))
+	w.Step(PlayerInput{})
+	assert.Equal(t, int64(1), w.Pickups.N)
+	assert.Equal(t, IPt(4, 4), w.Pickups.V[0].Pos())
+}
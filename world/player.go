@@ -15,6 +15,10 @@ type Player struct {
 	CooldownAfterGettingHit    Int
 	CooldownAfterGettingHitIdx Int
 	Energy                     Int
+	DoubleDamageIdx            Int  // if positive, the beam does double damage
+	FasterCooldownIdx          Int  // if positive, recover twice as fast after being hit
+	MoveCooldownIdx            Int  // if positive, the player can't move yet
+	EnteredMap                 bool // true once the player first got on the map
 	state                      string
 }
 
@@ -28,13 +32,30 @@ func NewPlayer() (p Player) {
 // ComputeFreePositions returns a matrix that indicates the positions where the
 // player can move to from his current state.
 func (p *Player) ComputeFreePositions(w *World) (free MatBool) {
+	if p.MoveCooldownIdx.IsPositive() {
+		return
+	}
+
 	if p.OnMap {
 		free = w.VisibleTiles
+		free.IntersectWith(w.MoveRange(p.pos))
+	} else if w.ForceReentry && p.EnteredMap {
+		// If a crate or a hound is on the reentry tile, the player comes
+		// back on the closest tile that is free.
+		free.SetAll()
+		free.Subtract(w.SolidTiles)
+		free.Subtract(w.EnemyPositions())
+		tiles := free.ToArray()
+		free.ClearAll()
+		if ok, pos := GetClosestPoint(tiles.V[:tiles.N], w.ReentryPos); ok {
+			free.Set(pos)
+		}
+		return
 	} else {
 		free.SetAll()
 	}
 
-	free.Subtract(w.Obstacles)
+	free.Subtract(w.SolidTiles)
 	free.Subtract(w.EnemyPositions())
 	return
 }
@@ -52,30 +73,53 @@ func (p *Player) Step(w *World, input PlayerInput) {
 		p.state = "Resting"
 	}
 
+	// Power-ups wear off with time.
+	if p.DoubleDamageIdx.IsPositive() {
+		p.DoubleDamageIdx.Dec()
+	}
+	if p.FasterCooldownIdx.IsPositive() {
+		p.FasterCooldownIdx.Dec()
+	}
+	if p.MoveCooldownIdx.IsPositive() {
+		p.MoveCooldownIdx.Dec()
+	}
+
 	if p.CooldownAfterGettingHitIdx.Gt(ZERO) {
 		p.CooldownAfterGettingHitIdx.Dec()
+		if p.FasterCooldownIdx.IsPositive() && p.CooldownAfterGettingHitIdx.IsPositive() {
+			p.CooldownAfterGettingHitIdx.Dec()
+		}
 		return
 	}
 
 	if input.Move {
 		free := p.ComputeFreePositions(w)
 		if free.At(input.MovePt) {
+			if p.OnMap {
+				p.MoveCooldownIdx = w.MoveCooldown(p.pos, input.MovePt)
+			}
 			p.pos = input.MovePt
 			p.OnMap = true
+			p.EnteredMap = true
+			w.emit(PlayerMoved, p.pos, ZERO)
 
 			// Collect ammos.
 			for i := int64(0); i < w.Ammos.N; {
 				if w.Ammos.V[i].Pos == w.Player.pos {
+					w.emit(AmmoCollected, w.Ammos.V[i].Pos, w.Ammos.V[i].Count)
 					w.Player.AmmoCount.Add(w.Ammos.V[i].Count)
 					if w.Player.AmmoCount.Gt(w.Player.AmmoLimit) {
 						w.Player.AmmoCount = w.Player.AmmoLimit
 					}
 					w.Ammos.V[i] = w.Ammos.V[w.Ammos.N-1]
 					w.Ammos.N--
+					w.AmmoRespawnTimer.Reset()
 				} else {
 					i++
 				}
 			}
+
+			w.CollectPickups()
 		}
 	}
 
@@ -90,16 +134,30 @@ func (p *Player) Step(w *World, input PlayerInput) {
 			}
 		}
 
-		if nShotEnemies > 0 {
+		hitCrate := w.HitCrate(input.ShootPt)
+		hitPortal := w.HitSpawnPortal(input.ShootPt)
+
+		if nShotEnemies > 0 || hitCrate || hitPortal {
 			w.Beam.Idx = w.BeamMax // show beam
 			w.Beam.End = w.TileToWorldPos(input.ShootPt)
+			w.emit(ShotFired, input.ShootPt, ZERO)
 			if w.UseAmmo {
 				w.Player.AmmoCount.Dec()
 			}
+		} else {
+			w.emit(ShotMissed, input.ShootPt, ZERO)
 		}
 	}
 }
 
+// BeamDamage returns how much health an enemy loses when hit by the beam.
+func (p *Player) BeamDamage() Int {
+	if p.DoubleDamageIdx.IsPositive() {
+		return TWO
+	}
+	return ONE
+}
+
 func (p *Player) Hit() {
 	p.JustHit = true
 	p.OnMap = false
@@ -2,7 +2,6 @@ package world
 
 import (
 	"bytes"
-	"fmt"
 	"github.com/google/uuid"
 	. "github.com/marisvali/miln/gamelib"
 	"slices"
@@ -20,7 +19,11 @@ import (
 // Playthrough structure and translating it to the new one.
 // Out of the 3 versions (ReleaseVersion, SimulationVersion and InputVersion),
 // the InputVersion is the one expected to change the least often.
-const InputVersion = 999
+// The versions so far:
+// - 999: the first release, see inputVersion999.
+// - 1000: terrain, pickups, ammo rules, movement rules, objectives and events
+// in the Level, Undo in the PlayerInput and checksums in the Playthrough.
+const InputVersion = 1000
 
 // Playthrough represents all the input sent to a World during the execution
 // of a level. Given this input and a compatible simulation, the same output
@@ -33,6 +36,14 @@ type Playthrough struct {
 	Id      uuid.UUID
 	Seed    Int
 	History []PlayerInput
+	// See StateChecksum. A ChecksumInterval of 0 means no checksums.
+	ChecksumInterval Int
+	Checksums        []uint32
+	// DesyncFrame is not saved, it is set by StepFrame when the replay doesn't
+	// match the Checksums. It is 0 if no mismatch was found (yet).
+	DesyncFrame Int
+	// undo is not saved, see stepFrame.
+	undo undoState
 }
 
 func (p *Playthrough) Serialize() []byte {
@@ -44,30 +55,69 @@ func (p *Playthrough) Serialize() []byte {
 	Serialize(buf, p.Id)
 	Serialize(buf, p.Seed)
 	SerializeSlice(buf, p.History)
+	Serialize(buf, p.ChecksumInterval)
+	SerializeSlice(buf, p.Checksums)
 	return Zip(buf.Bytes())
 }
 
 func (p *Playthrough) Clone() *Playthrough {
 	clone := *p
 	clone.History = slices.Clone(p.History)
+	clone.Checksums = slices.Clone(p.Checksums)
+	// The clone rebuilds its own undoState if it needs one. Sharing it would
+	// copy the snapshots, which are big, when most clones only need History.
+	clone.undo = undoState{nFrames: -1}
 	return &clone
 }
 
 func DeserializePlaythrough(data []byte) (p Playthrough) {
-	buf := bytes.NewBuffer(Unzip(data))
-	Deserialize(buf, &p.InputVersion)
+	p, err := TryDeserializePlaythrough(data)
+	Check(err)
+	return
+}
+
+// TryDeserializePlaythrough returns a VersionError for an InputVersion it
+// can't read and a CorruptDataError for anything that isn't a complete
+// playthrough. It doesn't check the SimulationVersion, see
+// TryNewWorldFromPlaythrough.
+// It reads playthrough files (see SerializePlaythroughFile) as well as raw
+// playthroughs, and converts playthroughs of InputVersion 999 (see
+// inputVersion999).
+func TryDeserializePlaythrough(data []byte) (p Playthrough, err error) {
+	if HasPlaythroughHeader(data) {
+		if _, data, err = splitPlaythroughFile(data); err != nil {
+			return
+		}
+	}
+	unzipped, err := TryUnzip(data)
+	if err != nil {
+		return
+	}
+	buf := bytes.NewBuffer(unzipped)
+	if err = TryDeserialize(buf, &p.InputVersion); err != nil {
+		return
+	}
+	if p.InputVersion.ToInt64() == inputVersion999 {
+		return tryDeserializePlaythrough999(buf)
+	}
 	if p.InputVersion.ToInt64() != InputVersion {
-		Check(fmt.Errorf("can't deserialize this playthrough - we are at "+
-			"InputVersion %d and playthrough was generated with InputVersion "+
-			"version %d",
-			InputVersion, p.InputVersion.ToInt64()))
+		err = &VersionError{"InputVersion", p.InputVersion.ToInt64(),
+			InputVersion}
+		return
+	}
+	for _, field := range []any{&p.SimulationVersion, &p.ReleaseVersion,
+		&p.Level, &p.Id, &p.Seed} {
+		if err = TryDeserialize(buf, field); err != nil {
+			return
+		}
+	}
+	if err = TryDeserializeSlice(buf, &p.History); err != nil {
+		return
+	}
+	if err = TryDeserialize(buf, &p.ChecksumInterval); err != nil {
+		return
 	}
-	Deserialize(buf, &p.SimulationVersion)
-	Deserialize(buf, &p.ReleaseVersion)
-	Deserialize(buf, &p.Level)
-	Deserialize(buf, &p.Id)
-	Deserialize(buf, &p.Seed)
-	DeserializeSlice(buf, &p.History)
+	err = TryDeserializeSlice(buf, &p.Checksums)
 	return
 }
 
@@ -75,6 +125,8 @@ func DeserializePlaythrough(data []byte) (p Playthrough) {
 // step operation many times, and you need to step a Playthrough and a World
 // at the same time.
 func Step(p *Playthrough, w *World, input PlayerInput) {
+	// The input is new, so there is nothing to verify, only to record.
 	p.History = append(p.History, input)
-	w.Step(input)
+	p.stepFrame(w, len(p.History)-1)
+	p.recordChecksum(w)
 }
@@ -0,0 +1,127 @@
+package world
+
+import (
+	"bytes"
+	. "github.com/marisvali/miln/gamelib"
+)
+
+// inputVersion999 is the InputVersion of the first playthroughs, saved before
+// the Level got terrain, pickups, ammo rules, objectives and events, before
+// PlayerInput got Undo and before the Playthrough got checksums.
+// TryDeserializePlaythrough still reads these playthroughs. It loads them with
+// the structures below, which must never change, and converts them. The new
+// fields get their zero values, which give the rules of that time.
+const inputVersion999 = 999
+
+type worldParams999 struct {
+	Boardgame                      bool
+	UseAmmo                        bool
+	AmmoLimit                      Int
+	EnemyMoveCooldownDuration      Int
+	EnemiesAggroWhenVisible        bool
+	SpawnPortalCooldownMin         Int
+	SpawnPortalCooldownMax         Int
+	HoundMaxHealth                 Int
+	HoundMoveCooldownMultiplier    Int
+	HoundPreparingToAttackCooldown Int
+	HoundAttackCooldownMultiplier  Int
+	HoundHitCooldownDuration       Int
+	HoundHitsPlayer                bool
+	HoundAggroDistance             Int
+}
+
+type spawnPortalParams999 struct {
+	Pos                 Pt
+	SpawnPortalCooldown Int
+	Waves               struct {
+		N int64
+		V [10]Wave
+	}
+}
+
+type level999 struct {
+	WorldParams        worldParams999
+	Obstacles          MatBool
+	SpawnPortalsParams struct {
+		N int64
+		V [30]spawnPortalParams999
+	}
+}
+
+type playerInput999 struct {
+	MousePt            Pt
+	LeftButtonPressed  bool
+	RightButtonPressed bool
+	Move               bool
+	MovePt             Pt
+	Shoot              bool
+	ShootPt            Pt
+}
+
+func (l *level999) level() (n Level) {
+	w := &l.WorldParams
+	n.Boardgame = w.Boardgame
+	n.UseAmmo = w.UseAmmo
+	n.AmmoLimit = w.AmmoLimit
+	n.EnemyMoveCooldownDuration = w.EnemyMoveCooldownDuration
+	n.EnemiesAggroWhenVisible = w.EnemiesAggroWhenVisible
+	n.SpawnPortalCooldownMin = w.SpawnPortalCooldownMin
+	n.SpawnPortalCooldownMax = w.SpawnPortalCooldownMax
+	n.HoundMaxHealth = w.HoundMaxHealth
+	n.HoundMoveCooldownMultiplier = w.HoundMoveCooldownMultiplier
+	n.HoundPreparingToAttackCooldown = w.HoundPreparingToAttackCooldown
+	n.HoundAttackCooldownMultiplier = w.HoundAttackCooldownMultiplier
+	n.HoundHitCooldownDuration = w.HoundHitCooldownDuration
+	n.HoundHitsPlayer = w.HoundHitsPlayer
+	n.HoundAggroDistance = w.HoundAggroDistance
+	n.Obstacles = l.Obstacles
+	n.SpawnPortalsParams.N = l.SpawnPortalsParams.N
+	for i := range l.SpawnPortalsParams.V {
+		p := &l.SpawnPortalsParams.V[i]
+		n.SpawnPortalsParams.V[i] = SpawnPortalParams{
+			Pos:                 p.Pos,
+			SpawnPortalCooldown: p.SpawnPortalCooldown,
+			Waves:               WavesArray{p.Waves.N, p.Waves.V},
+		}
+	}
+	return
+}
+
+func (in *playerInput999) playerInput() PlayerInput {
+	return PlayerInput{
+		MousePt:            in.MousePt,
+		LeftButtonPressed:  in.LeftButtonPressed,
+		RightButtonPressed: in.RightButtonPressed,
+		Move:               in.Move,
+		MovePt:             in.MovePt,
+		Shoot:              in.Shoot,
+		ShootPt:            in.ShootPt,
+	}
+}
+
+// tryDeserializePlaythrough999 reads what follows the InputVersion in a
+// playthrough of version 999 and converts it to the current Playthrough.
+func tryDeserializePlaythrough999(buf *bytes.Buffer) (p Playthrough,
+	err error) {
+	var l level999
+	for _, field := range []any{&p.SimulationVersion, &p.ReleaseVersion, &l,
+		&p.Id, &p.Seed} {
+		if err = TryDeserialize(buf, field); err != nil {
+			return
+		}
+	}
+	var history []playerInput999
+	if err = TryDeserializeSlice(buf, &history); err != nil {
+		return
+	}
+	p.InputVersion = I(InputVersion)
+	p.Level = l.level()
+	p.History = make([]PlayerInput, len(history))
+	for i := range history {
+		p.History[i] = history[i].playerInput()
+	}
+	// Checksums is empty but not nil, like it would be if it was loaded, so
+	// that the playthrough is the same after saving and loading it.
+	p.Checksums = []uint32{}
+	return
+}
@@ -2,8 +2,11 @@ package world
 
 import (
 	"bytes"
+	"encoding/binary"
 	. "github.com/marisvali/miln/gamelib"
 	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"math"
 	"testing"
 )
 
@@ -86,3 +89,77 @@ func BenchmarkPlaythroughClone(b *testing.B) {
 		res += len(p2.History)
 	}
 }
+
+func TestTryDeserializePlaythrough(t *testing.T) {
+	_, err := TryReadFile("playthroughs/missing.mln1000-999")
+	var missing *MissingFileError
+	assert.ErrorAs(t, err, &missing)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	data := ReadFile("playthroughs/large-playthrough.mln999-999")
+	p, err := TryDeserializePlaythrough(data)
+	assert.Nil(t, err)
+	assert.Equal(t, DeserializePlaythrough(data), p)
+
+	var corrupt *CorruptDataError
+	_, err = TryDeserializePlaythrough([]byte("not a playthrough"))
+	assert.ErrorAs(t, err, &corrupt)
+
+	// Truncated right after the History.
+	raw := Unzip(p.Serialize())
+	_, err = TryDeserializePlaythrough(Zip(raw[:len(raw)-10]))
+	assert.ErrorAs(t, err, &corrupt)
+
+	// A slice length that doesn't match the data doesn't get allocated.
+	binary.LittleEndian.PutUint64(raw[len(raw)-8:], math.MaxInt64)
+	_, err = TryDeserializePlaythrough(Zip(raw))
+	assert.ErrorAs(t, err, &corrupt)
+
+	p.InputVersion = I(InputVersion + 1)
+	_, err = TryDeserializePlaythrough(p.Serialize())
+	var version *VersionError
+	assert.ErrorAs(t, err, &version)
+	assert.Equal(t, "InputVersion", version.What)
+	assert.Equal(t, int64(InputVersion+1), version.Found)
+
+	p.InputVersion = I(InputVersion)
+	p.SimulationVersion = I(SimulationVersion + 1)
+	_, err = TryNewWorldFromPlaythrough(p)
+	assert.ErrorAs(t, err, &version)
+	assert.Equal(t, "SimulationVersion", version.What)
+
+	// A corrupt N would make NewWorld index past the end of an array.
+	p.SimulationVersion = I(SimulationVersion)
+	p.Level.PickupsParams.N = int64(len(p.Level.PickupsParams.V) + 1)
+	_, err = TryNewWorldFromPlaythrough(p)
+	assert.ErrorAs(t, err, &corrupt)
+}
+
+// The playthroughs in the playthroughs folder were saved by the first
+// release, with InputVersion 999. They must stay as they are, so that this
+// test keeps checking that old playthroughs load.
+func TestDeserializePlaythrough_999(t *testing.T) {
+	data := ReadFile("playthroughs/large-playthrough.mln999-999")
+	var version Int
+	Deserialize(bytes.NewBuffer(Unzip(data)), &version)
+	assert.Equal(t, I(999), version)
+
+	p := DeserializePlaythrough(data)
+	assert.Equal(t, I(InputVersion), p.InputVersion)
+	assert.Equal(t, I(999), p.SimulationVersion)
+	assert.Positive(t, p.SpawnPortalsParams.N)
+	assert.Positive(t, p.HoundMaxHealth.ToInt64())
+	assert.Positive(t, p.Obstacles.ToArray().N)
+	assert.Positive(t, len(p.History))
+	for _, input := range p.History {
+		assert.False(t, input.Undo)
+	}
+	// What isn't in the old layout has its zero value.
+	assert.Equal(t, int64(0), p.Events.N)
+	assert.Equal(t, Terrain{}, p.Terrain)
+	assert.Equal(t, p, DeserializePlaythrough(p.Serialize()))
+
+	_, err := TryDeserializePlaythrough(Zip(Unzip(data)[:100]))
+	var corrupt *CorruptDataError
+	assert.ErrorAs(t, err, &corrupt)
+}
@@ -0,0 +1,179 @@
+package world
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/goccy/go-yaml"
+	"github.com/google/uuid"
+	. "github.com/marisvali/miln/gamelib"
+	"slices"
+	"time"
+)
+
+// A playthrough file is a header followed by the serialized Playthrough:
+// - playthroughFileMagic
+// - playthroughFileVersion (int64)
+// - the PlaythroughHeader as YAML (int64 length, then the bytes)
+// - Playthrough.Serialize()
+// The header describes the playthrough without having to unzip it and replay
+// it, and keeps what the database knows about it (user, start and end time)
+// once it is downloaded.
+// Files without the header (raw Playthrough.Serialize()) are still valid,
+// TryDeserializePlaythrough reads both. A raw file starts with the magic of a
+// zip archive, so it can't be confused with a playthrough file.
+var playthroughFileMagic = [4]byte{'M', 'L', 'N', 'P'}
+
+// playthroughFileVersion must change when the layout above changes. Adding
+// fields to PlaythroughHeader doesn't change it, YAML handles missing fields.
+const playthroughFileVersion = 1
+
+// PlaythroughHeader is the metadata saved in front of a playthrough.
+type PlaythroughHeader struct {
+	InputVersion      int64     `yaml:"InputVersion"`
+	SimulationVersion int64     `yaml:"SimulationVersion"`
+	ReleaseVersion    int64     `yaml:"ReleaseVersion"`
+	Id                uuid.UUID `yaml:"Id"`
+	User              string    `yaml:"User"`
+	// UTC times at which the level started and at which the playthrough was
+	// saved.
+	Start time.Time `yaml:"Start"`
+	End   time.Time `yaml:"End"`
+	// See ReleaseFingerprint.
+	ReleaseFingerprint string `yaml:"ReleaseFingerprint"`
+	// See LevelHash.
+	LevelHash string `yaml:"LevelHash"`
+	// "ongoing", "won" or "lost", empty if unknown.
+	Status string   `yaml:"Status"`
+	Frames int64    `yaml:"Frames"`
+	Tags   []string `yaml:"Tags,omitempty"`
+}
+
+// NewPlaythroughHeader fills in what can be taken from a playthrough and the
+// World it led to. w can be nil, if the World isn't at hand (then the Status
+// is left empty). The End is now, the rest is up to the caller.
+func NewPlaythroughHeader(p *Playthrough, w *World) (h PlaythroughHeader) {
+	h.InputVersion = p.InputVersion.ToInt64()
+	h.SimulationVersion = p.SimulationVersion.ToInt64()
+	h.ReleaseVersion = p.ReleaseVersion.ToInt64()
+	h.Id = p.Id
+	h.End = time.Now().UTC()
+	h.LevelHash = LevelHash(p.Level)
+	h.Frames = int64(len(p.History))
+	if w != nil {
+		h.Status = w.Status().String()
+	}
+	return
+}
+
+// LevelHash identifies a Level by its contents, so that playthroughs of the
+// same level can be found even if the level was played from different files
+// or generated again with the same seed.
+func LevelHash(l Level) string {
+	buf := new(bytes.Buffer)
+	Serialize(buf, l)
+	hash := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(hash[:])
+}
+
+// ReleaseFingerprint identifies the data a release was built with: the fixed
+// levels and the GUI settings. Two releases with the same ReleaseVersion but
+// different fingerprints were built from different data (which they
+// shouldn't be). Files that don't exist in fsys are left out.
+func ReleaseFingerprint(fsys FS) string {
+	var files []string
+	if FileExists(fsys, "data/levels") {
+		files = GetFiles(fsys, "data/levels", "*")
+	}
+	files = append(files, "data/gui/gui.yaml")
+	slices.Sort(files)
+
+	hash := sha256.New()
+	for _, name := range files {
+		data, err := TryReadFileFS(fsys, name)
+		if err != nil {
+			continue
+		}
+		hash.Write([]byte(name))
+		hash.Write(data)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// SerializePlaythroughFile returns the header followed by the playthrough.
+func SerializePlaythroughFile(h PlaythroughHeader, p *Playthrough) []byte {
+	return serializePlaythroughFile(h, p.Serialize())
+}
+
+func serializePlaythroughFile(h PlaythroughHeader, body []byte) []byte {
+	header, err := yaml.Marshal(h)
+	Check(err)
+	buf := new(bytes.Buffer)
+	Serialize(buf, playthroughFileMagic)
+	Serialize(buf, int64(playthroughFileVersion))
+	SerializeSlice(buf, header)
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// AddPlaythroughHeader puts a header in front of data that is a raw
+// playthrough. Data that already has a header is returned as it is.
+func AddPlaythroughHeader(h PlaythroughHeader, data []byte) []byte {
+	if HasPlaythroughHeader(data) {
+		return data
+	}
+	return serializePlaythroughFile(h, data)
+}
+
+// HasPlaythroughHeader tells a playthrough file from a raw playthrough.
+func HasPlaythroughHeader(data []byte) bool {
+	return bytes.HasPrefix(data, playthroughFileMagic[:])
+}
+
+// splitPlaythroughFile returns the header and the serialized Playthrough.
+func splitPlaythroughFile(data []byte) (h PlaythroughHeader, body []byte,
+	err error) {
+	if !HasPlaythroughHeader(data) {
+		err = &CorruptDataError{errors.New("no playthrough header")}
+		return
+	}
+	buf := bytes.NewBuffer(data[len(playthroughFileMagic):])
+	var version int64
+	if err = TryDeserialize(buf, &version); err != nil {
+		return
+	}
+	if version != playthroughFileVersion {
+		err = &VersionError{"PlaythroughFileVersion", version,
+			playthroughFileVersion}
+		return
+	}
+	var header []byte
+	if err = TryDeserializeSlice(buf, &header); err != nil {
+		return
+	}
+	if err = yaml.Unmarshal(header, &h); err != nil {
+		err = &CorruptDataError{err}
+		return
+	}
+	body = buf.Bytes()
+	return
+}
+
+// TryReadPlaythroughHeader returns the header without decoding the
+// playthrough, which is much faster for tools that only look at the headers.
+// For a raw playthrough it decodes the playthrough and builds a header with
+// what can be found in it (no user, times or status).
+func TryReadPlaythroughHeader(data []byte) (h PlaythroughHeader, err error) {
+	if HasPlaythroughHeader(data) {
+		h, _, err = splitPlaythroughFile(data)
+		return
+	}
+	p, err := TryDeserializePlaythrough(data)
+	if err != nil {
+		return
+	}
+	h = NewPlaythroughHeader(&p, nil)
+	h.End = time.Time{}
+	return
+}
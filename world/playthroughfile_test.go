@@ -0,0 +1,63 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPlaythroughFile(t *testing.T) {
+	raw := ReadFile("playthroughs/average-playthrough.mln999-999")
+	p := DeserializePlaythrough(raw)
+	w := NewWorldFromPlaythrough(p)
+	for i := range p.History {
+		p.StepFrame(&w, i)
+	}
+
+	h := NewPlaythroughHeader(&p, &w)
+	h.User = "vali-dev"
+	h.Start = time.Date(2025, 5, 11, 9, 16, 15, 0, time.UTC)
+	h.End = h.Start.Add(time.Minute)
+	h.ReleaseFingerprint = ReleaseFingerprint(os.DirFS("..").(FS))
+	h.Tags = []string{"test"}
+	assert.Equal(t, int64(len(p.History)), h.Frames)
+	assert.Equal(t, w.Status().String(), h.Status)
+	assert.Equal(t, LevelHash(p.Level), h.LevelHash)
+
+	data := SerializePlaythroughFile(h, &p)
+	assert.True(t, HasPlaythroughHeader(data))
+	assert.False(t, HasPlaythroughHeader(raw))
+	assert.Equal(t, p, DeserializePlaythrough(data))
+	read, err := TryReadPlaythroughHeader(data)
+	assert.Nil(t, err)
+	assert.Equal(t, h, read)
+
+	// Adding a header to a raw playthrough gives the same file.
+	assert.Equal(t, data, AddPlaythroughHeader(h, p.Serialize()))
+	assert.Equal(t, data, AddPlaythroughHeader(h, data))
+
+	// Raw playthroughs get a header with what is in the playthrough.
+	read, err = TryReadPlaythroughHeader(raw)
+	assert.Nil(t, err)
+	assert.Equal(t, h.Id, read.Id)
+	assert.Equal(t, h.LevelHash, read.LevelHash)
+	assert.Equal(t, "", read.User)
+	assert.Equal(t, "", read.Status)
+
+	// A file from a newer build.
+	data[len(playthroughFileMagic)] = 2
+	_, err = TryReadPlaythroughHeader(data)
+	var version *VersionError
+	assert.ErrorAs(t, err, &version)
+	_, err = TryDeserializePlaythrough(data)
+	assert.ErrorAs(t, err, &version)
+}
+
+func TestReleaseFingerprint(t *testing.T) {
+	fsys := os.DirFS("..").(FS)
+	assert.Equal(t, ReleaseFingerprint(fsys), ReleaseFingerprint(fsys))
+	assert.NotEqual(t, ReleaseFingerprint(fsys),
+		ReleaseFingerprint(os.DirFS(".").(FS)))
+}
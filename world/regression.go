@@ -16,6 +16,7 @@ func (w *World) State() []byte {
 	// - player pos and health
 	// - enemy types, positions and health
 	// - obstacles
+	// - terrain, with the health of the crates
 	//
 	// Explanation:
 	//
@@ -81,6 +82,26 @@ func (w *World) State() []byte {
 	}
 	arr := w.Obstacles.ToArray()
 	Serialize(buf, arr.V[:arr.N])
+
+	// The terrain comes last and only if there is some, so that levels
+	// without terrain keep the State (and the RegressionId) they had before
+	// terrain existed. It is every tile that isn't Ground (position, type,
+	// crate health) followed by the number of such tiles. See DecodeState.
+	var nTerrain int64
+	var pt Pt
+	for pt.Y = ZERO; pt.Y.Lt(I(NRows)); pt.Y.Inc() {
+		for pt.X = ZERO; pt.X.Lt(I(NCols)); pt.X.Inc() {
+			if w.Terrain.Get(pt) != Ground {
+				Serialize(buf, pt)
+				Serialize(buf, w.Terrain.Get(pt))
+				Serialize(buf, w.CrateHealth.Get(pt))
+				nTerrain++
+			}
+		}
+	}
+	if nTerrain > 0 {
+		Serialize(buf, nTerrain)
+	}
 	return buf.Bytes()
 }
 
@@ -137,7 +158,7 @@ func RegressionId(p *Playthrough) string {
 	hash.Write(w.State())
 
 	for i := range p.History {
-		w.Step(p.History[i])
+		p.StepFrame(&w, i)
 
 		// Write the current state of the World to the hash.
 		hash.Write(w.State())
@@ -0,0 +1,420 @@
+package world
+
+import (
+	"fmt"
+	"github.com/goccy/go-yaml"
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// A scenario is a small test of the simulation, written in a way that shows
+// what the test is about at a glance:
+//
+//	s := scenario{
+//		board: `
+//			P.......
+//			.#......
+//			...H.... | Attacking attack=1
+//			........`,
+//		params: `HoundHitsPlayer: false`,
+//		script: `at 0 expect hound 3,2 Attacking; at 10 shoot 3,2`,
+//	}
+//	s.run(t)
+//
+// The board is the map, one row per line, one character per tile:
+//   - '.' ground, '#' tree, 'B', 'R', 'M', 'C' the terrain types (see
+//     terrainTypeChar)
+//   - 'P' the player, already on the map
+//   - 'H' a hound
+//   - 'A' one piece of ammo
+//   - 'S' a spawn portal without waves
+//
+// Rows that have hounds can be annotated after a '|', with one annotation
+// per hound, from left to right, separated by commas. An annotation is the
+// state of the hound followed by any of health=N, move=N, prepare=N, attack=N,
+// hit=N (the cooldowns of the states) and target=X,Y (where a searching hound
+// walks to). A hound with a cooldown set starts in the middle of its state,
+// otherwise it enters the state at the first step, like a spawned hound does.
+//
+// The params are YAML for WorldParams and override scenarioParams.
+//
+// The script is a list of commands separated by ';' or new lines. Each one
+// starts with "at F", where F is the index of the input it refers to. Frames
+// without commands are idle. A command is one of:
+//   - move X,Y
+//   - shoot X,Y
+//   - expect player X,Y / expect player off
+//   - expect health N
+//   - expect ammo N
+//   - expect hound X,Y [State]
+//   - expect nohound X,Y
+//   - expect hounds N
+//   - expect status Ongoing/Won/Lost
+//
+// Expectations are checked on the World after the input at frame F was
+// stepped.
+type scenario struct {
+	board  string
+	params string
+	script string
+	seed   int
+}
+
+// scenarioParams makes hounds react quickly, so that scenarios can be short.
+const scenarioParams = `
+AmmoLimit: 10
+EnemyMoveCooldownDuration: 10
+EnemiesAggroWhenVisible: true
+HoundMaxHealth: 3
+HoundMoveCooldownMultiplier: 1
+HoundPreparingToAttackCooldown: 10
+HoundAttackCooldownMultiplier: 1
+HoundHitCooldownDuration: 10
+HoundHitsPlayer: true
+CrateMaxHealth: 3
+`
+
+func parsePt(t *testing.T, s string) Pt {
+	var x, y int
+	_, err := fmt.Sscanf(s, "%d,%d", &x, &y)
+	if err != nil {
+		t.Fatalf("bad position %q: %v", s, err)
+	}
+	return IPt(x, y)
+}
+
+func parseInt(t *testing.T, s string) Int {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatalf("bad number %q: %v", s, err)
+	}
+	return I(v)
+}
+
+func parseHoundState(t *testing.T, s string) HoundState {
+	for k, v := range enemyStateName {
+		if v == s {
+			return k
+		}
+	}
+	t.Fatalf("unknown hound state %q", s)
+	return Searching
+}
+
+func parseTerrainType(c rune) (TerrainType, bool) {
+	for k, v := range terrainTypeChar {
+		if v == string(c) {
+			return k, true
+		}
+	}
+	return Ground, false
+}
+
+// annotateHound applies an annotation like "Attacking attack=1 health=2".
+func annotateHound(t *testing.T, h *Hound, annotation string) {
+	fields := strings.Fields(annotation)
+	if len(fields) == 0 {
+		return
+	}
+	h.state = parseHoundState(t, fields[0])
+	pinned := false
+	for _, f := range fields[1:] {
+		key, value, found := strings.Cut(f, "=")
+		if !found {
+			t.Fatalf("bad hound annotation %q", f)
+		}
+		switch key {
+		case "health":
+			h.health = parseInt(t, value)
+		case "move":
+			h.moveCooldownIdx = parseInt(t, value)
+			pinned = true
+		case "prepare":
+			h.preparingToAttackCooldownIdx = parseInt(t, value)
+			pinned = true
+		case "attack":
+			h.attackCooldownIdx = parseInt(t, value)
+			pinned = true
+		case "hit":
+			h.hitCooldownIdx = parseInt(t, value)
+			pinned = true
+		case "target":
+			h.randomTarget = parsePt(t, value)
+			pinned = true
+		default:
+			t.Fatalf("unknown hound annotation %q", key)
+		}
+	}
+	if pinned {
+		// Don't let the state reset what was set here.
+		h.solvedFirstState = true
+		h.previousState = h.state
+	}
+}
+
+// world builds the World described by the board and the params.
+func (s *scenario) world(t *testing.T) (w World) {
+	var l Level
+	Check(yaml.Unmarshal([]byte(scenarioParams), &l.WorldParams))
+	Check(yaml.Unmarshal([]byte(s.params), &l.WorldParams))
+
+	type houndSetup struct {
+		pos        Pt
+		annotation string
+	}
+	var hounds []houndSetup
+	var ammos []Pt
+	var player *Pt
+
+	y := 0
+	for _, line := range strings.Split(s.board, "\n") {
+		row, annotations, _ := strings.Cut(line, "|")
+		row = strings.TrimSpace(row)
+		if row == "" {
+			continue
+		}
+		if y >= NRows || len(row) > NCols {
+			t.Fatalf("the board is larger than %dx%d", NCols, NRows)
+		}
+		var rowAnnotations []string
+		if strings.TrimSpace(annotations) != "" {
+			rowAnnotations = strings.Split(annotations, ",")
+		}
+		for x, c := range row {
+			pos := IPt(x, y)
+			switch c {
+			case '.':
+			case '#':
+				l.Obstacles.Set(pos)
+			case 'P':
+				player = &pos
+			case 'H':
+				var annotation string
+				if len(rowAnnotations) > 0 {
+					annotation = rowAnnotations[0]
+					rowAnnotations = rowAnnotations[1:]
+				}
+				hounds = append(hounds, houndSetup{pos, annotation})
+			case 'A':
+				ammos = append(ammos, pos)
+			case 'S':
+				l.SpawnPortalsParams.V[l.SpawnPortalsParams.N].Pos = pos
+				l.SpawnPortalsParams.N++
+			default:
+				terrain, ok := parseTerrainType(c)
+				if !ok {
+					t.Fatalf("unknown board character %q", c)
+				}
+				l.Terrain.Set(pos, terrain)
+			}
+		}
+		if len(rowAnnotations) > 0 {
+			t.Fatalf("more annotations than hounds in row %d", y)
+		}
+		y++
+	}
+
+	w = NewWorld(I(s.seed), l)
+	if player != nil {
+		w.Player.pos = *player
+		w.Player.OnMap = true
+		w.Player.EnteredMap = true
+	}
+	for i, h := range hounds {
+		w.Enemies.V[i] = NewHound(I(i), w.WorldParams, h.pos)
+		annotateHound(t, &w.Enemies.V[i], h.annotation)
+	}
+	w.Enemies.N = int64(len(hounds))
+	for i, pos := range ammos {
+		w.Ammos.V[i] = Ammo{Pos: pos, Count: ONE}
+	}
+	w.Ammos.N = int64(len(ammos))
+	w.computeVisibleTiles()
+	return
+}
+
+type scenarioCommand struct {
+	frame int
+	words []string
+	text  string
+}
+
+func (s *scenario) commands(t *testing.T) (commands []scenarioCommand) {
+	script := strings.ReplaceAll(s.script, "\n", ";")
+	for _, text := range strings.Split(script, ";") {
+		words := strings.Fields(text)
+		if len(words) == 0 {
+			continue
+		}
+		if len(words) < 3 || words[0] != "at" {
+			t.Fatalf("bad command %q", text)
+		}
+		frame := parseInt(t, words[1]).ToInt()
+		commands = append(commands, scenarioCommand{frame, words[2:],
+			strings.TrimSpace(text)})
+	}
+	return
+}
+
+// expect checks one expectation of the script against the World.
+func expect(t *testing.T, w *World, c scenarioCommand) {
+	msg := c.text
+	words := c.words[1:]
+	if len(words) < 2 {
+		t.Fatalf("bad expectation %q", c.text)
+	}
+	switch words[0] {
+	case "player":
+		if words[1] == "off" {
+			assert.False(t, w.Player.OnMap, msg)
+		} else {
+			assert.True(t, w.Player.OnMap, msg)
+			assert.Equal(t, parsePt(t, words[1]), w.Player.Pos(), msg)
+		}
+	case "health":
+		assert.Equal(t, parseInt(t, words[1]), w.Player.Health, msg)
+	case "ammo":
+		assert.Equal(t, parseInt(t, words[1]), w.Player.AmmoCount, msg)
+	case "hound":
+		pos := parsePt(t, words[1])
+		var states []string
+		for i := range w.Enemies.N {
+			if w.Enemies.V[i].Pos() == pos {
+				states = append(states, w.Enemies.V[i].State())
+			}
+		}
+		if assert.NotEmpty(t, states, msg) && len(words) > 2 {
+			assert.Contains(t, states, words[2], msg)
+		}
+	case "nohound":
+		hounds := w.EnemyPositions()
+		assert.False(t, hounds.At(parsePt(t, words[1])), msg)
+	case "hounds":
+		assert.Equal(t, parseInt(t, words[1]).ToInt64(), w.Enemies.N, msg)
+	case "status":
+		status := map[string]WorldStatus{
+			"Ongoing": Ongoing, "Won": Won, "Lost": Lost}
+		expected, ok := status[words[1]]
+		if !ok {
+			t.Fatalf("unknown status %q", words[1])
+		}
+		assert.Equal(t, expected, w.Status(), msg)
+	default:
+		t.Fatalf("bad expectation %q", c.text)
+	}
+}
+
+// run builds the World, steps it through the script and checks the
+// expectations.
+func (s *scenario) run(t *testing.T) (w World) {
+	w = s.world(t)
+	commands := s.commands(t)
+	nFrames := 0
+	for _, c := range commands {
+		nFrames = max(nFrames, c.frame+1)
+	}
+
+	for frame := range nFrames {
+		var input PlayerInput
+		for _, c := range commands {
+			if c.frame != frame || c.words[0] == "expect" {
+				continue
+			}
+			if len(c.words) != 2 {
+				t.Fatalf("bad command %q", c.text)
+			}
+			switch c.words[0] {
+			case "move":
+				input.Move = true
+				input.MovePt = parsePt(t, c.words[1])
+			case "shoot":
+				input.Shoot = true
+				input.ShootPt = parsePt(t, c.words[1])
+			default:
+				t.Fatalf("bad command %q", c.text)
+			}
+		}
+		w.Step(input)
+		for _, c := range commands {
+			if c.frame == frame && c.words[0] == "expect" {
+				expect(t, &w, c)
+			}
+		}
+	}
+	return
+}
+
+func TestScenario_Board(t *testing.T) {
+	s := scenario{
+		board: `
+			P.......
+			.#......
+			...H..H. | Attacking attack=1 health=2, Hit
+			.A..M...
+			.......S`,
+	}
+	w := s.world(t)
+	assert.True(t, w.Player.OnMap)
+	assert.Equal(t, IPt(0, 0), w.Player.Pos())
+	assert.True(t, w.Obstacles.At(IPt(1, 1)))
+	assert.Equal(t, Mud, w.Terrain.Get(IPt(4, 3)))
+	assert.Equal(t, int64(1), w.Ammos.N)
+	assert.Equal(t, IPt(1, 3), w.Ammos.V[0].Pos)
+	assert.Equal(t, int64(1), w.SpawnPortals.N)
+	assert.Equal(t, IPt(7, 4), w.SpawnPortals.V[0].Pos())
+	assert.Equal(t, int64(2), w.Enemies.N)
+	assert.Equal(t, "Attacking", w.Enemies.V[0].State())
+	assert.Equal(t, I(2), w.Enemies.V[0].Health())
+	assert.Equal(t, I(1), w.Enemies.V[0].AttackCooldownIdx())
+	assert.Equal(t, IPt(6, 2), w.Enemies.V[1].Pos())
+	assert.Equal(t, "Hit", w.Enemies.V[1].State())
+}
+
+func TestScenario_HoundNoticesPlayer(t *testing.T) {
+	s := scenario{
+		board: `
+			P.......
+			........
+			...H....`,
+		script: `
+			at 0 expect hound 3,2 PreparingToAttack
+			at 10 expect hound 3,2 Attacking`,
+	}
+	s.run(t)
+}
+
+func TestScenario_AttackingHoundHitsPlayer(t *testing.T) {
+	// The enemies move at the first step, so the hound gets to the player
+	// right away.
+	s := scenario{
+		board: `
+			P.......
+			.H...... | Attacking attack=1`,
+		script: `
+			at 0 expect health 2
+			at 0 expect player off
+			at 0 expect hound 0,0 Attacking
+			at 1 expect hound 0,0 Searching`,
+	}
+	s.run(t)
+}
+
+func TestScenario_TreeBlocksShot(t *testing.T) {
+	s := scenario{
+		board: `
+			P#H..... | Searching move=5`,
+		params: `HoundMaxHealth: 1`,
+		script: `
+			at 0 shoot 2,0
+			at 0 expect hound 2,0 Searching
+			at 1 move 1,1
+			at 1 expect hound 2,0 PreparingToAttack
+			at 2 shoot 2,0
+			at 2 expect hounds 0
+			at 2 expect status Won`,
+	}
+	s.run(t)
+}
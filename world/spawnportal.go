@@ -19,20 +19,43 @@ type SpawnPortal struct {
 	Waves         WavesArray
 	frameIdx      Int
 	worldParams   WorldParams
+	Inactive      bool // an inactive portal doesn't spawn its waves
+	Destructible  bool // the beam damages it, see HitSpawnPortal
+	Pending       PendingSpawnsArray
+	nWavesStarted int64
 }
 
 func NewSpawnPortal(seed Int, p SpawnPortalParams, w WorldParams) (sp SpawnPortal) {
 	sp.RSeed(seed)
 	sp.pos = p.Pos
 	sp.MaxHealth = I(1)
+	if p.Health.IsPositive() {
+		sp.MaxHealth = p.Health
+		sp.Destructible = true
+	}
 	sp.Health = sp.MaxHealth
 	sp.SpawnCooldown = NewCooldown(p.SpawnPortalCooldown)
 	sp.Waves = p.Waves
 	sp.worldParams = w
+	sp.Inactive = p.Inactive
 	return
 }
 
 func (p *SpawnPortal) CurrentWave() *Wave {
+	idx := p.currentWaveIdx()
+	if idx < 0 {
+		return nil
+	}
+	return &p.Waves.V[idx]
+}
+
+// currentWaveIdx returns the index of the active wave or -1 if there is none.
+func (p *SpawnPortal) currentWaveIdx() int64 {
+	if p.Waves.N == 0 {
+		// Only events spawn hounds out of this portal.
+		return -1
+	}
+
 	// Compute the frame at which each wave starts.
 	waveStarts := [100]Int{}
 	startOfLastWave := ZERO
@@ -52,26 +75,30 @@ func (p *SpawnPortal) CurrentWave() *Wave {
 
 	if p.frameIdx.Lt(waveStarts[0]) {
 		// No wave has started yet.
-		return nil
+		return -1
 	}
 
 	if p.frameIdx.Geq(waveStarts[waveStartsLen-1]) {
 		// The last wave is active.
-		return &p.Waves.V[waveStartsLen-1]
+		return waveStartsLen - 1
 	}
 
 	for i := range waveStartsLen {
 		if p.frameIdx.Lt(waveStarts[i]) {
-			return &p.Waves.V[i-1]
+			return i - 1
 		}
 	}
 
 	Check(fmt.Errorf("should never get here"))
-	return nil
+	return -1
 }
 
 func (p *SpawnPortal) Step(w *World) {
 	p.frameIdx.Inc()
+	if idx := p.currentWaveIdx(); !p.Inactive && idx >= p.nWavesStarted {
+		p.nWavesStarted = idx + 1
+		w.emit(WaveStarted, p.pos, p.Waves.V[idx].NHounds)
+	}
 	p.SpawnCooldown.Update()
 	if !p.SpawnCooldown.Ready() {
 		return // Don't spawn.
@@ -81,6 +108,23 @@ func (p *SpawnPortal) Step(w *World) {
 		return // Only spawn when the enemy cooldown is ready.
 	}
 
+	// Hounds sent by the level's events come before the regular waves.
+	if p.Pending.N > 0 {
+		pending := &p.Pending.V[0]
+		p.spawnHound(w, pending.WorldParams)
+		pending.NHounds.Dec()
+		if !pending.NHounds.IsPositive() {
+			copy(p.Pending.V[:], p.Pending.V[1:p.Pending.N])
+			p.Pending.N--
+		}
+		p.SpawnCooldown.Reset()
+		return
+	}
+
+	if p.Inactive {
+		return
+	}
+
 	wave := p.CurrentWave()
 	if wave == nil {
 		// No wave active.
@@ -88,15 +132,88 @@ func (p *SpawnPortal) Step(w *World) {
 	}
 
 	if wave.NHounds.IsPositive() {
-		w.Enemies.V[w.Enemies.N] = NewHound(p.RInt63(), p.worldParams, p.pos)
-		w.Enemies.N++
+		p.spawnHound(w, p.worldParams)
 		wave.NHounds.Dec()
 	}
 
 	p.SpawnCooldown.Reset()
 }
 
+func (p *SpawnPortal) spawnHound(w *World, params WorldParams) {
+	if w.Enemies.N == int64(len(w.Enemies.V)) {
+		// Too many hounds on the map already.
+		return
+	}
+	w.Enemies.V[w.Enemies.N] = NewHound(p.RInt63(), params, p.pos)
+	w.emit(EnemySpawned, p.pos, ZERO)
+	w.markTarget(&w.Enemies.V[w.Enemies.N], p.pos)
+	w.Enemies.N++
+}
+
+// addPending queues hounds that come out one at a time, before the waves of
+// the portal.
+func (p *SpawnPortal) addPending(n Int, params WorldParams) {
+	if p.Pending.N < int64(len(p.Pending.V)) {
+		p.Pending.V[p.Pending.N] = PendingSpawn{n, params}
+		p.Pending.N++
+	}
+}
+
+// pendingHounds returns how many hounds the portal still has to send out
+// because of SpawnWave events.
+func (p *SpawnPortal) pendingHounds() (n Int) {
+	for i := range p.Pending.N {
+		n.Add(Max(p.Pending.V[i].NHounds, ZERO))
+	}
+	return
+}
+
+// waveHoundsLeft returns how many hounds the portal's waves can still spawn.
+// The hounds of a wave that ended before spawning them all never spawn.
+func (p *SpawnPortal) waveHoundsLeft() (n Int) {
+	for i := max(p.currentWaveIdx(), 0); i < p.Waves.N; i++ {
+		n.Add(Max(p.Waves.V[i].NHounds, ZERO))
+	}
+	return
+}
+
+// HitSpawnPortal damages the spawn portal at pos, if there is one and it can
+// be destroyed. It returns true if there was a portal to hit.
+func (w *World) HitSpawnPortal(pos Pt) bool {
+	i := w.spawnPortalIdx(pos)
+	if i < 0 || !w.SpawnPortals.V[i].Destructible {
+		return false
+	}
+	p := &w.SpawnPortals.V[i]
+	p.Health.Subtract(Min(w.Player.BeamDamage(), p.Health))
+	return true
+}
+
+// cullSpawnPortals removes the portals that were destroyed and remembers
+// where they were.
+func (w *World) cullSpawnPortals() {
+	n := int64(0)
+	for i := range w.SpawnPortals.N {
+		p := &w.SpawnPortals.V[i]
+		if p.Health.IsPositive() {
+			w.SpawnPortals.V[n] = *p
+			n++
+		} else {
+			w.DestroyedPortals.Set(p.pos)
+			w.emit(SpawnPortalDestroyed, p.pos, ZERO)
+		}
+	}
+	w.SpawnPortals.N = n
+}
+
 func (p *SpawnPortal) Active() bool {
+	if p.Pending.N > 0 {
+		return true
+	}
+	if p.Inactive || p.Waves.N == 0 {
+		return false
+	}
+
 	wave := p.CurrentWave()
 	if wave != &p.Waves.V[p.Waves.N-1] {
 		// We are not at the last wave yet.
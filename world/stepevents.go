@@ -0,0 +1,115 @@
+package world
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+)
+
+// StepEventType says what happened during a World.Step.
+// Step events are not to be confused with the level's Events, which are
+// scripted by the level designer. Step events are just a record of what the
+// simulation did, so that the GUI, the AI and the analysis tools don't have to
+// find out by comparing the World before and after a step.
+type StepEventType int64
+
+const (
+	PlayerMoved          StepEventType = iota // Pos is where the player moved to
+	ShotFired                                 // Pos is the tile that was shot at
+	ShotMissed                                // Pos is the tile that was shot at, it had nothing to hit
+	EnemyHit                                  // Pos is the enemy, Amount is the damage
+	EnemyKilled                               // Pos is where the enemy died
+	EnemySpawned                              // Pos is the spawn portal
+	PlayerHit                                 // Pos is where the player was hit
+	AmmoCollected                             // Pos is the ammo, Amount is the ammo count
+	WaveStarted                               // Pos is the spawn portal, Amount is the number of hounds
+	SpawnPortalDestroyed                      // Pos is where the spawn portal was
+	LevelWon
+	LevelLost
+)
+
+var stepEventTypeName = map[StepEventType]string{
+	PlayerMoved:          "PlayerMoved",
+	ShotFired:            "ShotFired",
+	ShotMissed:           "ShotMissed",
+	EnemyHit:             "EnemyHit",
+	EnemyKilled:          "EnemyKilled",
+	EnemySpawned:         "EnemySpawned",
+	PlayerHit:            "PlayerHit",
+	AmmoCollected:        "AmmoCollected",
+	WaveStarted:          "WaveStarted",
+	SpawnPortalDestroyed: "SpawnPortalDestroyed",
+	LevelWon:             "LevelWon",
+	LevelLost:            "LevelLost",
+}
+
+type StepEvent struct {
+	Type   StepEventType
+	Pos    Pt
+	Amount Int
+}
+
+// StepEventsArray holds the events of a single World.Step. It is a fixed
+// array so that recording events doesn't allocate anything. If more events
+// happen in a step than fit in the array, the last ones are dropped and
+// NDropped counts them.
+type StepEventsArray struct {
+	N        int64
+	V        [100]StepEvent
+	NDropped int64
+}
+
+func (t StepEventType) String() string {
+	return stepEventTypeName[t]
+}
+
+// Contains returns true if at least one event of type t happened.
+func (a *StepEventsArray) Contains(t StepEventType) bool {
+	return a.Count(t) > 0
+}
+
+// Count returns how many events of type t happened.
+func (a *StepEventsArray) Count(t StepEventType) (n int64) {
+	for i := range a.N {
+		if a.V[i].Type == t {
+			n++
+		}
+	}
+	return
+}
+
+func (a *StepEventsArray) String() string {
+	var str string
+	for i := range a.N {
+		e := a.V[i]
+		str += fmt.Sprintf("%s %02d %02d %d  ", e.Type, e.Pos.X.ToInt(),
+			e.Pos.Y.ToInt(), e.Amount.ToInt())
+	}
+	return str
+}
+
+// emit records that something happened during the current step.
+func (w *World) emit(t StepEventType, pos Pt, amount Int) {
+	if w.StepEvents.N == int64(len(w.StepEvents.V)) {
+		w.StepEvents.NDropped++
+		return
+	}
+	w.StepEvents.V[w.StepEvents.N] = StepEvent{t, pos, amount}
+	w.StepEvents.N++
+}
+
+// emitLevelEnd records the step in which the level was won or lost. It only
+// happens once, even if the World keeps being stepped afterwards.
+func (w *World) emitLevelEnd() {
+	if w.levelEnded {
+		return
+	}
+	switch w.Status() {
+	case Won:
+		w.emit(LevelWon, w.Player.Pos(), ZERO)
+	case Lost:
+		w.emit(LevelLost, w.Player.Pos(), ZERO)
+	default:
+		return
+	}
+	w.levelEnded = true
+}
@@ -0,0 +1,62 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWorld_StepEvents(t *testing.T) {
+	var l Level
+	l.HoundMaxHealth = ONE
+	l.UseAmmo = true
+	l.AmmoLimit = I(5)
+	l.AmmoParams.Strategy = AmmoAtSpawnPoints
+	l.AmmoParams.SpawnPoints.N = 1
+	l.AmmoParams.SpawnPoints.V[0] = AmmoSpawnPoint{IPt(2, 2), I(3)}
+	l.SpawnPortalsParams.N = 1
+	l.SpawnPortalsParams.V[0].Pos = IPt(6, 6)
+	l.Events.N = 1
+	l.Events.V[0] = LevelEvent{Trigger: AtStart, Action: SpawnWave,
+		Portal: IPt(6, 6), NHounds: I(1), Burst: true}
+	w := NewWorld(I(0), l)
+
+	w.Step(PlayerInput{})
+	assert.Equal(t, int64(1), w.StepEvents.Count(WaveStarted))
+	assert.Equal(t, int64(1), w.StepEvents.Count(EnemySpawned))
+
+	// Events only describe the last step.
+	w.Step(PlayerInput{})
+	assert.Equal(t, int64(0), w.StepEvents.N)
+
+	w.Step(PlayerInput{Move: true, MovePt: IPt(2, 2)})
+	assert.True(t, w.StepEvents.Contains(PlayerMoved))
+	assert.True(t, w.StepEvents.Contains(AmmoCollected))
+	assert.Equal(t, IPt(2, 2), w.StepEvents.V[0].Pos)
+
+	w.Step(PlayerInput{Shoot: true, ShootPt: IPt(3, 3)})
+	assert.True(t, w.StepEvents.Contains(ShotMissed))
+	assert.False(t, w.StepEvents.Contains(ShotFired))
+	assert.Equal(t, I(3), w.Player.AmmoCount)
+
+	w.Step(PlayerInput{Shoot: true, ShootPt: w.Enemies.V[0].Pos()})
+	assert.True(t, w.StepEvents.Contains(ShotFired))
+	assert.False(t, w.StepEvents.Contains(ShotMissed))
+	assert.True(t, w.StepEvents.Contains(EnemyHit))
+	assert.True(t, w.StepEvents.Contains(EnemyKilled))
+	assert.True(t, w.StepEvents.Contains(LevelWon))
+
+	// The end of the level is only reported once.
+	w.Step(PlayerInput{})
+	assert.False(t, w.StepEvents.Contains(LevelWon))
+}
+
+func TestWorld_EmitDoesNotAllocate(t *testing.T) {
+	w := &World{}
+	allocs := testing.AllocsPerRun(1000, func() {
+		w.emit(PlayerMoved, IPt(1, 1), ZERO)
+	})
+	assert.Equal(t, 0.0, allocs)
+	assert.Equal(t, int64(len(w.StepEvents.V)), w.StepEvents.N)
+	assert.Positive(t, w.StepEvents.NDropped)
+}
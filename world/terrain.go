@@ -0,0 +1,168 @@
+package world
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	"strings"
+)
+
+// TerrainType is an int64 and not an int because it is part of the Level,
+// which gets serialized using encoding/binary, which only accepts fixed-size
+// values.
+// Trees are not a TerrainType, they stay in Level.Obstacles.
+type TerrainType int64
+
+const (
+	Ground TerrainType = iota
+	// Bush blocks vision but the player can teleport into it.
+	Bush
+	// Rock is low, so it doesn't block vision, but nothing can stand on it.
+	Rock
+	// Mud can be walked on, but hounds prefer to walk around it.
+	Mud
+	// Crate blocks both vision and movement until the player breaks it with
+	// the beam. After that, it becomes Ground.
+	Crate
+)
+
+// MudPathCost is how expensive a mud tile is for a hound, compared to a
+// ground tile.
+const MudPathCost = 3
+
+var terrainTypeChar = map[TerrainType]string{
+	Ground: ".",
+	Bush:   "B",
+	Rock:   "R",
+	Mud:    "M",
+	Crate:  "C",
+}
+
+var terrainTypeName = map[TerrainType]string{
+	Ground: "ground",
+	Bush:   "bush",
+	Rock:   "rock",
+	Mud:    "mud",
+	Crate:  "crate",
+}
+
+func (t TerrainType) String() string { return terrainTypeName[t] }
+
+type Terrain struct {
+	Matrix[TerrainType]
+}
+
+// BlocksVision returns true if the player and the hounds can't see through
+// a tile of this type.
+func (t TerrainType) BlocksVision() bool {
+	return t == Bush || t == Crate
+}
+
+// BlocksMovement returns true if the player and the hounds can't stand on a
+// tile of this type.
+func (t TerrainType) BlocksMovement() bool {
+	return t == Rock || t == Crate
+}
+
+// updateTerrain recomputes everything the World derives from its Obstacles and
+// Terrain. It must be called whenever one of them changes.
+func (w *World) updateTerrain() {
+	w.OpaqueTiles = w.Obstacles
+	w.SolidTiles = w.Obstacles
+	w.hasMud = false
+	for i := range w.Terrain.Cells {
+		t := w.Terrain.Cells[i]
+		w.OpaqueTiles.Cells[i] = w.OpaqueTiles.Cells[i] || t.BlocksVision()
+		w.SolidTiles.Cells[i] = w.SolidTiles.Cells[i] || t.BlocksMovement()
+		if t == Mud {
+			w.PathCosts.Cells[i] = I(MudPathCost)
+			w.hasMud = true
+		} else {
+			w.PathCosts.Cells[i] = ONE
+		}
+	}
+}
+
+func (w *World) initCrates() {
+	crateHealth := Max(w.CrateMaxHealth, ONE)
+	for i := range w.Terrain.Cells {
+		if w.Terrain.Cells[i] == Crate {
+			w.CrateHealth.Cells[i] = crateHealth
+		}
+	}
+}
+
+// CrateTiles returns the tiles that have a crate on them.
+func (w *World) CrateTiles() (m MatBool) {
+	for i := range w.Terrain.Cells {
+		m.Cells[i] = w.Terrain.Cells[i] == Crate
+	}
+	return
+}
+
+// HitCrate damages the crate at pos, if there is one. It returns true if
+// there was a crate to hit.
+func (w *World) HitCrate(pos Pt) bool {
+	if w.Terrain.Get(pos) != Crate {
+		return false
+	}
+	health := w.CrateHealth.Get(pos)
+	health.Subtract(Min(w.Player.BeamDamage(), health))
+	w.CrateHealth.Set(pos, health)
+	if health.IsZero() {
+		w.Terrain.Set(pos, Ground)
+		w.updateTerrain()
+	}
+	return true
+}
+
+// ComputePath is what hounds use to find their way. If there is no mud on
+// the map, all tiles cost the same and the cheaper breadth-first search gives
+// the same result.
+func (w *World) ComputePath(start, end Pt, m MatBool) PathArray {
+	if w.hasMud {
+		return ComputePathWithCosts(start, end, m, w.PathCosts)
+	}
+	return ComputePath(start, end, m)
+}
+
+func (t Terrain) MarshalYAML() ([]byte, error) {
+	var s string
+	for i := 0; i < NRows; i++ {
+		row := t.Cells[NCols*i : NCols*(i+1)]
+		var tokens []string
+		for j := range row {
+			tokens = append(tokens, terrainTypeChar[row[j]])
+		}
+		s += "- [" + strings.Join(tokens, ",") + "]\n"
+	}
+	return []byte(s), nil
+}
+
+func (t *Terrain) UnmarshalYAML(b []byte) error {
+	s := strings.TrimSpace(string(b))
+
+	if s == "empty" {
+		t.Cells = [64]TerrainType{}
+		return nil
+	}
+
+	rows, err := SplitMatrixRows(s)
+	if err != nil {
+		return err
+	}
+	for rowIdx, tokens := range rows {
+		for cellIdx, token := range tokens {
+			found := false
+			for k, v := range terrainTypeChar {
+				if v == token {
+					t.Set(IPt(cellIdx, rowIdx), k)
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("unknown terrain type: %s", token)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func Test_TerrainYaml(t *testing.T) {
+	var tr Terrain
+	tr.Set(IPt(0, 0), Bush)
+	tr.Set(IPt(3, 1), Rock)
+	tr.Set(IPt(7, 7), Mud)
+	tr.Set(IPt(4, 6), Crate)
+
+	filename := "terrain.yaml"
+	SaveYAML(filename, tr)
+	var tr2 Terrain
+	LoadYAML(os.DirFS(".").(FS), filename, &tr2)
+	DeleteFile(filename)
+	assert.Equal(t, tr, tr2)
+}
+
+func TestWorld_BushesAndRocks(t *testing.T) {
+	var l Level
+	l.Terrain.Set(IPt(3, 0), Bush)
+	l.Terrain.Set(IPt(0, 3), Rock)
+	w := NewWorld(I(0), l)
+	w.Step(PlayerInput{Move: true, MovePt: IPt(0, 0)})
+
+	// The bush hides what is behind it but the player can go in it.
+	assert.True(t, w.VisibleTiles.At(IPt(3, 0)))
+	assert.False(t, w.VisibleTiles.At(IPt(5, 0)))
+	free := w.Player.ComputeFreePositions(&w)
+	assert.True(t, free.At(IPt(3, 0)))
+
+	// The rock doesn't hide anything but the player can't stand on it.
+	assert.True(t, w.VisibleTiles.At(IPt(0, 5)))
+	assert.False(t, free.At(IPt(0, 3)))
+}
+
+func TestWorld_CrateBreaksAfterHits(t *testing.T) {
+	var l Level
+	l.CrateMaxHealth = I(2)
+	l.Terrain.Set(IPt(2, 0), Crate)
+	w := NewWorld(I(0), l)
+	w.Step(PlayerInput{Move: true, MovePt: IPt(0, 0)})
+	assert.False(t, w.VisibleTiles.At(IPt(4, 0)))
+
+	w.Step(PlayerInput{Shoot: true, ShootPt: IPt(2, 0)})
+	assert.Equal(t, Crate, w.Terrain.Get(IPt(2, 0)))
+	w.Step(PlayerInput{Shoot: true, ShootPt: IPt(2, 0)})
+	assert.Equal(t, Ground, w.Terrain.Get(IPt(2, 0)))
+	assert.True(t, w.VisibleTiles.At(IPt(4, 0)))
+}
+
+func Test_ComputePathWithCostsAvoidsMud(t *testing.T) {
+	var m MatBool
+	var costs Matrix[Int]
+	for i := range costs.Cells {
+		costs.Cells[i] = ONE
+	}
+	// A wall of deep mud between start and end, except for one tile.
+	for y := 0; y < NRows-1; y++ {
+		costs.Set(IPt(3, y), I(10))
+	}
+
+	path := ComputePathWithCosts(IPt(0, 0), IPt(6, 0), m, costs)
+	assert.Equal(t, IPt(0, 0), path.V[0])
+	assert.Equal(t, IPt(6, 0), path.V[path.N-1])
+	cost := ZERO
+	for i := int64(1); i < path.N; i++ {
+		cost.Add(costs.Get(path.V[i]))
+	}
+	// Going through the mud costs 15. Going around it is longer but cheaper.
+	assert.True(t, cost.Lt(I(15)))
+	assert.Greater(t, path.N, int64(7))
+	assert.Equal(t, ComputePath(IPt(0, 0), IPt(6, 0), m).N, int64(7))
+}
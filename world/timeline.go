@@ -0,0 +1,149 @@
+package world
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+)
+
+// The timeline is a flat, per-frame record of a playthrough, meant for
+// analysis outside of Go. Each row is the state of the World after the input
+// of that frame was applied.
+
+type HoundSnapshot struct {
+	Pos                          Pt
+	State                        string
+	Health                       Int
+	MoveCooldownIdx              Int
+	PreparingToAttackCooldownIdx Int
+	AttackCooldownIdx            Int
+	HitCooldownIdx               Int
+}
+
+type TimelineRow struct {
+	PlaythroughId string
+	LevelHash     string
+	Frame         int64
+	Input         PlayerInput
+	PlayerPos     Pt
+	PlayerHealth  Int
+	PlayerAmmo    Int
+	PlayerOnMap   bool
+	NVisibleTiles int64
+	Hounds        []HoundSnapshot
+}
+
+// Timeline replays the playthrough and returns a row for each frame. If
+// onlyActions is true, only the frames in which the player moved or shot are
+// kept.
+func Timeline(p *Playthrough, onlyActions bool) (rows []TimelineRow) {
+	id := p.Id.String()
+	levelHash := p.Level.Hash()
+	w := NewWorldFromPlaythrough(*p)
+	for i := range p.History {
+		p.StepFrame(&w, i)
+		input := p.History[i]
+		if onlyActions && !input.Move && !input.Shoot {
+			continue
+		}
+
+		row := TimelineRow{
+			PlaythroughId: id,
+			LevelHash:     levelHash,
+			Frame:         int64(i),
+			Input:         input,
+			PlayerPos:     w.Player.Pos(),
+			PlayerHealth:  w.Player.Health,
+			PlayerAmmo:    w.Player.AmmoCount,
+			PlayerOnMap:   w.Player.OnMap,
+			NVisibleTiles: w.VisibleTiles.ToArray().N,
+		}
+		for j := range w.Enemies.N {
+			h := &w.Enemies.V[j]
+			row.Hounds = append(row.Hounds, HoundSnapshot{
+				Pos:                          h.Pos(),
+				State:                        h.State(),
+				Health:                       h.Health(),
+				MoveCooldownIdx:              h.MoveCooldownIdx(),
+				PreparingToAttackCooldownIdx: h.PreparingToAttackCooldownIdx(),
+				AttackCooldownIdx:            h.AttackCooldownIdx(),
+				HitCooldownIdx:               h.HitCooldownIdx(),
+			})
+		}
+		rows = append(rows, row)
+	}
+	return
+}
+
+// TimelineTable puts timeline rows, possibly from many playthroughs, in a
+// table. There is a group of columns for each hound, as many groups as the
+// largest number of hounds in any row. The columns of the hounds that don't
+// exist in a row have an empty state and -1 everywhere else.
+func TimelineTable(rows []TimelineRow) (t Table) {
+	maxHounds := 0
+	for _, row := range rows {
+		maxHounds = max(maxHounds, len(row.Hounds))
+	}
+
+	t.AddColumn("playthrough_id", StringColumn)
+	t.AddColumn("level_hash", StringColumn)
+	t.AddColumn("frame", Int64Column)
+	t.AddColumn("move", BoolColumn)
+	t.AddColumn("move_x", Int64Column)
+	t.AddColumn("move_y", Int64Column)
+	t.AddColumn("shoot", BoolColumn)
+	t.AddColumn("shoot_x", Int64Column)
+	t.AddColumn("shoot_y", Int64Column)
+	t.AddColumn("player_x", Int64Column)
+	t.AddColumn("player_y", Int64Column)
+	t.AddColumn("player_health", Int64Column)
+	t.AddColumn("player_ammo", Int64Column)
+	t.AddColumn("player_on_map", BoolColumn)
+	t.AddColumn("visible_tiles", Int64Column)
+	t.AddColumn("n_hounds", Int64Column)
+	for i := range maxHounds {
+		prefix := fmt.Sprintf("hound%d_", i)
+		t.AddColumn(prefix+"x", Int64Column)
+		t.AddColumn(prefix+"y", Int64Column)
+		t.AddColumn(prefix+"state", StringColumn)
+		t.AddColumn(prefix+"health", Int64Column)
+		t.AddColumn(prefix+"move_cooldown", Int64Column)
+		t.AddColumn(prefix+"preparing_to_attack_cooldown", Int64Column)
+		t.AddColumn(prefix+"attack_cooldown", Int64Column)
+		t.AddColumn(prefix+"hit_cooldown", Int64Column)
+	}
+
+	for _, row := range rows {
+		values := []any{
+			row.PlaythroughId,
+			row.LevelHash,
+			row.Frame,
+			row.Input.Move,
+			row.Input.MovePt.X.ToInt64(),
+			row.Input.MovePt.Y.ToInt64(),
+			row.Input.Shoot,
+			row.Input.ShootPt.X.ToInt64(),
+			row.Input.ShootPt.Y.ToInt64(),
+			row.PlayerPos.X.ToInt64(),
+			row.PlayerPos.Y.ToInt64(),
+			row.PlayerHealth.ToInt64(),
+			row.PlayerAmmo.ToInt64(),
+			row.PlayerOnMap,
+			row.NVisibleTiles,
+			int64(len(row.Hounds)),
+		}
+		for i := range maxHounds {
+			if i < len(row.Hounds) {
+				h := row.Hounds[i]
+				values = append(values, h.Pos.X.ToInt64(), h.Pos.Y.ToInt64(),
+					h.State, h.Health.ToInt64(), h.MoveCooldownIdx.ToInt64(),
+					h.PreparingToAttackCooldownIdx.ToInt64(),
+					h.AttackCooldownIdx.ToInt64(), h.HitCooldownIdx.ToInt64())
+			} else {
+				values = append(values, int64(-1), int64(-1), "", int64(-1),
+					int64(-1), int64(-1), int64(-1), int64(-1))
+			}
+		}
+		t.AddRow(values...)
+	}
+	return
+}
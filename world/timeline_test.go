@@ -0,0 +1,33 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTimeline(t *testing.T) {
+	p := DeserializePlaythrough(ReadFile("playthroughs/average-playthrough.mln999-999"))
+	rows := Timeline(&p, false)
+	assert.Equal(t, len(p.History), len(rows))
+
+	// The last row has the state of the World at the end of the playthrough.
+	w := p.ReplayUpTo(len(p.History))
+	last := rows[len(rows)-1]
+	assert.Equal(t, w.Player.Pos(), last.PlayerPos)
+	assert.Equal(t, w.Player.Health, last.PlayerHealth)
+	assert.Equal(t, int(w.Enemies.N), len(last.Hounds))
+	assert.Equal(t, p.Level.Hash(), last.LevelHash)
+
+	actions := Timeline(&p, true)
+	assert.Less(t, len(actions), len(rows))
+	for _, row := range actions {
+		assert.True(t, row.Input.Move || row.Input.Shoot)
+	}
+
+	table := TimelineTable(rows)
+	assert.Equal(t, len(rows), len(table.Rows))
+	for _, row := range table.Rows {
+		assert.Equal(t, len(table.Names), len(row))
+	}
+}
@@ -0,0 +1,213 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+)
+
+// In a TurnBased level the enemies only act after the player acts. Every move
+// or shot is a turn and a turn lasts exactly one enemy move, which is
+// EnemyMoveCooldownDuration frames of enemy simulation. All the cooldowns
+// counted in frames (hound attacks, portals, ammo, pickups) keep their meaning
+// relative to the enemy moves this way.
+
+type IntentKind int64
+
+const (
+	NoIntent IntentKind = iota
+	MoveIntent
+	AttackIntent
+)
+
+// Intent is what an enemy will do during the next turn, if the player doesn't
+// interfere.
+type Intent struct {
+	Kind IntentKind
+	From Pt
+	To   Pt
+}
+
+type IntentsArray struct {
+	N int64
+	V [30]Intent
+}
+
+// framesPerTurn is the number of frames the enemies are simulated for when
+// the player takes an action in a TurnBased level.
+func (w *World) framesPerTurn() Int {
+	return Max(w.EnemyMoveCooldownDuration, ONE)
+}
+
+// stepTurn advances the enemies by one logical turn.
+func (w *World) stepTurn() {
+	frames := w.framesPerTurn()
+	for i := ZERO; i.Lt(frames); i.Inc() {
+		w.stepEnemies()
+		if i.Eq(ZERO) && w.Beam.Idx.Eq(w.BeamMax) {
+			// The enemies were hit by the beam in the first frame of the
+			// turn, they shouldn't be hit again in the next frames.
+			w.Beam.Idx.Dec()
+		}
+	}
+}
+
+// EnemyIntents predicts what each enemy will do during the next turn if the
+// player doesn't move or shoot. The intents are in the same order as
+// w.Enemies. Enemies spawned during the turn don't have an intent.
+func (w *World) EnemyIntents() (intents IntentsArray) {
+	next := *w
+	next.Player.JustHit = false
+	next.stepTurn()
+
+	intents.N = w.Enemies.N
+	for i := range w.Enemies.N {
+		from := w.Enemies.V[i].Pos()
+		to := next.Enemies.V[i].Pos()
+		intents.V[i] = Intent{NoIntent, from, to}
+		if next.Player.JustHit && w.Player.OnMap && to == w.Player.Pos() {
+			intents.V[i].Kind = AttackIntent
+		} else if to != from {
+			intents.V[i].Kind = MoveIntent
+		}
+	}
+	return
+}
+
+// EffectiveHistory returns the inputs that still have an effect after all the
+// undos in history are applied. An undo takes back the last turn that was not
+// already taken back, including the idle frames that followed it. There is no
+// limit to how many turns can be taken back.
+func EffectiveHistory(history []PlayerInput) (effective []PlayerInput) {
+	var turnStarts []int
+	for _, input := range history {
+		if input.Undo {
+			if len(turnStarts) > 0 {
+				effective = effective[:turnStarts[len(turnStarts)-1]]
+				turnStarts = turnStarts[:len(turnStarts)-1]
+			}
+			continue
+		}
+		if input.Move || input.Shoot {
+			turnStarts = append(turnStarts, len(effective))
+		}
+		effective = append(effective, input)
+	}
+	return
+}
+
+// ReplayUpTo returns the World as it is after the first nFrames inputs of the
+// playthrough.
+func (p *Playthrough) ReplayUpTo(nFrames int) (w World) {
+	w = NewWorldFromPlaythrough(*p)
+	for i := range nFrames {
+		p.StepFrame(&w, i)
+	}
+	return
+}
+
+// StepFrame advances w, which must be the World after the first i inputs of
+// the playthrough, by the input at index i. Undos restore the World saved
+// before the turn they take back, see stepFrame.
+// StepFrame also checks w against the recorded checksums, see DesyncFrame.
+func (p *Playthrough) StepFrame(w *World, i int) {
+	p.stepFrame(w, i)
+	p.verifyChecksum(w, i+1)
+}
+
+// undoSnapshotInterval is how many turns apart the snapshots kept for undos
+// are. A World is big, so an undo replays the turns since the last snapshot
+// instead of keeping a snapshot of every turn.
+const undoSnapshotInterval = 10
+
+// undoState is what stepFrame keeps to take turns back. It describes the
+// first nFrames inputs of the History.
+type undoState struct {
+	nFrames int
+	// The index in the History of each turn that can still be taken back.
+	turns []int
+	// The World before every undoSnapshotInterval-th turn in turns.
+	snapshots []turnSnapshot
+}
+
+type turnSnapshot struct {
+	frame int
+	world World
+}
+
+// stepFrame keeps track of the turns that can be taken back, see undoState.
+// The state only matches w if the frames were stepped in order. If they
+// weren't (e.g. after a seek), it is rebuilt by the first undo that needs it.
+// Undos are ignored in levels that aren't TurnBased.
+func (p *Playthrough) stepFrame(w *World, i int) {
+	if !w.TurnBased {
+		w.Step(p.History[i])
+		return
+	}
+	u := &p.undo
+	if i == 0 {
+		*u = undoState{}
+	}
+	input := p.History[i]
+	if input.Undo {
+		if u.nFrames != i {
+			p.rebuildUndo(i)
+		}
+		if n := len(u.turns); n > 0 {
+			*w = p.worldBeforeTurn(u.turns[n-1])
+			u.turns = u.turns[:n-1]
+		}
+	} else {
+		if (input.Move || input.Shoot) && u.nFrames == i {
+			if len(u.turns)%undoSnapshotInterval == 0 {
+				u.snapshots = append(u.snapshots, turnSnapshot{i, *w})
+			}
+			u.turns = append(u.turns, i)
+		}
+		w.Step(input)
+	}
+	if u.nFrames == i {
+		u.nFrames = i + 1
+	}
+}
+
+// worldBeforeTurn returns the World right before the turn that starts at
+// frame, which is the last turn that can be taken back. It replays the turns
+// since the last snapshot and forgets the snapshot of the turn, if it has one.
+func (p *Playthrough) worldBeforeTurn(frame int) World {
+	u := &p.undo
+	if n := len(u.snapshots); n > 0 && u.snapshots[n-1].frame == frame {
+		w := u.snapshots[n-1].world
+		u.snapshots = u.snapshots[:n-1]
+		return w
+	}
+	start := 0
+	var w World
+	if n := len(u.snapshots); n > 0 {
+		start, w = u.snapshots[n-1].frame, u.snapshots[n-1].world
+	} else {
+		w = NewWorldFromPlaythrough(*p)
+	}
+	// The turns taken back since the snapshot were all after it, so the
+	// undos between start and frame don't reach before start.
+	for _, input := range EffectiveHistory(p.History[start:frame]) {
+		w.Step(input)
+	}
+	return w
+}
+
+// rebuildUndo replays the first i inputs to get the undoState at frame i.
+func (p *Playthrough) rebuildUndo(i int) {
+	w := NewWorldFromPlaythrough(*p)
+	for j := range i {
+		p.stepFrame(&w, j)
+	}
+}
+
+// NUndos counts how many times the player took back a turn.
+func (p *Playthrough) NUndos() (n int) {
+	for _, input := range p.History {
+		if input.Undo {
+			n++
+		}
+	}
+	return
+}
@@ -0,0 +1,169 @@
+package world
+
+import (
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func turnBasedLevel() (l Level) {
+	l.TurnBased = true
+	l.EnemyMoveCooldownDuration = I(20)
+	l.EnemiesAggroWhenVisible = true
+	l.HoundMaxHealth = I(3)
+	l.HoundMoveCooldownMultiplier = ONE
+	l.HoundAttackCooldownMultiplier = ONE
+	l.HoundPreparingToAttackCooldown = I(20)
+	l.HoundHitCooldownDuration = I(20)
+	l.HoundHitsPlayer = true
+	l.SpawnPortalsParams.N = 1
+	l.SpawnPortalsParams.V[0].Pos = IPt(7, 7)
+	l.Events.N = 1
+	l.Events.V[0] = LevelEvent{Trigger: AtStart, Action: SpawnWave,
+		Portal: IPt(7, 7), NHounds: I(1), Burst: true}
+	return
+}
+
+func TestWorld_EnemiesOnlyActOnTurns(t *testing.T) {
+	w := NewWorld(I(0), turnBasedLevel())
+	w.Step(PlayerInput{Move: true, MovePt: IPt(1, 1)})
+	assert.Equal(t, int64(1), w.Enemies.N)
+	houndPos := w.Enemies.V[0].Pos()
+
+	// Waiting doesn't give the enemies any time.
+	for range 100 {
+		w.Step(PlayerInput{})
+	}
+	assert.Equal(t, houndPos, w.Enemies.V[0].Pos())
+
+	// Each action is at most one enemy move and the intents predict it.
+	moved := false
+	attacked := false
+	for range 20 {
+		intents := w.EnemyIntents()
+		assert.Equal(t, int64(1), intents.N)
+		assert.Equal(t, houndPos, intents.V[0].From)
+
+		w.Step(PlayerInput{Shoot: true, ShootPt: IPt(0, 7)})
+		if intents.V[0].Kind == AttackIntent {
+			attacked = true
+			assert.True(t, w.Player.JustHit)
+			break
+		}
+		newPos := w.Enemies.V[0].Pos()
+		assert.Equal(t, intents.V[0].To, newPos)
+		assert.True(t, tileDist(houndPos, newPos).Leq(ONE))
+		moved = moved || newPos != houndPos
+		houndPos = newPos
+	}
+	assert.True(t, moved)
+	assert.True(t, attacked)
+}
+
+func TestEffectiveHistory(t *testing.T) {
+	move := PlayerInput{Move: true, MovePt: IPt(1, 1)}
+	shoot := PlayerInput{Shoot: true, ShootPt: IPt(2, 2)}
+	idle := PlayerInput{}
+	undo := PlayerInput{Undo: true}
+
+	history := []PlayerInput{idle, move, idle, shoot, idle, idle, undo}
+	assert.Equal(t, []PlayerInput{idle, move, idle}, EffectiveHistory(history))
+
+	// Undos can go back all the way and extra undos do nothing.
+	history = append(history, undo, undo, move)
+	assert.Equal(t, []PlayerInput{idle, move}, EffectiveHistory(history))
+}
+
+func TestPlaythrough_UndoRestoresWorld(t *testing.T) {
+	var p Playthrough
+	p.Level = turnBasedLevel()
+	p.SimulationVersion = I(SimulationVersion)
+	w := NewWorldFromPlaythrough(p)
+
+	Step(&p, &w, PlayerInput{Move: true, MovePt: IPt(1, 1)})
+	Step(&p, &w, PlayerInput{})
+	before := w.State()
+
+	Step(&p, &w, PlayerInput{Shoot: true, ShootPt: w.Enemies.V[0].Pos()})
+	Step(&p, &w, PlayerInput{Move: true, MovePt: IPt(2, 1)})
+	assert.NotEqual(t, before, w.State())
+
+	Step(&p, &w, PlayerInput{Undo: true})
+	Step(&p, &w, PlayerInput{Undo: true})
+	assert.Equal(t, before, w.State())
+	assert.Equal(t, ONE, w.NTurns)
+	assert.Equal(t, 2, p.NUndos())
+
+	// Replaying the recorded playthrough gives the same World.
+	replayed := p.ReplayUpTo(len(p.History))
+	assert.Equal(t, w.State(), replayed.State())
+}
+
+func TestPlaythrough_UndoAfterSeek(t *testing.T) {
+	var p Playthrough
+	p.Level = turnBasedLevel()
+	p.SimulationVersion = I(SimulationVersion)
+	w := NewWorldFromPlaythrough(p)
+	Step(&p, &w, PlayerInput{Move: true, MovePt: IPt(1, 1)})
+	before := w.State()
+	Step(&p, &w, PlayerInput{Move: true, MovePt: IPt(2, 1)})
+	Step(&p, &w, PlayerInput{Undo: true})
+	assert.Equal(t, before, w.State())
+
+	// Stepping the undo of a World that came from elsewhere still finds the
+	// turn it takes back.
+	w = p.ReplayUpTo(2)
+	p.ReplayUpTo(1)
+	p.StepFrame(&w, 2)
+	assert.Equal(t, before, w.State())
+}
+
+func TestPlaythrough_UndoIgnoredInRealTime(t *testing.T) {
+	var p Playthrough
+	p.Level = turnBasedLevel()
+	p.TurnBased = false
+	p.SimulationVersion = I(SimulationVersion)
+	w := NewWorldFromPlaythrough(p)
+	Step(&p, &w, PlayerInput{Move: true, MovePt: IPt(1, 1)})
+	Step(&p, &w, PlayerInput{Undo: true})
+
+	expected := NewWorldFromPlaythrough(p)
+	expected.Step(PlayerInput{Move: true, MovePt: IPt(1, 1)})
+	expected.Step(PlayerInput{})
+	assert.Equal(t, expected.State(), w.State())
+	assert.True(t, w.Player.OnMap)
+}
+
+func TestPlaythrough_UndoManyTurns(t *testing.T) {
+	var p Playthrough
+	p.Level = turnBasedLevel()
+	p.HoundHitsPlayer = false
+	p.SimulationVersion = I(SimulationVersion)
+	w := NewWorldFromPlaythrough(p)
+
+	// Go past several snapshots, with turns taken back along the way.
+	undo := PlayerInput{Undo: true}
+	for i := range 3 * undoSnapshotInterval {
+		Step(&p, &w, PlayerInput{Move: true, MovePt: IPt(i%2, 1)})
+		Step(&p, &w, PlayerInput{})
+		if i%7 == 6 {
+			Step(&p, &w, undo)
+		}
+	}
+	for range undoSnapshotInterval + 3 {
+		Step(&p, &w, undo)
+		expected := NewWorldFromPlaythrough(p)
+		for _, input := range EffectiveHistory(p.History) {
+			expected.Step(input)
+		}
+		assert.Equal(t, expected.State(), w.State())
+	}
+
+	// A clone doesn't carry the snapshots but can still take turns back.
+	clone := p.Clone()
+	assert.Empty(t, clone.undo.snapshots)
+	w2 := w
+	Step(clone, &w2, undo)
+	Step(&p, &w, undo)
+	assert.Equal(t, w.State(), w2.State())
+}
@@ -0,0 +1,402 @@
+package world
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+)
+
+// Severity says if an Issue stops a level from working (IssueError) or only
+// makes it behave in a way that is probably not intended (IssueWarning).
+type Severity int
+
+const (
+	IssueError Severity = iota
+	IssueWarning
+)
+
+var severityName = map[Severity]string{
+	IssueError:   "error",
+	IssueWarning: "warning",
+}
+
+// Issue is a problem found by Validate.
+type Issue struct {
+	Severity Severity
+	Field    string // e.g. "SpawnPortalsParams[2].Pos", empty for all the level
+	Message  string
+}
+
+func (s Severity) String() string {
+	return severityName[s]
+}
+
+func (i Issue) String() string {
+	if i.Field == "" {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Field, i.Message)
+}
+
+// HasErrors returns true if at least one of the issues is an IssueError.
+func HasErrors(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Severity == IssueError {
+			return true
+		}
+	}
+	return false
+}
+
+// levelValidator collects the issues of a level.
+type levelValidator struct {
+	l      *Level
+	issues []Issue
+	// Tiles that nothing can ever stand on. Crates are not included because
+	// the player can break them.
+	blocked MatBool
+}
+
+func (v *levelValidator) add(severity Severity, field string, format string,
+	a ...any) {
+	v.issues = append(v.issues,
+		Issue{severity, field, fmt.Sprintf(format, a...)})
+}
+
+// count returns how many elements of an array can be checked, and reports an
+// error if the level has more than the array can hold. Loading such a level
+// from YAML keeps the extra N, which makes the World crash with an index out
+// of range.
+func (v *levelValidator) count(field string, n int64, capacity int) int64 {
+	if n > int64(capacity) {
+		v.add(IssueError, field, "has %d entries, at most %d are supported", n,
+			capacity)
+		return int64(capacity)
+	}
+	return n
+}
+
+// checkPos reports a position that is off the map or on a tile where nothing
+// can stand.
+func (v *levelValidator) checkPos(field string, pos Pt) bool {
+	if !v.blocked.InBounds(pos) {
+		v.add(IssueError, field, "%s is outside the %dx%d map", ptStr(pos),
+			NCols, NRows)
+		return false
+	}
+	if v.blocked.At(pos) {
+		v.add(IssueError, field, "%s is on an obstacle", ptStr(pos))
+		return false
+	}
+	return true
+}
+
+func (v *levelValidator) checkPositive(field string, val Int, why string) {
+	if !val.IsPositive() {
+		v.add(IssueError, field, "is %d, %s", val.ToInt64(), why)
+	}
+}
+
+// portalIdx returns the index of the portal at pos, or -1.
+func (v *levelValidator) portalIdx(pos Pt) int64 {
+	for i := range min(v.l.SpawnPortalsParams.N,
+		int64(len(v.l.SpawnPortalsParams.V))) {
+		if v.l.SpawnPortalsParams.V[i].Pos == pos {
+			return i
+		}
+	}
+	return -1
+}
+
+// houndsFromPortal returns how many hounds the portal at pos can spawn,
+// through its waves and through events.
+func (v *levelValidator) houndsFromPortal(pos Pt) (n int64) {
+	l := v.l
+	if i := v.portalIdx(pos); i >= 0 {
+		p := &l.SpawnPortalsParams.V[i]
+		for j := range min(p.Waves.N, int64(len(p.Waves.V))) {
+			n += max(p.Waves.V[j].NHounds.ToInt64(), 0)
+		}
+	}
+	for i := range min(l.Events.N, int64(len(l.Events.V))) {
+		e := &l.Events.V[i]
+		if e.Action == SpawnWave && e.Portal == pos {
+			n += max(e.NHounds.ToInt64(), 0)
+		}
+	}
+	return
+}
+
+// blockedTiles returns the tiles of the level that nothing can ever stand on.
+func blockedTiles(l *Level) (blocked MatBool) {
+	blocked = l.Obstacles
+	for i := range l.Terrain.Cells {
+		if l.Terrain.Cells[i] == Rock {
+			blocked.Cells[i] = true
+		}
+	}
+	return
+}
+
+func (v *levelValidator) checkMap() {
+	v.blocked = blockedTiles(v.l)
+
+	free := v.blocked
+	free.Negate()
+	if free.ToArray().N == 0 {
+		v.add(IssueError, "Obstacles", "there is no free tile on the map")
+		return
+	}
+	if !IsLevelValid(v.blocked) {
+		v.add(IssueError, "Obstacles", "the free tiles are not all connected, "+
+			"some of them can't be reached by the hounds")
+	}
+}
+
+func (v *levelValidator) checkWorldParams(nHounds int64) {
+	p := &v.l.WorldParams
+	if nHounds > 0 {
+		v.checkPositive("WorldParams.HoundMaxHealth", p.HoundMaxHealth,
+			"hounds would be born dead")
+		v.checkPositive("WorldParams.HoundMoveCooldownMultiplier",
+			p.HoundMoveCooldownMultiplier, "hounds would never move")
+		v.checkPositive("WorldParams.HoundAttackCooldownMultiplier",
+			p.HoundAttackCooldownMultiplier, "hounds would never attack")
+		v.checkPositive("WorldParams.HoundPreparingToAttackCooldown",
+			p.HoundPreparingToAttackCooldown,
+			"hounds would prepare to attack forever")
+		v.checkPositive("WorldParams.HoundHitCooldownDuration",
+			p.HoundHitCooldownDuration,
+			"hounds would never recover after being hit")
+		if !p.EnemyMoveCooldownDuration.IsPositive() {
+			v.add(IssueWarning, "WorldParams.EnemyMoveCooldownDuration",
+				"is %d, hounds move every frame",
+				p.EnemyMoveCooldownDuration.ToInt64())
+		}
+	}
+	if p.UseAmmo {
+		v.checkPositive("WorldParams.AmmoLimit", p.AmmoLimit,
+			"the player could never carry ammo")
+	}
+	if p.ForceReentry {
+		v.checkPos("WorldParams.ReentryPos", p.ReentryPos)
+	}
+	if p.MovementMode != Teleport && p.MovementMode != Walk {
+		v.add(IssueError, "WorldParams.MovementMode", "unknown mode %d",
+			p.MovementMode)
+	}
+}
+
+func (v *levelValidator) checkPortals() {
+	l := v.l
+	n := v.count("SpawnPortalsParams", l.SpawnPortalsParams.N,
+		len(l.SpawnPortalsParams.V))
+	var seen MatBool
+	for i := range n {
+		field := fmt.Sprintf("SpawnPortalsParams[%d]", i)
+		p := &l.SpawnPortalsParams.V[i]
+		if v.checkPos(field+".Pos", p.Pos) {
+			if seen.At(p.Pos) {
+				v.add(IssueWarning, field+".Pos",
+					"another portal is already at %s", ptStr(p.Pos))
+			}
+			seen.Set(p.Pos)
+		}
+		nWaves := v.count(field+".Waves", p.Waves.N, len(p.Waves.V))
+		if nWaves > 0 && !p.SpawnPortalCooldown.IsPositive() {
+			v.add(IssueWarning, field+".SpawnPortalCooldown",
+				"is %d, hounds spawn every time the hounds move",
+				p.SpawnPortalCooldown.ToInt64())
+		}
+		for j := range nWaves {
+			wave := &p.Waves.V[j]
+			if wave.NHounds.IsNegative() {
+				v.add(IssueError, fmt.Sprintf("%s.Waves[%d].NHounds", field, j),
+					"is negative")
+			}
+		}
+		if p.Health.IsNegative() {
+			v.add(IssueError, field+".Health", "is negative")
+		}
+		if p.Inactive && nWaves > 0 && !v.activated(p.Pos) {
+			v.add(IssueWarning, field+".Inactive",
+				"no event activates this portal, its waves never spawn")
+		}
+	}
+}
+
+// activated returns true if an event activates the portal at pos.
+func (v *levelValidator) activated(pos Pt) bool {
+	for i := range min(v.l.Events.N, int64(len(v.l.Events.V))) {
+		e := &v.l.Events.V[i]
+		if e.Action == ActivatePortal && e.Portal == pos {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *levelValidator) checkPickups() {
+	l := v.l
+	n := v.count("PickupsParams", l.PickupsParams.N, len(l.PickupsParams.V))
+	for i := range n {
+		field := fmt.Sprintf("PickupsParams[%d]", i)
+		p := &l.PickupsParams.V[i]
+		switch p.SpawnRule {
+		case SpawnFixed:
+			v.checkPos(field+".Pos", p.Pos)
+		case SpawnTimed:
+			v.checkPositive(field+".SpawnCooldown", p.SpawnCooldown,
+				"the pickup would never spawn")
+		case SpawnHoundDrop:
+			if !p.DropChance.IsPositive() || p.DropChance.Gt(I(100)) {
+				v.add(IssueError, field+".DropChance",
+					"is %d, must be between 1 and 100", p.DropChance.ToInt64())
+			}
+		default:
+			v.add(IssueError, field+".SpawnRule", "unknown rule %d",
+				p.SpawnRule)
+		}
+		switch p.Type {
+		case HealthPickup, EnergyPickup:
+			v.checkPositive(field+".Amount", p.Amount,
+				"the pickup would do nothing")
+		case DoubleDamagePickup, FasterCooldownPickup:
+			v.checkPositive(field+".Duration", p.Duration,
+				"the pickup would do nothing")
+		default:
+			v.add(IssueError, field+".Type", "unknown type %d", p.Type)
+		}
+	}
+}
+
+func (v *levelValidator) checkAmmo() {
+	a := &v.l.AmmoParams
+	n := v.count("AmmoParams.SpawnPoints", a.SpawnPoints.N,
+		len(a.SpawnPoints.V))
+	for i := range n {
+		v.checkPos(fmt.Sprintf("AmmoParams.SpawnPoints[%d].Pos", i),
+			a.SpawnPoints.V[i].Pos)
+	}
+	if v.l.UseAmmo && a.Strategy == AmmoAtSpawnPoints && n == 0 {
+		v.add(IssueError, "AmmoParams.SpawnPoints", "the ammo spawns at spawn "+
+			"points but there are none")
+	}
+}
+
+func (v *levelValidator) checkEvents(nHounds int64) {
+	l := v.l
+	n := v.count("Events", l.Events.N, len(l.Events.V))
+	for i := range n {
+		field := fmt.Sprintf("Events[%d]", i)
+		e := &l.Events.V[i]
+		if v.portalIdx(e.Portal) < 0 {
+			v.add(IssueWarning, field+".Portal",
+				"there is no portal at %s, the event does nothing",
+				ptStr(e.Portal))
+		}
+		switch e.Trigger {
+		case HoundsKilled:
+			if e.Count.ToInt64() > nHounds {
+				v.add(IssueWarning, field+".Count",
+					"is %d, but only %d hounds can spawn, the event never "+
+						"fires", e.Count.ToInt64(), nHounds)
+			}
+		case PortalDestroyed:
+			if i := v.portalIdx(e.TriggerPos); i < 0 ||
+				!l.SpawnPortalsParams.V[i].Health.IsPositive() {
+				v.add(IssueWarning, field+".TriggerPos",
+					"there is no portal with Health at %s, the event never "+
+						"fires", ptStr(e.TriggerPos))
+			}
+		}
+		if e.Action == SpawnWave && e.NHounds.IsNegative() {
+			v.add(IssueError, field+".NHounds", "is negative")
+		}
+	}
+}
+
+func (v *levelValidator) checkObjectives() {
+	l := v.l
+	n := v.count("Objectives", l.Objectives.N, len(l.Objectives.V))
+	var survive, timeLimit *ObjectiveParams
+	for i := range n {
+		field := fmt.Sprintf("Objectives[%d]", i)
+		o := &l.Objectives.V[i]
+		switch o.Type {
+		case KillAllEnemies:
+		case Survive:
+			v.checkPositive(field+".Seconds", o.Seconds, "the level is won "+
+				"right away")
+			survive = o
+		case TimeLimit:
+			v.checkPositive(field+".Seconds", o.Seconds, "the level is lost "+
+				"right away")
+			timeLimit = o
+		case ReachExit, CollectKey:
+			v.checkPos(field+".Pos", o.Pos)
+		case ProtectAlly:
+			v.checkPos(field+".Pos", o.Pos)
+			v.checkPositive(field+".Health", o.Health, "the ally is dead "+
+				"from the start")
+		case KillTarget:
+			if v.portalIdx(o.Pos) < 0 {
+				v.add(IssueError, field+".Pos",
+					"there is no portal at %s, the target never spawns",
+					ptStr(o.Pos))
+			} else if v.houndsFromPortal(o.Pos) == 0 {
+				v.add(IssueError, field+".Pos", "the portal at %s never "+
+					"spawns a hound, the target never spawns", ptStr(o.Pos))
+			}
+		default:
+			v.add(IssueError, field+".Type", "unknown type %d", o.Type)
+		}
+	}
+	if survive != nil && timeLimit != nil &&
+		timeLimit.Seconds.Leq(survive.Seconds) {
+		v.add(IssueError, "Objectives", "the time limit of %ds ends before "+
+			"the %ds the player must survive", timeLimit.Seconds.ToInt64(),
+			survive.Seconds.ToInt64())
+	}
+}
+
+// Validate finds the problems of a level that make the World crash or
+// behave in a way the level's author probably didn't want.
+func Validate(l Level) []Issue {
+	v := levelValidator{l: &l}
+	v.checkMap()
+	v.checkPortals()
+	var nHounds int64
+	nPortals := min(l.SpawnPortalsParams.N, int64(len(l.SpawnPortalsParams.V)))
+	for i := range nPortals {
+		nHounds += v.houndsFromPortal(l.SpawnPortalsParams.V[i].Pos)
+	}
+	if nHounds > int64(len(EnemiesArray{}.V)) {
+		v.add(IssueWarning, "SpawnPortalsParams", "%d hounds can spawn but at "+
+			"most %d fit on the map, the rest are lost if they are all "+
+			"alive at the same time", nHounds, len(EnemiesArray{}.V))
+	}
+	v.checkWorldParams(nHounds)
+	v.checkPickups()
+	v.checkAmmo()
+	v.checkEvents(nHounds)
+	v.checkObjectives()
+	return v.issues
+}
+
+// ValidateLevelYAML is Validate for a level that is not loaded yet. Unlike
+// LevelFromYAML, it reports a wrong version or a YAML that doesn't parse as
+// an Issue instead of crashing.
+func ValidateLevelYAML(data []byte) []Issue {
+	_, l, err := TryLevelFromYAML(data)
+	var versionErr *VersionError
+	if errors.As(err, &versionErr) {
+		return []Issue{{IssueError, versionErr.What, fmt.Sprintf("is %d, "+
+			"this build loads levels of version %d", versionErr.Found,
+			versionErr.Expected)}}
+	}
+	if err != nil {
+		return []Issue{{IssueError, "", err.Error()}}
+	}
+	return Validate(l)
+}
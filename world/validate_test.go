@@ -0,0 +1,97 @@
+package world
+
+import (
+	"fmt"
+	. "github.com/marisvali/miln/gamelib"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func fields(issues []Issue) (f []string) {
+	for _, i := range issues {
+		f = append(f, i.Field)
+	}
+	return
+}
+
+func TestValidate_Levels(t *testing.T) {
+	fsys := os.DirFS("..").(FS)
+	for _, name := range GetFiles(fsys, "data/levels", "*") {
+		issues := ValidateLevelYAML(ReadFile("../" + name))
+		assert.False(t, HasErrors(issues), "%s: %v", name, issues)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var l Level
+	l.HoundMaxHealth = I(1)
+	l.HoundMoveCooldownMultiplier = I(1)
+	l.HoundAttackCooldownMultiplier = I(1)
+	l.HoundPreparingToAttackCooldown = I(10)
+	l.HoundHitCooldownDuration = I(10)
+	l.EnemyMoveCooldownDuration = I(10)
+	l.SpawnPortalsParams.N = 1
+	l.SpawnPortalsParams.V[0] = SpawnPortalParams{Pos: IPt(6, 6),
+		SpawnPortalCooldown: I(100)}
+	l.SpawnPortalsParams.V[0].Waves.N = 1
+	l.SpawnPortalsParams.V[0].Waves.V[0].NHounds = I(2)
+	assert.Empty(t, Validate(l))
+
+	bad := l
+	bad.HoundHitCooldownDuration = ZERO
+	bad.Obstacles.Set(IPt(1, 1))
+	bad.SpawnPortalsParams.V[0].Pos = IPt(1, 1)
+	bad.Objectives.N = 1
+	bad.Objectives.V[0] = ObjectiveParams{Type: KillTarget, Pos: IPt(3, 3)}
+	bad.Events.N = 1
+	bad.Events.V[0] = LevelEvent{Trigger: HoundsKilled, Count: I(3),
+		Action: ActivatePortal, Portal: IPt(1, 1)}
+	issues := Validate(bad)
+	assert.True(t, HasErrors(issues))
+	assert.Equal(t, []string{
+		"SpawnPortalsParams[0].Pos",
+		"WorldParams.HoundHitCooldownDuration",
+		"Events[0].Count",
+		"Objectives[0].Pos"}, fields(issues))
+	assert.Equal(t, "error: SpawnPortalsParams[0].Pos: (1, 1) is on an "+
+		"obstacle", issues[0].String())
+
+	// Portals.
+	bad = l
+	bad.SpawnPortalsParams.V[0].Health = I(-1)
+	bad.Events.N = 1
+	bad.Events.V[0] = LevelEvent{Trigger: PortalDestroyed, TriggerPos: IPt(6, 6),
+		Action: ActivatePortal, Portal: IPt(6, 6)}
+	assert.Equal(t, []string{"SpawnPortalsParams[0].Health",
+		"Events[0].TriggerPos"}, fields(Validate(bad)))
+	bad.SpawnPortalsParams.V[0].Health = TWO
+	assert.Empty(t, Validate(bad))
+
+	// Walls that split the map.
+	bad = l
+	for y := range NRows {
+		bad.Obstacles.Set(IPt(3, y))
+	}
+	assert.Equal(t, []string{"Obstacles"}, fields(Validate(bad)))
+}
+
+func TestValidateLevelYAML(t *testing.T) {
+	issues := ValidateLevelYAML([]byte("InputVersion: 999\n"))
+	assert.Equal(t, []string{"InputVersion"}, fields(issues))
+
+	issues = ValidateLevelYAML([]byte("InputVersion: [\n"))
+	assert.True(t, HasErrors(issues))
+
+	// More portals than fit in the arrays of the World.
+	var yaml strings.Builder
+	fmt.Fprintf(&yaml, "InputVersion: %d\nLevel:\n  SpawnPortalsParams:\n",
+		InputVersion)
+	for i := range 31 {
+		fmt.Fprintf(&yaml, "  - Pos: [%d, %d]\n", i%NCols, i/NCols)
+	}
+	issues = ValidateLevelYAML([]byte(yaml.String()))
+	assert.Equal(t, []string{"SpawnPortalsParams"}, fields(issues))
+	assert.Contains(t, issues[0].Message, "31 entries, at most 30")
+}
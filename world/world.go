@@ -29,21 +29,39 @@ type World struct {
 	Rand
 	WorldParams
 	Obstacles         MatBool
+	Terrain           Terrain
+	OpaqueTiles       MatBool // trees and terrain that block vision
+	SolidTiles        MatBool // trees and terrain that can't be occupied
+	PathCosts         Matrix[Int]
+	CrateHealth       Matrix[Int]
 	Player            Player
 	Enemies           EnemiesArray
 	Beam              Beam
 	VisibleTiles      MatBool
 	TimeStep          Int
+	NTurns            Int // the turns taken so far, in a TurnBased level
 	BeamMax           Int
 	BlockSize         Int
 	EnemyMoveCooldown Cooldown
 	Ammos             AmmosArray
+	AmmoParams        AmmoParams
+	AmmoRespawnTimer  Cooldown
 	SpawnPortals      SpawnPortalsArray
+	Pickups           PickupsArray
+	PickupSpawners    PickupSpawnersArray
+	Objectives        ObjectivesArray
+	Events            EventsArray
+	NHoundsKilled     Int
+	DestroyedPortals  MatBool
+	StepEvents        StepEventsArray // what happened during the last Step
 	vision            Vision
+	hasMud            bool
+	levelEnded        bool
 }
 
 type WorldParams struct {
 	Boardgame                      bool `yaml:"Boardgame"`
+	TurnBased                      bool `yaml:"TurnBased"`
 	UseAmmo                        bool `yaml:"UseAmmo"`
 	AmmoLimit                      Int  `yaml:"AmmoLimit"`
 	EnemyMoveCooldownDuration      Int  `yaml:"EnemyMoveCooldownDuration"`
@@ -57,6 +75,16 @@ type WorldParams struct {
 	HoundHitCooldownDuration       Int  `yaml:"HoundHitCooldownDuration"`
 	HoundHitsPlayer                bool `yaml:"HoundHitsPlayer"`
 	HoundAggroDistance             Int  `yaml:"HoundAggroDistance"`
+	CrateMaxHealth                 Int  `yaml:"CrateMaxHealth"`
+	// Movement rules. The zero values give the original rules: teleport
+	// anywhere visible while on the map and anywhere at all while off it.
+	// ForceReentry only applies after being hit, the first entry on the map
+	// can still be anywhere.
+	MovementMode        MovementMode `yaml:"MovementMode"`
+	MaxTeleportDistance Int          `yaml:"MaxTeleportDistance"` // in tiles
+	MoveCooldownPerTile Int          `yaml:"MoveCooldownPerTile"` // in frames
+	ForceReentry        bool         `yaml:"ForceReentry"`
+	ReentryPos          Pt           `yaml:"ReentryPos"`
 }
 
 type Beam struct {
@@ -77,17 +105,38 @@ type PlayerInput struct {
 	MovePt             Pt // tile-coordinates
 	Shoot              bool
 	ShootPt            Pt // tile-coordinates
+	// Undo takes back the last action in a turn-based level. World.Step treats
+	// it as an idle frame, see Playthrough.StepFrame for how it is applied.
+	Undo bool
 }
 
 func NewWorld(seed Int, l Level) (w World) {
 	// Initialize values.
 	w.Obstacles = l.Obstacles
+	w.Terrain = l.Terrain
 	w.WorldParams = l.WorldParams
+	w.updateTerrain()
+	w.initCrates()
 	w.RSeed(seed)
 	for i := range l.SpawnPortalsParams.N {
 		w.SpawnPortals.V[i] = NewSpawnPortal(w.RInt63(), l.SpawnPortalsParams.V[i], w.WorldParams)
 	}
 	w.SpawnPortals.N = l.SpawnPortalsParams.N
+	for i := range l.PickupsParams.N {
+		w.PickupSpawners.V[i] = NewPickupSpawner(l.PickupsParams.V[i])
+	}
+	w.PickupSpawners.N = l.PickupsParams.N
+	w.AmmoParams = l.AmmoParams
+	for i := range l.Objectives.N {
+		w.Objectives.V[i] = NewObjective(l.Objectives.V[i])
+	}
+	w.Objectives.N = l.Objectives.N
+	w.updateExitLocks()
+	for i := range l.Events.N {
+		w.Events.V[i].LevelEvent = l.Events.V[i]
+	}
+	w.Events.N = l.Events.N
+	w.AmmoRespawnTimer = NewCooldown(l.AmmoParams.RespawnDelay)
 	w.vision = NewVision()
 
 	// Params
@@ -96,6 +145,7 @@ func NewWorld(seed Int, l Level) (w World) {
 	w.Player = NewPlayer()
 	w.Player.AmmoLimit = w.AmmoLimit
 	w.EnemyMoveCooldown = NewCooldown(w.EnemyMoveCooldownDuration)
+	w.SpawnFixedPickups()
 
 	// GUI needs this even without the world ever doing a step.
 	// Note: this was true when the player started on the map, so it might not
@@ -108,11 +158,24 @@ func NewWorld(seed Int, l Level) (w World) {
 // NewWorldFromPlaythrough checks if the Playthrough has the same simulation
 // version as the current code.
 func NewWorldFromPlaythrough(p Playthrough) (w World) {
+	w, err := TryNewWorldFromPlaythrough(p)
+	Check(err)
+	return
+}
+
+// TryNewWorldFromPlaythrough returns a VersionError if the playthrough was
+// made by another simulation and a CorruptDataError if an array of the Level
+// has more elements than it can hold. It doesn't check the rest of the Level,
+// see Validate.
+func TryNewWorldFromPlaythrough(p Playthrough) (w World, err error) {
 	if p.SimulationVersion.ToInt64() != SimulationVersion {
-		Check(fmt.Errorf("can't run this playthrough with the current "+
-			"simulation - we are at SimulationVersion %d and playthrough "+
-			"was generated with SimulationVersion version %d",
-			SimulationVersion, p.SimulationVersion.ToInt64()))
+		err = &VersionError{"SimulationVersion", p.SimulationVersion.ToInt64(),
+			SimulationVersion}
+		return
+	}
+	if err = p.Level.checkCapacity(); err != nil {
+		err = &CorruptDataError{err}
+		return
 	}
 	w = NewWorld(p.Seed, p.Level)
 	return
@@ -130,7 +193,7 @@ func (w *World) WorldPosToTile(pt Pt) Pt {
 
 func (w *World) computeVisibleTiles() {
 	// Compute which tiles are visible.
-	obstacles := w.Obstacles
+	obstacles := w.OpaqueTiles
 	for i := range w.Enemies.N {
 		obstacles.Set(w.Enemies.V[i].Pos())
 	}
@@ -160,6 +223,15 @@ func (w *World) SpawnPortalPositions() (m MatBool) {
 	return
 }
 
+func (w *World) DestructiblePortalPositions() (m MatBool) {
+	for i := range w.SpawnPortals.N {
+		if w.SpawnPortals.V[i].Destructible {
+			m.Set(w.SpawnPortals.V[i].pos)
+		}
+	}
+	return
+}
+
 func (w *World) Step(input PlayerInput) {
 	w.StepDebug(input)
 
@@ -170,35 +242,21 @@ func (w *World) Step(input PlayerInput) {
 	// of when to reset this state is a decision that only the coordinating
 	// parent (e.g. the World) can take.
 	w.Player.JustHit = false
+	w.StepEvents.N = 0
+	w.StepEvents.NDropped = 0
 	w.Player.Step(w, input)
 	w.computeVisibleTiles()
-
-	stepEnemies := true
-	if w.Boardgame && !input.Move && !input.Shoot {
-		stepEnemies = false
-	}
-
-	if stepEnemies {
-		// Step the ammos.
-		if w.UseAmmo {
-			w.SpawnAmmos()
-		}
-
-		w.EnemyMoveCooldown.Update()
-
-		// Step the enemies.
-		for i := range w.Enemies.N {
-			w.Enemies.V[i].Step(w)
-		}
-
-		// Step SpawnPortalsParams.
-		for i := range w.SpawnPortals.N {
-			w.SpawnPortals.V[i].Step(w)
-		}
-
-		if w.EnemyMoveCooldown.Ready() {
-			w.EnemyMoveCooldown.Reset()
+	// A portal destroyed by the player doesn't get to spawn anymore.
+	w.cullSpawnPortals()
+
+	if w.TurnBased {
+		// Only actions make time pass for the enemies.
+		if input.Move || input.Shoot {
+			w.NTurns.Inc()
+			w.stepTurn()
 		}
+	} else if !w.Boardgame || input.Move || input.Shoot {
+		w.stepEnemies()
 	}
 
 	// Cull dead enemies.
@@ -207,62 +265,56 @@ func (w *World) Step(input PlayerInput) {
 		if w.Enemies.V[i].Alive() {
 			w.Enemies.V[n] = w.Enemies.V[i]
 			n++
+		} else {
+			w.emit(EnemyKilled, w.Enemies.V[i].Pos(), ZERO)
+			w.NHoundsKilled.Inc()
+			w.DropPickups(w.Enemies.V[i].Pos())
+			if w.Enemies.V[i].Marked() {
+				w.Objectives.V[w.Enemies.V[i].targetIdx].Done = true
+			}
 		}
 	}
 	w.Enemies.N = n
 
-	// Cull dead SpawnPortals.
-	n = int64(0)
-	for i := range w.SpawnPortals.N {
-		if w.SpawnPortals.V[i].Health.IsPositive() {
-			w.SpawnPortals.V[n] = w.SpawnPortals.V[i]
-			n++
-		}
-	}
-	w.SpawnPortals.N = n
-
 	w.TimeStep.Inc()
 	if w.TimeStep.Eq(I(math.MaxInt64)) {
 		// Damn.
 		Check(fmt.Errorf("got to an unusually large time step: %d", w.TimeStep.ToInt64()))
 	}
+
+	w.stepObjectives()
+	w.emitLevelEnd()
+	w.CheckDebug()
 }
 
-func (w *World) SpawnAmmos() {
-	// Spawn new ammos
-	for {
-		// Count ammo available in the world now.
-		available := ZERO
-		for i := range w.Ammos.N {
-			available.Add(w.Ammos.V[i].Count)
-		}
+// stepEnemies advances everything that is not the player by one frame.
+func (w *World) stepEnemies() {
+	// Step the ammos.
+	if w.UseAmmo {
+		w.SpawnAmmos()
+	}
 
-		// Count total ammo.
-		totalAmmo := w.Player.AmmoCount.Plus(available)
+	// Step the pickups.
+	w.StepPickupSpawners()
 
-		if totalAmmo.Geq(w.Player.AmmoLimit) {
-			// There's enough ammo available.
-			return
-		}
+	w.EnemyMoveCooldown.Update()
 
-		// Build matrix with positions occupied everywhere we don't want to
-		// spawn ammo.
-		occ := w.Obstacles
-		for i := range w.Ammos.N {
-			occ.Set(w.Ammos.V[i].Pos)
-		}
-		occ.Set(w.Player.Pos())
-		for i := range w.Enemies.N {
-			occ.Set(w.Enemies.V[i].Pos())
-		}
+	// Step the enemies.
+	for i := range w.Enemies.N {
+		w.Enemies.V[i].Step(w)
+	}
 
-		// Spawn ammo.
-		ammo := Ammo{
-			Pos:   occ.OccupyRandomPos(&w.Rand),
-			Count: I(3),
-		}
-		w.Ammos.V[w.Ammos.N] = ammo
-		w.Ammos.N++
+	// Step the level's event timeline.
+	w.stepEvents()
+
+	// Step SpawnPortalsParams.
+	for i := range w.SpawnPortals.N {
+		w.SpawnPortals.V[i].Step(w)
+	}
+
+	if w.EnemyMoveCooldown.Ready() {
+		w.hitAllies()
+		w.EnemyMoveCooldown.Reset()
 	}
 }
 
@@ -277,7 +329,7 @@ func (w *World) AllEnemiesDead() bool {
 			return false
 		}
 	}
-	return true
+	return !w.eventsWillSpawn()
 }
 
 type WorldStatus int
@@ -288,10 +340,21 @@ const (
 	Lost
 )
 
+func (s WorldStatus) String() string {
+	switch s {
+	case Won:
+		return "won"
+	case Lost:
+		return "lost"
+	default:
+		return "ongoing"
+	}
+}
+
 func (w *World) Status() WorldStatus {
-	if w.AllEnemiesDead() {
+	if w.objectivesWon() {
 		return Won
-	} else if w.Player.Health.Leq(ZERO) {
+	} else if w.Player.Health.Leq(ZERO) || w.objectivesLost() {
 		return Lost
 	} else {
 		return Ongoing
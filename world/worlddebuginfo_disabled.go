@@ -7,3 +7,6 @@ type WorldDebugInfo struct {
 
 func (w *World) StepDebug(input PlayerInput) {
 }
+
+func (w *World) CheckDebug() {
+}
@@ -2,11 +2,27 @@
 
 package world
 
+import (
+	. "github.com/marisvali/miln/gamelib"
+)
+
 type WorldDebugInfo struct {
 	History PlayerInputArray
+	// What CheckDebug needs to know about the World before the Step.
+	healthBefore  Int
+	pickupsBefore PickupsArray
 }
 
 func (w *World) StepDebug(input PlayerInput) {
-	w.WorldDebugInfo.History.Data[w.WorldDebugInfo.History.N] = input
+	w.WorldDebugInfo.History.V[w.WorldDebugInfo.History.N] = input
 	w.WorldDebugInfo.History.N++
+	w.healthBefore = w.Player.Health
+	w.pickupsBefore = w.Pickups
+}
+
+// CheckDebug crashes as soon as a Step breaks an invariant, see
+// CheckStepInvariants.
+func (w *World) CheckDebug() {
+	Check(CheckInvariants(w))
+	Check(checkHealth(w.healthBefore, &w.pickupsBefore, w))
 }